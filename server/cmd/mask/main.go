@@ -0,0 +1,210 @@
+// Command mask anonymizes CSV and JSON extracts with consistent fake data,
+// the same way as POST /api/fake-data/mask:
+//
+//	mask -config mask.json -key "$MASK_KEY" -out masked/ users.csv orders.json
+//
+// Every file in a run, and in any other run with the same key, maps equal
+// values to equal fakes, so masked tables can still be joined. Without files
+// it masks standard input to standard output.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/siddhantgureja/safetrace/faker"
+)
+
+func main() {
+	configPath := flag.String("config", "", "masking config file (required)")
+	key := flag.String("key", os.Getenv("MASK_KEY"), "masking key, at least 16 characters (default $MASK_KEY)")
+	outDir := flag.String("out", "", "directory for masked files (default: next to each input, as name.masked.ext)")
+	format := flag.String("format", "", "input format, csv or json (default: from the file name or content)")
+	allowMissing := flag.Bool("allow-missing", false, "allow mapped columns that no input contains")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: mask -config mask.json [flags] [file ...]\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("mask: ")
+
+	if *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config, err := faker.ParseMaskConfig(data)
+	var configErrors faker.SchemaErrors
+	if errors.As(err, &configErrors) {
+		for _, configError := range configErrors {
+			log.Printf("%s: %s: %s", *configPath, configError.Path, configError.Message)
+		}
+		os.Exit(1)
+	}
+
+	if *key == "" {
+		log.Print("no -key or MASK_KEY given; the output will not match other runs")
+	}
+	masker, err := faker.NewMasker(config, *key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flag.NArg() == 0 {
+		stats, err := masker.Mask(os.Stdin, os.Stdout, *format)
+		if err != nil {
+			log.Fatal(err)
+		}
+		checkUnmatched(stats.Unmatched, *allowMissing, nil)
+		return
+	}
+
+	inputs := flag.Args()
+	outputs := make([]string, len(inputs))
+	for i, input := range inputs {
+		outputs[i] = outputPath(input, *outDir)
+	}
+	if err := checkOutputs(inputs, outputs); err != nil {
+		log.Fatal(err)
+	}
+
+	// Files are masked into temporary files, renamed into place only once
+	// every input has been masked. A column only has to appear in one of the
+	// files.
+	var unmatched []string
+	var temps []string
+	records := make([]int, len(inputs))
+	for i, input := range inputs {
+		temp, stats, err := maskFile(masker, input, outputs[i], *format)
+		if err != nil {
+			removeAll(temps)
+			log.Fatalf("%s: %v", input, err)
+		}
+		temps = append(temps, temp)
+		records[i] = stats.Records
+
+		if i == 0 {
+			unmatched = stats.Unmatched
+		} else {
+			unmatched = intersect(unmatched, stats.Unmatched)
+		}
+	}
+	checkUnmatched(unmatched, *allowMissing, temps)
+
+	for i, temp := range temps {
+		if err := os.Rename(temp, outputs[i]); err != nil {
+			removeAll(temps[i:])
+			log.Fatal(err)
+		}
+		log.Printf("%s: masked %d records into %s", inputs[i], records[i], outputs[i])
+	}
+}
+
+// maskFile masks input into a temporary file next to output and returns its
+// path
+func maskFile(masker *faker.Masker, input string, output string, format string) (string, faker.MaskStats, error) {
+	in, err := os.Open(input)
+	if err != nil {
+		return "", faker.MaskStats{}, err
+	}
+	defer in.Close()
+
+	if format == "" {
+		format = faker.MaskInputFormat(input)
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return "", faker.MaskStats{}, err
+	}
+	out, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*.tmp")
+	if err != nil {
+		return "", faker.MaskStats{}, err
+	}
+
+	stats, err := masker.Mask(in, out, format)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", stats, err
+	}
+	return out.Name(), stats, nil
+}
+
+// checkOutputs refuses outputs that would overwrite an input or each other
+func checkOutputs(inputs []string, outputs []string) error {
+	for i, output := range outputs {
+		for _, input := range inputs {
+			if samePath(output, input) {
+				return fmt.Errorf("%s would be overwritten by its masked copy", input)
+			}
+		}
+		for j, other := range outputs[:i] {
+			if samePath(output, other) {
+				return fmt.Errorf("%s and %s would both be masked into %s", inputs[j], inputs[i], output)
+			}
+		}
+	}
+	return nil
+}
+
+// samePath reports whether two paths name the same file, through links too
+// when both exist
+func samePath(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA == nil && errB == nil && absA == absB {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
+// outputPath names the masked copy of input, in dir if one is given
+func outputPath(input string, dir string) string {
+	if dir != "" {
+		return filepath.Join(dir, filepath.Base(input))
+	}
+	ext := filepath.Ext(input)
+	return strings.TrimSuffix(input, ext) + ".masked" + ext
+}
+
+// checkUnmatched fails the run if mapped columns were never found, removing
+// the masked files since a misspelt column means its data went out unmasked
+func checkUnmatched(unmatched []string, allowMissing bool, temps []string) {
+	if len(unmatched) == 0 || allowMissing {
+		return
+	}
+	removeAll(temps)
+	log.Fatalf("mapped columns not found in any input: %s (use -allow-missing if this is expected)", strings.Join(unmatched, ", "))
+}
+
+func removeAll(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+func intersect(a []string, b []string) []string {
+	var both []string
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				both = append(both, x)
+				break
+			}
+		}
+	}
+	return both
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckOutputs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "users.csv")
+	if err := os.WriteFile(input, []byte("email\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		inputs  []string
+		out     string
+		wantErr string
+	}{
+		{"next to the input", []string{input}, "", ""},
+		{"another directory", []string{input}, filepath.Join(dir, "masked"), ""},
+		{"the input's directory", []string{input}, dir, "would be overwritten"},
+		{"the input's directory, relatively", []string{input}, filepath.Join(dir, "masked", ".."), "would be overwritten"},
+		{"a link to the input's directory", []string{input}, link, "would be overwritten"},
+		{"inputs with the same name", []string{input, filepath.Join(dir, "sub", "users.csv")}, filepath.Join(dir, "masked"), "would both be masked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := make([]string, len(tt.inputs))
+			for i, input := range tt.inputs {
+				outputs[i] = outputPath(input, tt.out)
+			}
+			err := checkOutputs(tt.inputs, outputs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
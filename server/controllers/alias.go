@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+)
+
+// AliasController handles operations on masked email aliases
+type AliasController struct {
+	client  *mongo.Client
+	aliases *services.MaskedEmailService
+}
+
+// NewAliasController creates a new alias controller
+func NewAliasController(client *mongo.Client, aliases *services.MaskedEmailService) *AliasController {
+	return &AliasController{
+		client:  client,
+		aliases: aliases,
+	}
+}
+
+// ListAliases retrieves the authenticated user's aliases, newest first.
+// ?vaultItemId= limits them to the aliases linked to one vault item.
+func (c *AliasController) ListAliases(ctx *gin.Context) {
+	filter := bson.M{"userId": middleware.UserID(ctx)}
+	if vaultItemID := ctx.Query("vaultItemId"); vaultItemID != "" {
+		objID, err := primitive.ObjectIDFromHex(vaultItemID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+			return
+		}
+		filter["vaultItemId"] = objID
+	}
+
+	collection := c.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(dbCtx, filter, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch aliases"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	aliases := []models.MaskedAlias{}
+	if err := cursor.All(dbCtx, &aliases); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode aliases"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, aliases)
+}
+
+// CreateAlias creates a new alias for a site that forwards to forwardTo once
+// the address is verified. A verification link is mailed to it unless the
+// user already verified it for another alias.
+func (c *AliasController) CreateAlias(ctx *gin.Context) {
+	var request models.AliasRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.ForwardTo == nil || request.Site == nil || strings.TrimSpace(*request.Site) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "forwardTo and site are required"})
+		return
+	}
+
+	now := time.Now()
+	alias := models.MaskedAlias{
+		UserID:    middleware.UserID(ctx),
+		Status:    services.AliasActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if !c.applyAliasRequest(ctx, &alias, request) {
+		return
+	}
+
+	if err := c.aliases.Create(ctx.Request.Context(), &alias); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alias"})
+		return
+	}
+	// The alias stays paused without verifySentAt, and the link can be resent
+	if err := c.aliases.SendVerification(ctx.Request.Context(), &alias); err != nil {
+		log.Printf("Aliases: failed to send verification for %s: %v", alias.Address, err)
+	}
+
+	ctx.JSON(http.StatusCreated, alias)
+}
+
+// GetAlias retrieves one alias with its statistics
+func (c *AliasController) GetAlias(ctx *gin.Context) {
+	alias, ok := c.findAlias(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, alias)
+}
+
+// UpdateAlias changes an alias's forwarding address, site, note, linked
+// vault item or status. Fields left out of the request are unchanged. A new
+// forwarding address pauses forwarding until it is verified.
+func (c *AliasController) UpdateAlias(ctx *gin.Context) {
+	alias, ok := c.findAlias(ctx)
+	if !ok {
+		return
+	}
+	previousForwardTo := alias.ForwardTo
+
+	var request models.AliasRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !c.applyAliasRequest(ctx, alias, request) {
+		return
+	}
+	alias.UpdatedAt = time.Now()
+	forwardChanged := !strings.EqualFold(alias.ForwardTo, previousForwardTo)
+	if forwardChanged {
+		alias.ForwardVerified = false
+	}
+
+	collection := c.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"forwardTo":       alias.ForwardTo,
+			"forwardVerified": alias.ForwardVerified,
+			"site":            alias.Site,
+			"note":            alias.Note,
+			"vaultItemId":     alias.VaultItemID,
+			"status":          alias.Status,
+			"updatedAt":       alias.UpdatedAt,
+		},
+	}
+	result, err := collection.UpdateOne(dbCtx, bson.M{"_id": alias.ID, "userId": alias.UserID}, update)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alias"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+		return
+	}
+	if forwardChanged {
+		if err := c.aliases.SendVerification(ctx.Request.Context(), alias); err != nil {
+			log.Printf("Aliases: failed to send verification for %s: %v", alias.Address, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, alias)
+}
+
+// ResendVerification mails a new verification link for an alias whose
+// forwarding address is not verified yet
+func (c *AliasController) ResendVerification(ctx *gin.Context) {
+	alias, ok := c.findAlias(ctx)
+	if !ok {
+		return
+	}
+	if alias.ForwardVerified {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Forwarding address is already verified"})
+		return
+	}
+
+	if err := c.aliases.SendVerification(ctx.Request.Context(), alias); err != nil {
+		log.Printf("Aliases: failed to send verification for %s: %v", alias.Address, err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	ctx.JSON(http.StatusOK, alias)
+}
+
+// VerifyForwarding confirms a forwarding address from the link mailed to it.
+// It needs no authentication, since the token proves access to the mailbox.
+func (c *AliasController) VerifyForwarding(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	alias, err := c.aliases.Verify(ctx.Request.Context(), token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify forwarding address"})
+		return
+	}
+	if alias == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Mail sent to " + alias.Address + " will now be forwarded to " + alias.ForwardTo})
+}
+
+// DeleteAlias deletes an alias. Mail sent to it afterwards is refused.
+func (c *AliasController) DeleteAlias(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alias"})
+		return
+	}
+	if result.DeletedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Alias deleted successfully"})
+}
+
+// findAlias loads the alias named by the :id parameter for the authenticated
+// user, writing an error response and returning false if it cannot
+func (c *AliasController) findAlias(ctx *gin.Context) (*models.MaskedAlias, bool) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	collection := c.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var alias models.MaskedAlias
+	err = collection.FindOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)}).Decode(&alias)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alias"})
+		return nil, false
+	}
+	return &alias, true
+}
+
+// applyAliasRequest validates the fields set in request and copies them onto
+// alias, writing an error response and returning false if any are invalid
+func (c *AliasController) applyAliasRequest(ctx *gin.Context, alias *models.MaskedAlias, request models.AliasRequest) bool {
+	if request.ForwardTo != nil {
+		address, err := mail.ParseAddress(*request.ForwardTo)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "forwardTo must be a valid email address"})
+			return false
+		}
+		// Forwarding to another alias could loop forever
+		if strings.HasSuffix(strings.ToLower(address.Address), "@"+c.aliases.Domain()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "forwardTo cannot be an alias address"})
+			return false
+		}
+		alias.ForwardTo = address.Address
+	}
+	if request.Site != nil {
+		alias.Site = strings.TrimSpace(*request.Site)
+	}
+	if request.Note != nil {
+		alias.Note = *request.Note
+	}
+
+	if request.Status != nil {
+		switch *request.Status {
+		case services.AliasActive, services.AliasBlocked, services.AliasDisabled:
+			alias.Status = *request.Status
+		default:
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "status must be active, blocked or disabled"})
+			return false
+		}
+	}
+
+	if request.VaultItemID != nil {
+		if *request.VaultItemID == "" {
+			alias.VaultItemID = nil
+			return true
+		}
+		objID, err := primitive.ObjectIDFromHex(*request.VaultItemID)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+			return false
+		}
+
+		collection := c.client.Database("safetrace").Collection("vault")
+		dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		count, err := collection.CountDocuments(dbCtx, bson.M{"_id": objID, "userId": alias.UserID})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch vault item"})
+			return false
+		}
+		if count == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Vault item not found"})
+			return false
+		}
+		alias.VaultItemID = &objID
+	}
+	return true
+}
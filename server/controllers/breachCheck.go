@@ -1,22 +1,36 @@
 package controllers
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siddhantgureja/safetrace/middleware"
 	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+	"github.com/siddhantgureja/safetrace/utils"
 )
 
 // BreachCheckController handles operations for checking data breaches
-type BreachCheckController struct{}
+type BreachCheckController struct {
+	lookup      *services.BreachLookup
+	history     *services.BreachHistory
+	emailRules  map[string]utils.EmailProviderRule
+	phoneRegion string
+}
 
-// NewBreachCheckController creates a new breach check controller
-func NewBreachCheckController() *BreachCheckController {
-	return &BreachCheckController{}
+// NewBreachCheckController creates a new breach check controller. Checks by
+// authenticated users are recorded in history when it is not nil.
+func NewBreachCheckController(lookup *services.BreachLookup, history *services.BreachHistory) *BreachCheckController {
+	return &BreachCheckController{
+		lookup:      lookup,
+		history:     history,
+		emailRules:  utils.EmailProviderRules(),
+		phoneRegion: utils.DefaultPhoneRegion(),
+	}
 }
 
 // CheckEmail checks if an email has been involved in a data breach
@@ -32,50 +46,138 @@ func (c *BreachCheckController) CheckEmail(ctx *gin.Context) {
 		return
 	}
 
-	// Get API key from environment
-	apiKey := os.Getenv("XPOSED_API_KEY")
-	if apiKey == "" {
-		// Use mock data if no API key
-		mockResponse := getMockBreachResponse(request.Email)
-		ctx.JSON(http.StatusOK, mockResponse)
+	email, err := utils.NormalizeEmail(request.Email, c.emailRules)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address", "reason": err.(*utils.EmailError).Reason})
+		return
+	}
+
+	response, ok := c.checkIdentity(ctx, services.IdentityEmail, email.Canonical)
+	if !ok {
+		return
+	}
+	response.CheckedEmail = email.Canonical
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// CheckPhone checks if a phone number has been involved in a data breach
+func (c *BreachCheckController) CheckPhone(ctx *gin.Context) {
+	var request models.BreachCheckRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Phone == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Phone is required"})
 		return
 	}
 
-	// Make request to XposedOrNot API
-	client := &http.Client{}
-	url := fmt.Sprintf("https://api.xposedornot.com/v1/check-email/%s", request.Email)
-	req, err := http.NewRequest("GET", url, nil)
+	region := request.Region
+	if region == "" {
+		region = c.phoneRegion
+	}
+	phone, err := utils.NormalizePhone(request.Phone, region)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone number", "reason": err.(*utils.PhoneError).Reason})
+		return
+	}
+
+	response, ok := c.checkIdentity(ctx, services.IdentityPhone, phone.E164)
+	if !ok {
 		return
 	}
+	response.CheckedPhone = phone.E164
 
-	req.Header.Add("X-Api-Key", apiKey)
-	req.Header.Add("Content-Type", "application/json")
+	ctx.JSON(http.StatusOK, response)
+}
+
+// CheckUsername checks if a username has been involved in a data breach
+func (c *BreachCheckController) CheckUsername(ctx *gin.Context) {
+	var request models.BreachCheckRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	resp, err := client.Do(req)
+	if request.Username == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
+		return
+	}
+
+	username, err := utils.NormalizeUsername(request.Username)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check breach data"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid username", "reason": err.(*utils.UsernameError).Reason})
 		return
 	}
-	defer resp.Body.Close()
 
-	var apiResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse API response"})
+	response, ok := c.checkIdentity(ctx, services.IdentityUsername, username)
+	if !ok {
 		return
 	}
+	response.CheckedUsername = username
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// checkIdentity looks up a normalized identity and builds the response. It
+// writes the error response itself and returns false when the lookup fails.
+func (c *BreachCheckController) checkIdentity(ctx *gin.Context, kind string, value string) (*models.BreachCheckResponse, bool) {
+	result, err := c.lookup.Lookup(ctx.Request.Context(), kind, value, requestedMaxAge(ctx, c.lookup.TTL()))
+	var rateLimited *services.RateLimitError
+	if errors.As(err, &rateLimited) {
+		ctx.Header("Retry-After", strconv.Itoa(int(rateLimited.RetryAfter.Seconds())))
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Breach provider is rate limiting requests, try again later"})
+		return nil, false
+	}
+	if errors.Is(err, services.ErrUnsupportedIdentity) {
+		ctx.JSON(http.StatusNotImplemented, gin.H{"error": "The configured breach provider does not support " + kind + " lookups"})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check breach data"})
+		return nil, false
+	}
 
-	// Map API response to our model
-	// Note: This is a simplified mapping. Adjust based on actual API response
-	breachResponse := models.BreachCheckResponse{
-		Found:    apiResponse["found"].(bool),
-		Count:    int(apiResponse["count"].(float64)),
-		Source:   "XposedOrNot",
-		Severity: getSeverityLevel(int(apiResponse["count"].(float64))),
+	if result.Cached {
+		ctx.Header("X-Cache", "HIT")
+		ctx.Header("Age", strconv.Itoa(int(time.Since(result.CheckedAt).Seconds())))
+	} else {
+		ctx.Header("X-Cache", "MISS")
 	}
 
-	ctx.JSON(http.StatusOK, breachResponse)
+	response := &models.BreachCheckResponse{
+		Found:     len(result.Breaches) > 0,
+		Count:     len(result.Breaches),
+		Source:    result.Source,
+		Severity:  utils.SeverityLevel(len(result.Breaches)),
+		Breaches:  result.Breaches,
+		Cached:    result.Cached,
+		CheckedAt: &result.CheckedAt,
+	}
+	c.recordCheck(ctx, kind, c.lookup.IdentityHash(kind, value), response)
+	return response, true
+}
+
+// recordCheck stores a check in the authenticated user's history
+func (c *BreachCheckController) recordCheck(ctx *gin.Context, kind string, identityHash string, response *models.BreachCheckResponse) {
+	userID := middleware.UserID(ctx)
+	if c.history == nil || userID == "" {
+		return
+	}
+
+	go c.history.Record(models.BreachCheckRecord{
+		UserID:       userID,
+		Kind:         kind,
+		IdentityHash: identityHash,
+		Found:        response.Found,
+		Count:        response.Count,
+		Severity:     response.Severity,
+		Source:       response.Source,
+		Breaches:     response.Breaches,
+		CheckedAt:    time.Now(),
+	})
 }
 
 // CheckPassword checks if a password has been involved in a data breach
@@ -91,19 +193,35 @@ func (c *BreachCheckController) CheckPassword(ctx *gin.Context) {
 		return
 	}
 
-	// Get API key from environment
-	apiKey := os.Getenv("XPOSED_API_KEY")
-	if apiKey == "" {
-		// Use mock data if no API key
-		mockResponse := getMockBreachResponse(request.Password)
-		ctx.JSON(http.StatusOK, mockResponse)
-		return
+	// No breach provider checks passwords yet, so every password gets mock
+	// data; a real check would use the Pwned Passwords range API
+	response := getMockBreachResponse(request.Password)
+	c.recordCheck(ctx, services.IdentityPassword, services.PasswordHashPrefix(request.Password), &response)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// requestedMaxAge returns how old a cached result the client accepts. Clients
+// force a fresh check with fresh=true or "Cache-Control: no-cache", and can
+// bound the age with "Cache-Control: max-age=<seconds>".
+func requestedMaxAge(ctx *gin.Context, ttl time.Duration) time.Duration {
+	if ctx.Query("fresh") == "true" {
+		return 0
+	}
+
+	for _, directive := range strings.Split(ctx.GetHeader("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return 0
+		}
+		if strings.HasPrefix(directive, "max-age=") {
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil && seconds >= 0 && time.Duration(seconds)*time.Second < ttl {
+				return time.Duration(seconds) * time.Second
+			}
+		}
 	}
 
-	// In a real app, this would use the Pwned Passwords API or similar
-	// For this example, we'll use a similar mock approach as the email check
-	mockResponse := getMockBreachResponse(request.Password)
-	ctx.JSON(http.StatusOK, mockResponse)
+	return ttl
 }
 
 // getMockBreachResponse generates mock data for demonstration purposes
@@ -137,19 +255,6 @@ func getMockBreachResponse(input string) models.BreachCheckResponse {
 		Found:    count > 0,
 		Count:    count,
 		Source:   "Mock Data",
-		Severity: getSeverityLevel(count),
+		Severity: utils.SeverityLevel(count),
 	}
 }
-
-// getSeverityLevel calculates a severity level based on breach count
-func getSeverityLevel(count int) string {
-	if count == 0 {
-		return "None"
-	} else if count < 3 {
-		return "Low"
-	} else if count < 7 {
-		return "Medium"
-	} else {
-		return "High"
-	}
-} 
\ No newline at end of file
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// domainTokenPrefix prefixes the TXT record value that proves domain ownership
+const domainTokenPrefix = "safetrace-verification="
+
+// DomainController handles domain verification and domain-wide breach searches
+type DomainController struct {
+	client   *mongo.Client
+	provider services.BreachProvider
+	resolver services.TXTResolver
+}
+
+// NewDomainController creates a new domain controller
+func NewDomainController(client *mongo.Client, provider services.BreachProvider, resolver services.TXTResolver) *DomainController {
+	return &DomainController{
+		client:   client,
+		provider: provider,
+		resolver: resolver,
+	}
+}
+
+// ListDomains retrieves the domains claimed by the authenticated user
+func (c *DomainController) ListDomains(ctx *gin.Context) {
+	collection := c.client.Database("safetrace").Collection("domains")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(dbCtx, bson.M{"userId": middleware.UserID(ctx)})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch domains"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	domains := []models.DomainVerification{}
+	if err := cursor.All(dbCtx, &domains); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode domains"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, domains)
+}
+
+// ClaimDomain starts verification of a domain and returns the TXT record
+// that has to be published to prove ownership
+func (c *DomainController) ClaimDomain(ctx *gin.Context) {
+	var request models.DomainRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	domain, err := utils.NormalizeDomain(request.Domain)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A valid domain name is required", "reason": err.Error()})
+		return
+	}
+
+	userID := middleware.UserID(ctx)
+	collection := c.client.Database("safetrace").Collection("domains")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Claiming the same domain twice returns the existing token
+	var existing models.DomainVerification
+	err = collection.FindOne(dbCtx, bson.M{"userId": userID, "domain": domain}).Decode(&existing)
+	if err == nil {
+		ctx.JSON(http.StatusOK, existing)
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch domain"})
+		return
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	verification := models.DomainVerification{
+		UserID:    userID,
+		Domain:    domain,
+		Token:     token,
+		TXTRecord: domainTokenPrefix + token,
+		CreatedAt: time.Now(),
+	}
+
+	result, err := collection.InsertOne(dbCtx, verification)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim domain"})
+		return
+	}
+
+	verification.ID = result.InsertedID.(primitive.ObjectID)
+	ctx.JSON(http.StatusCreated, verification)
+}
+
+// VerifyDomain looks up the domain's TXT records and marks it verified when
+// the expected token is present
+func (c *DomainController) VerifyDomain(ctx *gin.Context) {
+	verification, ok := c.findDomain(ctx)
+	if !ok {
+		return
+	}
+
+	if !verification.Verified {
+		lookupCtx, cancel := context.WithTimeout(ctx.Request.Context(), 10*time.Second)
+		found, err := services.HasTXTRecord(lookupCtx, c.resolver, verification.Domain, verification.TXTRecord)
+		cancel()
+		if err != nil {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Unable to look up TXT records for " + verification.Domain})
+			return
+		}
+		if !found {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":     "Verification record not found",
+				"txtRecord": verification.TXTRecord,
+			})
+			return
+		}
+
+		collection := c.client.Database("safetrace").Collection("domains")
+		dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		verification.Verified = true
+		verification.VerifiedAt = time.Now()
+		update := bson.M{"$set": bson.M{"verified": true, "verifiedAt": verification.VerifiedAt}}
+		if _, err := collection.UpdateOne(dbCtx, bson.M{"_id": verification.ID}, update); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update domain"})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, verification)
+}
+
+// DeleteDomain removes a claimed domain
+func (c *DomainController) DeleteDomain(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("domains")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete domain"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Domain deleted successfully"})
+}
+
+// GetDomainBreaches lists every breached address on a verified domain. Pass
+// format=csv to download the list as CSV.
+func (c *DomainController) GetDomainBreaches(ctx *gin.Context) {
+	verification, ok := c.findDomain(ctx)
+	if !ok {
+		return
+	}
+
+	if !verification.Verified {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Domain has not been verified"})
+		return
+	}
+
+	searcher, ok := c.provider.(services.DomainSearcher)
+	if !ok {
+		ctx.JSON(http.StatusNotImplemented, gin.H{"error": "Breach provider does not support domain search"})
+		return
+	}
+
+	searchCtx, cancel := context.WithTimeout(ctx.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	results, err := searcher.SearchDomain(searchCtx, verification.Domain)
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to search domain breaches"})
+		return
+	}
+
+	response := models.DomainBreachResponse{
+		Domain:    verification.Domain,
+		Source:    c.provider.Name(),
+		Addresses: []models.DomainBreachRecord{},
+	}
+	for email, breaches := range results {
+		response.Addresses = append(response.Addresses, models.DomainBreachRecord{
+			Email:    email,
+			Count:    len(breaches),
+			Breaches: breaches,
+			Severity: utils.SeverityLevel(len(breaches)),
+		})
+	}
+	sort.Slice(response.Addresses, func(i, j int) bool {
+		return response.Addresses[i].Email < response.Addresses[j].Email
+	})
+	response.Count = len(response.Addresses)
+
+	if ctx.Query("format") == "csv" {
+		writeDomainBreachesCSV(ctx, response)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// findDomain loads the domain named by the :id parameter for the
+// authenticated user, writing an error response if it cannot
+func (c *DomainController) findDomain(ctx *gin.Context) (*models.DomainVerification, bool) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	collection := c.client.Database("safetrace").Collection("domains")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var verification models.DomainVerification
+	err = collection.FindOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)}).Decode(&verification)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Domain not found"})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch domain"})
+		return nil, false
+	}
+
+	return &verification, true
+}
+
+// writeDomainBreachesCSV writes a domain breach report as a CSV download
+func writeDomainBreachesCSV(ctx *gin.Context, response models.DomainBreachResponse) {
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", "attachment; filename=\""+response.Domain+"-breaches.csv\"")
+	ctx.Status(http.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Write([]string{"email", "count", "severity", "breaches"})
+	for _, record := range response.Addresses {
+		writer.Write([]string{
+			record.Email,
+			strconv.Itoa(record.Count),
+			record.Severity,
+			strings.Join(record.Breaches, ";"),
+		})
+	}
+	writer.Flush()
+}
@@ -1,147 +1,339 @@
 package controllers
 
 import (
-	"math/rand"
+	"bufio"
+	"errors"
+	"io"
+	"log"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/siddhantgureja/safetrace/faker"
+	"github.com/siddhantgureja/safetrace/middleware"
 	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+	"github.com/siddhantgureja/safetrace/utils"
 )
 
+// fakeDataFlushEvery is how many records are buffered before being flushed
+// to the client
+const fakeDataFlushEvery = 200
+
+// maxSchemaBytes limits the size of a posted schema
+const maxSchemaBytes = 64 << 10
+
 // FakeDataController handles operations for generating fake data
-type FakeDataController struct{}
+type FakeDataController struct {
+	inboxes  *services.InboxService
+	sink     *services.SMTPSink // nil unless the SMTP sink is enabled
+	maxCount int
+}
 
 // NewFakeDataController creates a new fake data controller
-func NewFakeDataController() *FakeDataController {
-	// Seed the random number generator
-	rand.Seed(time.Now().UnixNano())
-	return &FakeDataController{}
+func NewFakeDataController(inboxes *services.InboxService, sink *services.SMTPSink) *FakeDataController {
+	return &FakeDataController{
+		inboxes:  inboxes,
+		sink:     sink,
+		maxCount: utils.EnvInt("FAKE_DATA_MAX_COUNT", 10000),
+	}
 }
 
-// GenerateFakeData generates fake data for testing purposes
+// GenerateFakeData generates fake data for testing purposes. Passing the same
+// ?seed= always returns the same data; without one a random seed is used. The
+// seed is returned in the X-Fake-Data-Seed header so any result can be
+// reproduced.
+//
+// Without ?count= a single JSON object is returned. With ?count= the records
+// are streamed in the requested ?format= (json, ndjson, csv or sql, with
+// ?table= naming the table for INSERT statements).
+//
+// With ?inbox=true the single record's email is a real disposable mailbox,
+// readable through the inbox routes until it expires. ?inbox=local uses an
+// alias on the built-in SMTP sink instead. Both require a signed-in user.
 func (c *FakeDataController) GenerateFakeData(ctx *gin.Context) {
-	// In a real implementation, we would use Faker.js on the frontend
-	// or a Go equivalent like go-faker
-	// For this example, we'll use a simple implementation
+	generator, ok := fakeDataGenerator(ctx)
+	if !ok {
+		return
+	}
+
+	inboxKind := ctx.DefaultQuery("inbox", "false")
+	switch inboxKind {
+	case "false", "true", "local":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "inbox must be true, local or false"})
+		return
+	}
+	if inboxKind == "local" && c.sink == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The SMTP sink is not enabled"})
+		return
+	}
+	if inboxKind != "false" && middleware.UserID(ctx) == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	withInbox := inboxKind != "false"
+
+	format := ctx.DefaultQuery("format", faker.FormatJSON)
+	countParam := ctx.Query("count")
+	if countParam == "" && format == faker.FormatJSON {
+		record := generator.Record()
+		if inboxKind == "true" && !c.attachInbox(ctx, &record) {
+			return
+		}
+		if inboxKind == "local" {
+			alias, err := c.sink.NewAlias(ctx.Request.Context(), middleware.UserID(ctx), record.Email)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sink alias"})
+				return
+			}
+			record.Email = c.sink.Address(alias)
+			record.InboxID = alias.Alias
+			record.InboxExpiresAt = &alias.ExpiresAt
+		}
+		setSeedHeaders(ctx, generator)
+		ctx.JSON(http.StatusOK, record)
+		return
+	}
+	if withInbox {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "inbox can only be used for a single JSON record"})
+		return
+	}
 
-	response := models.FakeDataResponse{
-		Name:       generateRandomName(),
-		Email:      generateRandomEmail(),
-		Address:    generateRandomAddress(),
-		Phone:      generateRandomPhone(),
-		CreditCard: generateRandomCreditCard(),
-		Username:   generateRandomUsername(),
-		Password:   generateRandomPassword(),
+	count, ok := c.fakeDataCount(ctx)
+	if !ok {
+		return
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	writer := bufio.NewWriter(ctx.Writer)
+	encoder, err := faker.NewEncoder(format, writer, faker.RecordColumns, ctx.DefaultQuery("table", "fake_data"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	streamFakeData(ctx, generator, format, writer, encoder, count, func() ([]interface{}, error) {
+		return faker.RecordValues(generator.Record()), nil
+	})
 }
 
-// Helper functions to generate random data
+// attachInbox replaces the record's email with a new disposable inbox,
+// writing an error response and returning false if it cannot be created
+func (c *FakeDataController) attachInbox(ctx *gin.Context, record *models.FakeDataResponse) bool {
+	inbox, err := c.inboxes.Create(ctx.Request.Context(), middleware.UserID(ctx), services.InboxLocalPart(record.Email))
+	if err != nil {
+		mailServiceError(ctx, err)
+		return false
+	}
 
-func generateRandomName() string {
-	firstNames := []string{
-		"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda",
-		"William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
-		"Thomas", "Sarah", "Charles", "Karen", "Christopher", "Nancy", "Daniel", "Lisa",
-		"Matthew", "Margaret", "Anthony", "Betty", "Mark", "Sandra", "Donald", "Ashley",
+	record.Email = inbox.Address
+	record.InboxID = inbox.ID
+	record.InboxExpiresAt = &inbox.ExpiresAt
+	return true
+}
+
+// GenerateFromSchema generates records shaped like the posted schema, which
+// maps field names to generator types (see faker.ParseSchema). It accepts the
+// same ?seed=, ?count=, ?format= and ?table= as GenerateFakeData and always
+// returns a list, of one record when ?count= is omitted.
+func (c *FakeDataController) GenerateFromSchema(ctx *gin.Context) {
+	generator, ok := fakeDataGenerator(ctx)
+	if !ok {
+		return
+	}
+	count, ok := c.fakeDataCount(ctx)
+	if !ok {
+		return
 	}
 
-	lastNames := []string{
-		"Smith", "Johnson", "Williams", "Jones", "Brown", "Davis", "Miller", "Wilson",
-		"Moore", "Taylor", "Anderson", "Thomas", "Jackson", "White", "Harris", "Martin",
-		"Thompson", "Garcia", "Martinez", "Robinson", "Clark", "Rodriguez", "Lewis", "Lee",
-		"Walker", "Hall", "Allen", "Young", "Hernandez", "King", "Wright", "Lopez",
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSchemaBytes))
+	if err != nil {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Schema must be smaller than 64KB"})
+		return
 	}
 
-	return firstNames[rand.Intn(len(firstNames))] + " " + lastNames[rand.Intn(len(lastNames))]
+	schema, err := faker.ParseSchema(body)
+	if err == nil {
+		err = schema.CheckCount(count, generator.Locale())
+	}
+	var schemaErrors faker.SchemaErrors
+	if errors.As(err, &schemaErrors) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schema", "details": schemaErrors})
+		return
+	}
+
+	format := ctx.DefaultQuery("format", faker.FormatJSON)
+	writer := bufio.NewWriter(ctx.Writer)
+	encoder, err := faker.NewEncoder(format, writer, schema.Columns(), ctx.DefaultQuery("table", "fake_data"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	streamFakeData(ctx, generator, format, writer, encoder, count, func() ([]interface{}, error) {
+		return schema.Generate(generator)
+	})
+}
+
+// ListIdentifierTypes lists the document number types that can be generated
+// and validated, with the countries each one has formats for
+func (c *FakeDataController) ListIdentifierTypes(ctx *gin.Context) {
+	types := []gin.H{}
+	for _, name := range faker.IdentifierKindNames() {
+		kind := faker.IdentifierKinds[name]
+		types = append(types, gin.H{"type": name, "name": kind.Name, "countries": kind.Countries()})
+	}
+	ctx.JSON(http.StatusOK, types)
 }
 
-func generateRandomEmail() string {
-	domains := []string{"gmail.com", "yahoo.com", "hotmail.com", "outlook.com", "example.com"}
-	username := strings.ToLower(strings.Replace(generateRandomName(), " ", ".", -1))
-	return username + "@" + domains[rand.Intn(len(domains))]
+// GenerateIdentifiers generates ?count= structurally valid document numbers
+// of one type. ?country= picks the format for IBANs, VAT IDs and passports,
+// defaulting to the ?locale='s country. ?seed= works as for GenerateFakeData.
+func (c *FakeDataController) GenerateIdentifiers(ctx *gin.Context) {
+	generator, ok := fakeDataGenerator(ctx)
+	if !ok {
+		return
+	}
+	count, ok := c.fakeDataCount(ctx)
+	if !ok {
+		return
+	}
+
+	values := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		value, err := generator.Identifier(ctx.Param("type"), ctx.Query("country"))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		values = append(values, value)
+	}
+
+	setSeedHeaders(ctx, generator)
+	ctx.JSON(http.StatusOK, gin.H{"type": ctx.Param("type"), "values": values})
 }
 
-func generateRandomAddress() string {
-	streetNumbers := []string{"123", "456", "789", "1234", "5678", "9101", "1122", "3344"}
-	streets := []string{
-		"Main St", "Oak Ave", "Pine Rd", "Maple Ln", "Cedar Blvd", "Washington Ave",
-		"Park Pl", "Lake Dr", "River Rd", "Mountain View", "Sunset Blvd", "Valley Way",
+// ValidateIdentifier checks a document number against the format and check
+// digits of its type
+func (c *FakeDataController) ValidateIdentifier(ctx *gin.Context) {
+	var request models.IdentifierValidationRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	cities := []string{
-		"New York", "Los Angeles", "Chicago", "Houston", "Phoenix", "Philadelphia",
-		"San Antonio", "San Diego", "Dallas", "San Jose", "Austin", "Jacksonville",
+	if request.Value == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "value is required"})
+		return
 	}
-	states := []string{"NY", "CA", "IL", "TX", "AZ", "PA", "FL", "OH", "MI", "GA"}
-	zipCodes := []string{"10001", "90001", "60601", "77001", "85001", "19101", "78201", "92101"}
 
-	return streetNumbers[rand.Intn(len(streetNumbers))] + " " +
-		streets[rand.Intn(len(streets))] + ", " +
-		cities[rand.Intn(len(cities))] + ", " +
-		states[rand.Intn(len(states))] + " " +
-		zipCodes[rand.Intn(len(zipCodes))]
+	result, err := faker.ValidateIdentifier(ctx.Param("type"), request.Value, request.Country)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
 }
 
-func generateRandomPhone() string {
-	format := "(###) ###-####"
-	result := ""
-	for _, char := range format {
-		if char == '#' {
-			result += string(rune('0' + rand.Intn(10)))
-		} else {
-			result += string(char)
-		}
+// fakeDataCount reads ?count=, defaulting to 1, writing a 400 response and
+// returning false if it is out of range
+func (c *FakeDataController) fakeDataCount(ctx *gin.Context) (int, bool) {
+	countParam := ctx.Query("count")
+	if countParam == "" {
+		return 1, true
+	}
+
+	count, err := strconv.Atoi(countParam)
+	if err != nil || count < 1 || count > c.maxCount {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and " + strconv.Itoa(c.maxCount)})
+		return 0, false
 	}
-	return result
+	return count, true
 }
 
-func generateRandomCreditCard() string {
-	prefixes := []string{"4", "5", "37", "34", "6011"}
-	prefix := prefixes[rand.Intn(len(prefixes))]
-	
-	// Generate the remaining digits
-	remaining := 16 - len(prefix)
-	for i := 0; i < remaining; i++ {
-		prefix += string(rune('0' + rand.Intn(10)))
-	}
-	
-	// Format with spaces
-	formatted := ""
-	for i, char := range prefix {
-		if i > 0 && i%4 == 0 {
-			formatted += " "
+// streamFakeData writes count records from next with chunked encoding,
+// flushing regularly so memory use does not grow with count. It stops early
+// if the client goes away or next fails.
+func streamFakeData(ctx *gin.Context, generator *faker.Generator, format string, writer *bufio.Writer, encoder faker.Encoder, count int, next func() ([]interface{}, error)) {
+	ctx.Header("Content-Type", faker.ContentTypes[format])
+	setSeedHeaders(ctx, generator)
+	if format == faker.FormatCSV || format == faker.FormatSQL {
+		ctx.Header("Content-Disposition", `attachment; filename="fake-data.`+format+`"`)
+	}
+	ctx.Status(http.StatusOK)
+
+	if err := encoder.Begin(); err != nil {
+		return
+	}
+	for i := 0; i < count; i++ {
+		values, err := next()
+		if err != nil {
+			log.Printf("Fake data: stopped after %d records: %v", i, err)
+			writer.Flush()
+			return
+		}
+		if err := encoder.Encode(values); err != nil {
+			return
+		}
+		if (i+1)%fakeDataFlushEvery == 0 {
+			if ctx.Request.Context().Err() != nil || writer.Flush() != nil {
+				return
+			}
+			ctx.Writer.Flush()
 		}
-		formatted += string(char)
 	}
-	
-	return formatted
+	if err := encoder.End(); err != nil {
+		return
+	}
+	writer.Flush()
+	ctx.Writer.Flush()
 }
 
-func generateRandomUsername() string {
-	adjectives := []string{
-		"happy", "sunny", "clever", "brave", "mighty", "kind", "swift", "bright",
-		"cool", "epic", "awesome", "super", "mega", "ultra", "hyper", "alpha",
+// fakeDataGenerator creates a generator for the request's ?seed=, ?month=
+// and ?locale=, limited to the ?cardNetwork= and ?testCards= given, writing a 400
+// response and returning false if any of them are invalid
+func fakeDataGenerator(ctx *gin.Context) (*faker.Generator, bool) {
+	generator := faker.NewRandom()
+	if seedParam := ctx.Query("seed"); seedParam != "" {
+		seed, err := strconv.ParseInt(seedParam, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "seed must be an integer"})
+			return nil, false
+		}
+		generator = faker.New(seed)
 	}
-	nouns := []string{
-		"tiger", "eagle", "ninja", "wizard", "ranger", "warrior", "hunter", "falcon",
-		"dragon", "knight", "hero", "champion", "titan", "legend", "master", "chief",
+	if month := ctx.Query("month"); month != "" {
+		reference, err := time.Parse("2006-01", month)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "month must be formatted as YYYY-MM"})
+			return nil, false
+		}
+		generator.SetReferenceMonth(reference)
 	}
-	
-	return adjectives[rand.Intn(len(adjectives))] + 
-		nouns[rand.Intn(len(nouns))] +
-		string(rune('0' + rand.Intn(10))) +
-		string(rune('0' + rand.Intn(10)))
-}
 
-func generateRandomPassword() string {
-	length := 12 + rand.Intn(4) // Password length between 12 and 15
-	charset := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+"
-	password := make([]byte, length)
-	for i := range password {
-		password[i] = charset[rand.Intn(len(charset))]
+	if locale := ctx.Query("locale"); locale != "" {
+		if err := generator.SetLocale(locale); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
 	}
-	return string(password)
-} 
\ No newline at end of file
+
+	testCards, err := strconv.ParseBool(ctx.DefaultQuery("testCards", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "testCards must be true or false"})
+		return nil, false
+	}
+	options := faker.CardOptions{Network: ctx.Query("cardNetwork"), TestOnly: testCards}
+	if err := generator.SetCardOptions(options); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return generator, true
+}
+
+// setSeedHeaders returns the seed and reference month a response was
+// generated with, which together reproduce it
+func setSeedHeaders(ctx *gin.Context, generator *faker.Generator) {
+	ctx.Header("X-Fake-Data-Seed", strconv.FormatInt(generator.Seed(), 10))
+	ctx.Header("X-Fake-Data-Month", generator.ReferenceMonth().Format("2006-01"))
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+)
+
+// HistoryController handles operations on users' breach check history
+type HistoryController struct {
+	client *mongo.Client
+	lookup *services.BreachLookup
+}
+
+// NewHistoryController creates a new history controller
+func NewHistoryController(client *mongo.Client, lookup *services.BreachLookup) *HistoryController {
+	return &HistoryController{
+		client: client,
+		lookup: lookup,
+	}
+}
+
+// ListHistory retrieves the authenticated user's breach checks, newest first.
+// Filter with ?kind= and, to see one identity, ?identityHash= as given in its
+// records. Checked values never go in the URL, since URLs end up in access
+// logs; SearchHistory takes them in the body instead.
+func (c *HistoryController) ListHistory(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+
+	if ctx.Query("value") != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "value is not accepted in the URL; use POST /api/history/search or ?identityHash="})
+		return
+	}
+	filter := bson.M{"userId": middleware.UserID(ctx)}
+	if kind := ctx.Query("kind"); kind != "" {
+		filter["kind"] = kind
+	}
+	if identityHash := ctx.Query("identityHash"); identityHash != "" {
+		filter["identityHash"] = identityHash
+	}
+
+	c.listHistory(ctx, filter, page, limit)
+}
+
+// SearchHistory retrieves the authenticated user's checks of one identity,
+// given in the body as the email, phone, username or password that was
+// checked
+func (c *HistoryController) SearchHistory(ctx *gin.Context) {
+	var request models.BreachHistorySearch
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Kind == "" || request.Value == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "kind and value are required"})
+		return
+	}
+
+	identityHash, ok := c.identityHash(request.Kind, request.Value, request.Region)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "value is not a valid " + request.Kind})
+		return
+	}
+	filter := bson.M{"userId": middleware.UserID(ctx), "kind": request.Kind, "identityHash": identityHash}
+	c.listHistory(ctx, filter, request.Page, request.Limit)
+}
+
+// listHistory writes one page of the checks matching filter, newest first
+func (c *HistoryController) listHistory(ctx *gin.Context, filter bson.M, page int, limit int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	collection := c.client.Database("safetrace").Collection("breach_history")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := collection.CountDocuments(dbCtx, filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count history"})
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "checkedAt", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(dbCtx, filter, opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	items := []models.BreachCheckRecord{}
+	if err := cursor.All(dbCtx, &items); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode history"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.BreachHistoryPage{
+		Items: items,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
+
+// DiffChecks compares two checks of the same identity, given as ?from= and
+// ?to= check IDs. Without ?to=, the latest check of the identity is used.
+func (c *HistoryController) DiffChecks(ctx *gin.Context) {
+	fromID, err := primitive.ObjectIDFromHex(ctx.Query("from"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("breach_history")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userID := middleware.UserID(ctx)
+	var from models.BreachCheckRecord
+	if err := collection.FindOne(dbCtx, bson.M{"_id": fromID, "userId": userID}).Decode(&from); err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "Check not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch check"})
+		return
+	}
+
+	filter := bson.M{"userId": userID, "kind": from.Kind, "identityHash": from.IdentityHash}
+	opts := options.FindOne()
+	if toParam := ctx.Query("to"); toParam != "" {
+		toID, err := primitive.ObjectIDFromHex(toParam)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+			return
+		}
+		filter["_id"] = toID
+	} else {
+		opts.SetSort(bson.D{{Key: "checkedAt", Value: -1}})
+	}
+
+	var to models.BreachCheckRecord
+	if err := collection.FindOne(dbCtx, filter, opts).Decode(&to); err != nil {
+		if err == mongo.ErrNoDocuments {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "No matching check of the same identity found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch check"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, diffChecks(from, to))
+}
+
+// identityHash returns the stored form of an identity for a history lookup.
+// Phone numbers are read in region, as CheckPhone reads them.
+func (c *HistoryController) identityHash(kind string, value string, region string) (string, bool) {
+	if kind == services.IdentityPassword {
+		return services.PasswordHashPrefix(value), true
+	}
+
+	normalized, ok := normalizeIdentity(kind, value, region)
+	if !ok {
+		return "", false
+	}
+	return c.lookup.IdentityHash(kind, normalized), true
+}
+
+// diffChecks lists the breaches added and removed between two checks
+func diffChecks(from models.BreachCheckRecord, to models.BreachCheckRecord) models.BreachCheckDiff {
+	diff := models.BreachCheckDiff{
+		Kind:         from.Kind,
+		IdentityHash: from.IdentityHash,
+		From:         from,
+		To:           to,
+		Added:        []string{},
+		Removed:      []string{},
+		Unchanged:    []string{},
+		CountChange:  to.Count - from.Count,
+	}
+
+	before := map[string]bool{}
+	for _, breach := range from.Breaches {
+		before[breach] = true
+	}
+	after := map[string]bool{}
+	for _, breach := range to.Breaches {
+		after[breach] = true
+		if before[breach] {
+			diff.Unchanged = append(diff.Unchanged, breach)
+		} else {
+			diff.Added = append(diff.Added, breach)
+		}
+	}
+	for _, breach := range from.Breaches {
+		if !after[breach] {
+			diff.Removed = append(diff.Removed, breach)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Unchanged)
+
+	if from.Severity != to.Severity {
+		diff.SeverityChange = from.Severity + " -> " + to.Severity
+	}
+	return diff
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+)
+
+// InboxController handles reading the disposable inboxes behind fake identities
+type InboxController struct {
+	inboxes *services.InboxService
+}
+
+// NewInboxController creates a new inbox controller
+func NewInboxController(inboxes *services.InboxService) *InboxController {
+	return &InboxController{
+		inboxes: inboxes,
+	}
+}
+
+// GetInbox retrieves an inbox's address and expiry
+func (c *InboxController) GetInbox(ctx *gin.Context) {
+	inbox, ok := c.inbox(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, inbox)
+}
+
+// ListMessages retrieves the messages that have arrived in an inbox
+func (c *InboxController) ListMessages(ctx *gin.Context) {
+	inbox, ok := c.inbox(ctx)
+	if !ok {
+		return
+	}
+
+	messages, err := c.inboxes.Messages(ctx.Request.Context(), inbox)
+	if err != nil {
+		mailServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, messages)
+}
+
+// GetMessage retrieves one message with its text and HTML bodies
+func (c *InboxController) GetMessage(ctx *gin.Context) {
+	inbox, ok := c.inbox(ctx)
+	if !ok {
+		return
+	}
+
+	message, err := c.inboxes.Message(ctx.Request.Context(), inbox, ctx.Param("messageId"))
+	if err != nil {
+		mailServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+}
+
+// DeleteInbox deletes an inbox before its TTL runs out
+func (c *InboxController) DeleteInbox(ctx *gin.Context) {
+	inbox, ok := c.inbox(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.inboxes.Delete(ctx.Request.Context(), inbox); err != nil {
+		mailServiceError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Inbox deleted successfully"})
+}
+
+// inbox loads the inbox named by the :id parameter, writing a 404 response
+// and returning false if it cannot be read
+func (c *InboxController) inbox(ctx *gin.Context) (*models.DisposableInbox, bool) {
+	inbox, err := c.inboxes.Get(ctx.Request.Context(), ctx.Param("id"), middleware.UserID(ctx))
+	if errors.Is(err, services.ErrInboxNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Inbox not found or expired"})
+		return nil, false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch inbox"})
+		return nil, false
+	}
+	return inbox, true
+}
+
+// mailServiceError writes the response for a failed mail service call
+func mailServiceError(ctx *gin.Context, err error) {
+	var rateLimitErr *services.RateLimitError
+	switch {
+	case errors.Is(err, services.ErrMailNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+	case errors.As(err, &rateLimitErr):
+		ctx.Header("Retry-After", strconv.Itoa(int(rateLimitErr.RetryAfter.Seconds())))
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Mail service is rate limiting requests, try again later"})
+	default:
+		log.Printf("Inboxes: mail service request failed: %v", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Mail service is unavailable"})
+	}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siddhantgureja/safetrace/faker"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// MaskController handles anonymizing uploaded records with fake data
+type MaskController struct {
+	maxBytes int64
+}
+
+// NewMaskController creates a new mask controller
+func NewMaskController() *MaskController {
+	return &MaskController{
+		maxBytes: int64(utils.EnvInt("MASK_MAX_BYTES", 32<<20)),
+	}
+}
+
+// MaskData replaces the personal data in an uploaded CSV or JSON file with
+// consistent fakes. The multipart form holds the file, its masking config
+// (see faker.ParseMaskConfig) and optionally a key: files masked with the
+// same key map equal values to equal fakes, so they can still be joined.
+//
+// Mapped columns missing from the file are an error, since a misspelt column
+// would otherwise go out unmasked, unless allowMissing is true.
+func (c *MaskController) MaskData(ctx *gin.Context) {
+	// Leave room for the config and the multipart framing around the file
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.maxBytes+maxSchemaBytes+4096)
+
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File must be at most " + strconv.FormatInt(c.maxBytes>>20, 10) + "MB"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > c.maxBytes {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File must be at most " + strconv.FormatInt(c.maxBytes>>20, 10) + "MB"})
+		return
+	}
+
+	config, err := faker.ParseMaskConfig([]byte(ctx.Request.FormValue("config")))
+	var configErrors faker.SchemaErrors
+	if errors.As(err, &configErrors) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid masking config", "details": configErrors})
+		return
+	}
+	masker, err := faker.NewMasker(config, ctx.Request.FormValue("key"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	allowMissing, err := strconv.ParseBool(ctx.DefaultPostForm("allowMissing", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "allowMissing must be true or false"})
+		return
+	}
+
+	// The whole output is buffered so nothing is sent if a column is missing
+	var output bytes.Buffer
+	stats, err := masker.Mask(file, &output, faker.MaskInputFormat(header.Filename))
+	if errors.Is(err, faker.ErrMaskCollision) {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file: " + err.Error()})
+		return
+	}
+	if len(stats.Unmatched) > 0 && !allowMissing {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Mapped columns not found in the file: " + strings.Join(stats.Unmatched, ", "),
+			"columns": stats.Unmatched,
+		})
+		return
+	}
+
+	ctx.Header("X-Masked-Records", strconv.Itoa(stats.Records))
+	ctx.Header("Content-Disposition", `attachment; filename="masked.`+stats.Format+`"`)
+	ctx.Data(http.StatusOK, faker.ContentTypes[stats.Format], output.Bytes())
+}
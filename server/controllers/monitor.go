@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// MonitorController handles operations on monitored identities and breach alerts
+type MonitorController struct {
+	client *mongo.Client
+}
+
+// NewMonitorController creates a new monitor controller
+func NewMonitorController(client *mongo.Client) *MonitorController {
+	return &MonitorController{
+		client: client,
+	}
+}
+
+// AddIdentity starts monitoring an identity for the authenticated user
+func (c *MonitorController) AddIdentity(ctx *gin.Context) {
+	var request models.MonitorRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, ok := normalizeIdentity(request.Kind, request.Value, "")
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "kind must be email, username or phone and value is required"})
+		return
+	}
+
+	now := time.Now()
+	identity := models.MonitoredIdentity{
+		UserID:        middleware.UserID(ctx),
+		Kind:          request.Kind,
+		Value:         value,
+		KnownBreaches: []string{},
+		NextCheckAt:   now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	collection := c.client.Database("safetrace").Collection("monitored_identities")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(dbCtx, identity)
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Identity is already monitored"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add monitored identity"})
+		return
+	}
+
+	identity.ID = result.InsertedID.(primitive.ObjectID)
+	ctx.JSON(http.StatusCreated, identity)
+}
+
+// ListIdentities retrieves the authenticated user's monitored identities
+func (c *MonitorController) ListIdentities(ctx *gin.Context) {
+	collection := c.client.Database("safetrace").Collection("monitored_identities")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+	cursor, err := collection.Find(dbCtx, bson.M{"userId": middleware.UserID(ctx)}, opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch monitored identities"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	identities := []models.MonitoredIdentity{}
+	if err := cursor.All(dbCtx, &identities); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode monitored identities"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, identities)
+}
+
+// DeleteIdentity stops monitoring an identity
+func (c *MonitorController) DeleteIdentity(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("monitored_identities")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete monitored identity"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Monitored identity not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Monitored identity deleted successfully"})
+}
+
+// ListAlerts retrieves the authenticated user's breach alerts, newest first
+func (c *MonitorController) ListAlerts(ctx *gin.Context) {
+	filter := bson.M{"userId": middleware.UserID(ctx)}
+	if ctx.Query("unacknowledged") == "true" {
+		filter["acknowledged"] = false
+	}
+
+	collection := c.client.Database("safetrace").Collection("breach_alerts")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(100)
+	cursor, err := collection.Find(dbCtx, filter, opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	alerts := []models.BreachAlert{}
+	if err := cursor.All(dbCtx, &alerts); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode alerts"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, alerts)
+}
+
+// AcknowledgeAlert marks a breach alert as seen
+func (c *MonitorController) AcknowledgeAlert(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("breach_alerts")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objID, "userId": middleware.UserID(ctx)}
+	result, err := collection.UpdateOne(dbCtx, filter, bson.M{"$set": bson.M{"acknowledged": true}})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Alert acknowledged"})
+}
+
+// normalizeIdentity returns the stored form of an identity value, reading
+// phone numbers without a country code in region or, if empty, the default
+func normalizeIdentity(kind string, value string, region string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", false
+	}
+
+	switch kind {
+	case services.IdentityEmail:
+		email, err := utils.NormalizeEmail(value, utils.EmailProviderRules())
+		if err != nil {
+			return "", false
+		}
+		return email.Canonical, true
+	case services.IdentityUsername:
+		username, err := utils.NormalizeUsername(value)
+		if err != nil {
+			return "", false
+		}
+		return username, true
+	case services.IdentityPhone:
+		if region == "" {
+			region = utils.DefaultPhoneRegion()
+		}
+		phone, err := utils.NormalizePhone(value, region)
+		if err != nil {
+			return "", false
+		}
+		return phone.E164, true
+	default:
+		return "", false
+	}
+}
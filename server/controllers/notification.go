@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/services"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// NotificationController handles operations on breach alert notification channels
+type NotificationController struct {
+	client   *mongo.Client
+	notifier *services.Notifier
+}
+
+// NewNotificationController creates a new notification controller
+func NewNotificationController(client *mongo.Client, notifier *services.Notifier) *NotificationController {
+	return &NotificationController{
+		client:   client,
+		notifier: notifier,
+	}
+}
+
+// ListChannels retrieves the authenticated user's notification channels
+func (c *NotificationController) ListChannels(ctx *gin.Context) {
+	collection := c.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(dbCtx, bson.M{"userId": middleware.UserID(ctx)})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channels"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	channels := []models.NotificationChannel{}
+	if err := cursor.All(dbCtx, &channels); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode channels"})
+		return
+	}
+
+	// Webhook secrets are only shown when a channel is created
+	for i := range channels {
+		channels[i].Secret = ""
+	}
+
+	ctx.JSON(http.StatusOK, channels)
+}
+
+// CreateChannel creates a new notification channel. For webhooks a signing
+// secret is generated and returned once. Email channels stay paused until
+// their address is verified from a link mailed to it, unless the user
+// already verified it for another channel.
+func (c *NotificationController) CreateChannel(ctx *gin.Context) {
+	var channel models.NotificationChannel
+	if err := ctx.ShouldBindJSON(&channel); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if message := validateChannel(&channel); message != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	if !c.checkWebhookURL(ctx, channel) {
+		return
+	}
+
+	now := time.Now()
+	channel.ID = primitive.NilObjectID
+	channel.UserID = middleware.UserID(ctx)
+	channel.Enabled = true
+	channel.EmailVerified, channel.VerifyTokenHash, channel.VerifySentAt = false, "", nil
+	channel.CreatedAt = now
+	channel.UpdatedAt = now
+	if channel.MinSeverity == "" {
+		channel.MinSeverity = "Low"
+	}
+
+	var secret string
+	if channel.Type == services.ChannelWebhook {
+		secretBytes := make([]byte, 32)
+		if _, err := rand.Read(secretBytes); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+			return
+		}
+		secret = hex.EncodeToString(secretBytes)
+
+		encrypted, err := utils.Encrypt(secret, "")
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt secret"})
+			return
+		}
+		channel.Secret = encrypted
+	} else {
+		channel.Secret = ""
+	}
+
+	collection := c.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(dbCtx, channel)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create channel"})
+		return
+	}
+
+	channel.ID = result.InsertedID.(primitive.ObjectID)
+	if channel.Type == services.ChannelEmail {
+		// The channel stays paused without verifySentAt, and the link can be resent
+		if err := c.notifier.SendVerification(ctx.Request.Context(), &channel); err != nil {
+			log.Printf("Notifier: failed to send verification for channel %s: %v", channel.ID.Hex(), err)
+		}
+	}
+	channel.Secret = secret
+
+	ctx.JSON(http.StatusCreated, channel)
+}
+
+// UpdateChannel updates the name, destination, minimum severity or enabled
+// state of a notification channel. The enabled state is only changed when
+// the request includes it. A new email address pauses the channel until it
+// is verified.
+func (c *NotificationController) UpdateChannel(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var request models.NotificationChannelUpdate
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	channel := models.NotificationChannel{
+		Type:        request.Type,
+		Name:        request.Name,
+		URL:         request.URL,
+		Email:       request.Email,
+		MinSeverity: request.MinSeverity,
+	}
+	if message := validateChannel(&channel); message != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	if !c.checkWebhookURL(ctx, channel) {
+		return
+	}
+	if channel.MinSeverity == "" {
+		channel.MinSeverity = "Low"
+	}
+
+	collection := c.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objID, "userId": middleware.UserID(ctx), "type": channel.Type}
+	var existing models.NotificationChannel
+	err = collection.FindOne(dbCtx, filter).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return
+	}
+
+	set := bson.M{
+		"name":        channel.Name,
+		"url":         channel.URL,
+		"email":       channel.Email,
+		"minSeverity": channel.MinSeverity,
+		"updatedAt":   time.Now(),
+	}
+	if request.Enabled != nil {
+		set["enabled"] = *request.Enabled
+	}
+	update := bson.M{"$set": set}
+	// Only the previous address was verified, so the new one has to be
+	emailChanged := channel.Type == services.ChannelEmail && !strings.EqualFold(channel.Email, existing.Email)
+	if emailChanged {
+		set["emailVerified"] = false
+		update["$unset"] = bson.M{"verifyTokenHash": "", "verifySentAt": ""}
+	}
+
+	result, err := collection.UpdateOne(dbCtx, filter, update)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update channel"})
+		return
+	}
+
+	if result.MatchedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if emailChanged {
+		existing.Email = channel.Email
+		if err := c.notifier.SendVerification(ctx.Request.Context(), &existing); err != nil {
+			log.Printf("Notifier: failed to send verification for channel %s: %v", existing.ID.Hex(), err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Channel updated successfully"})
+}
+
+// DeleteChannel deletes a notification channel
+func (c *NotificationController) DeleteChannel(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete channel"})
+		return
+	}
+
+	if result.DeletedCount == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Channel deleted successfully"})
+}
+
+// TestChannel sends a sample alert to a notification channel. Why a delivery
+// failed is only logged, so the endpoint cannot be used to probe networks.
+func (c *NotificationController) TestChannel(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var channel models.NotificationChannel
+	err = collection.FindOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)}).Decode(&channel)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return
+	}
+
+	if channel.Type == services.ChannelEmail && !channel.EmailVerified {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email address is not verified yet"})
+		return
+	}
+
+	alert := models.BreachAlert{
+		UserID:      channel.UserID,
+		Kind:        services.IdentityEmail,
+		Value:       "test@example.com",
+		NewBreaches: []string{"Example Breach"},
+		TotalCount:  1,
+		Severity:    utils.SeverityLevel(1),
+		Source:      "SafeTrace test",
+		CreatedAt:   time.Now(),
+	}
+
+	attempts, err := c.notifier.Deliver(ctx.Request.Context(), channel, alert)
+	if err != nil {
+		log.Printf("Notifier: test delivery to channel %s failed: %v", channel.ID.Hex(), err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Test notification could not be delivered", "attempts": attempts})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Test notification delivered", "attempts": attempts})
+}
+
+// ResendVerification mails a new verification link for an email channel
+// whose address is not verified yet
+func (c *NotificationController) ResendVerification(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var channel models.NotificationChannel
+	err = collection.FindOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)}).Decode(&channel)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Channel not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch channel"})
+		return
+	}
+	if channel.Type != services.ChannelEmail {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Only email channels are verified"})
+		return
+	}
+	if channel.EmailVerified {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email address is already verified"})
+		return
+	}
+
+	if err := c.notifier.SendVerification(ctx.Request.Context(), &channel); err != nil {
+		log.Printf("Notifier: failed to send verification for channel %s: %v", channel.ID.Hex(), err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	channel.Secret = ""
+	ctx.JSON(http.StatusOK, channel)
+}
+
+// VerifyChannel confirms an email channel's address from the link mailed to
+// it. It needs no authentication, since the token proves access to the
+// mailbox.
+func (c *NotificationController) VerifyChannel(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	channel, err := c.notifier.VerifyChannel(ctx.Request.Context(), token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email address"})
+		return
+	}
+	if channel == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "SafeTrace alerts will now be sent to " + channel.Email})
+}
+
+// ListDeadLetters retrieves alerts that could not be delivered
+func (c *NotificationController) ListDeadLetters(ctx *gin.Context) {
+	collection := c.client.Database("safetrace").Collection("dead_letters")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(100)
+	cursor, err := collection.Find(dbCtx, bson.M{"userId": middleware.UserID(ctx)}, opts)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dead letters"})
+		return
+	}
+	defer cursor.Close(dbCtx)
+
+	deadLetters := []models.DeadLetter{}
+	if err := cursor.All(dbCtx, &deadLetters); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode dead letters"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deadLetters)
+}
+
+// RetryDeadLetter redelivers an alert that previously failed
+func (c *NotificationController) RetryDeadLetter(ctx *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	collection := c.client.Database("safetrace").Collection("dead_letters")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var deadLetter models.DeadLetter
+	err = collection.FindOne(dbCtx, bson.M{"_id": objID, "userId": middleware.UserID(ctx)}).Decode(&deadLetter)
+	if err == mongo.ErrNoDocuments {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dead letter"})
+		return
+	}
+
+	err = c.notifier.RetryDeadLetter(ctx.Request.Context(), deadLetter)
+	if errors.Is(err, services.ErrChannelUnverified) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Email address is not verified yet"})
+		return
+	}
+	if err != nil {
+		log.Printf("Notifier: retry of dead letter %s failed: %v", deadLetter.ID.Hex(), err)
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "Alert could not be delivered"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Alert delivered"})
+}
+
+// checkWebhookURL rejects webhooks whose host resolves to a loopback,
+// private or link-local address, writing an error response and returning
+// false
+func (c *NotificationController) checkWebhookURL(ctx *gin.Context, channel models.NotificationChannel) bool {
+	if channel.Type != services.ChannelWebhook {
+		return true
+	}
+	if err := c.notifier.CheckWebhookURL(ctx.Request.Context(), channel.URL); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "url must be on a public host: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// validateChannel returns a message describing what is wrong with a channel,
+// or an empty string if it is valid. An email channel's address is reduced
+// to the bare address, without a display name.
+func validateChannel(channel *models.NotificationChannel) string {
+	switch channel.Type {
+	case services.ChannelWebhook:
+		parsed, err := url.Parse(channel.URL)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return "url must be an absolute http or https URL"
+		}
+	case services.ChannelEmail:
+		address, err := mail.ParseAddress(channel.Email)
+		if err != nil {
+			return "email must be a valid email address"
+		}
+		channel.Email = address.Address
+	default:
+		return "type must be webhook or email"
+	}
+
+	if channel.MinSeverity != "" && utils.SeverityRank(channel.MinSeverity) == 0 {
+		return "minSeverity must be Low, Medium or High"
+	}
+
+	return ""
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/passgen"
+	"github.com/siddhantgureja/safetrace/strength"
+)
+
+// PasswordController handles operations for password analysis
+type PasswordController struct{}
+
+// NewPasswordController creates a new password controller
+func NewPasswordController() *PasswordController {
+	return &PasswordController{}
+}
+
+// CheckStrength estimates how hard a password is to guess
+func (c *PasswordController) CheckStrength(ctx *gin.Context) {
+	var request models.PasswordStrengthRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Password == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, strength.Estimate(request.Password, passwordUserInputs(request)))
+}
+
+// passwordUserInputs collects the personal words a user might build a
+// password from: their name, email local part and email domain
+func passwordUserInputs(request models.PasswordStrengthRequest) []string {
+	inputs := append([]string{}, request.UserInputs...)
+	inputs = append(inputs, strings.Fields(request.Name)...)
+
+	if at := strings.LastIndex(request.Email, "@"); at > 0 {
+		local, domain := request.Email[:at], request.Email[at+1:]
+		inputs = append(inputs, local)
+		inputs = append(inputs, strings.FieldsFunc(local, func(r rune) bool {
+			return r == '.' || r == '_' || r == '+' || r == '-'
+		})...)
+		if label := strings.Split(domain, ".")[0]; label != "" {
+			inputs = append(inputs, label)
+		}
+	}
+
+	return inputs
+}
+
+// maxGenerateCount bounds how many passwords one request can generate
+const maxGenerateCount = 20
+
+// GeneratePassword generates random passwords from the requested character
+// classes, minimums and site rules
+func (c *PasswordController) GeneratePassword(ctx *gin.Context) {
+	var request models.PasswordGenerateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, ok := generateCount(ctx, request.Count)
+	if !ok {
+		return
+	}
+
+	policy := passgen.Policy{
+		Length:            request.Length,
+		Lowercase:         enabled(request.Lowercase),
+		Uppercase:         enabled(request.Uppercase),
+		Digits:            enabled(request.Digits),
+		Symbols:           enabled(request.Symbols),
+		MinLowercase:      request.MinLowercase,
+		MinUppercase:      request.MinUppercase,
+		MinDigits:         request.MinDigits,
+		MinSymbols:        request.MinSymbols,
+		ExcludeLookAlikes: request.ExcludeLookAlikes,
+		Exclude:           request.Exclude,
+	}
+	if request.Site != nil {
+		policy.Site = *request.Site
+	}
+
+	results := make([]models.GeneratedPassword, 0, count)
+	for i := 0; i < count; i++ {
+		result, err := passgen.Password(policy)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		results = append(results, result)
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// GeneratePassphrase generates diceware passphrases
+func (c *PasswordController) GeneratePassphrase(ctx *gin.Context) {
+	var request models.PassphraseGenerateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, ok := generateCount(ctx, request.Count)
+	if !ok {
+		return
+	}
+
+	policy := passgen.PassphrasePolicy{
+		Words:         request.Words,
+		Separator:     "-",
+		Capitalize:    request.Capitalize,
+		IncludeNumber: request.IncludeNumber,
+	}
+	if request.Separator != nil {
+		policy.Separator = *request.Separator
+	}
+
+	results := make([]models.GeneratedPassword, 0, count)
+	for i := 0; i < count; i++ {
+		result, err := passgen.Passphrase(policy)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		results = append(results, result)
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// generateCount checks the requested number of results, defaulting to 1,
+// writing a 400 response and returning false if it is out of range
+func generateCount(ctx *gin.Context, count int) (int, bool) {
+	if count == 0 {
+		return 1, true
+	}
+	if count < 1 || count > maxGenerateCount {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and " + strconv.Itoa(maxGenerateCount)})
+		return 0, false
+	}
+	return count, true
+}
+
+// enabled reads an optional flag that defaults to true
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
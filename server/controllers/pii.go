@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/pii"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// PIIController handles scanning text and files for personal data and secrets
+type PIIController struct {
+	maxBytes int64
+}
+
+// NewPIIController creates a new PII controller
+func NewPIIController() *PIIController {
+	return &PIIController{
+		maxBytes: int64(utils.EnvInt("PII_SCAN_MAX_BYTES", 10<<20)),
+	}
+}
+
+// ScanText finds personal data and secrets in submitted text
+func (c *PIIController) ScanText(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.maxBytes+4096)
+
+	var request models.PIIScanRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.tooLarge(ctx)
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Text == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Text is required"})
+		return
+	}
+
+	options := pii.Options{MinConfidence: pii.DefaultMinConfidence, Types: request.Types, Region: request.Region}
+	if request.MinConfidence != nil {
+		options.MinConfidence = *request.MinConfidence
+	}
+	c.scan(ctx, request.Text, pii.FormatText, options)
+}
+
+// ScanFile finds personal data and secrets in an uploaded plain text, CSV or
+// JSON file. CSV and JSON files are scanned field by field, and the column or
+// key name counts as context, so a column named "phone" makes its numbers more
+// likely to be reported as phone numbers.
+func (c *PIIController) ScanFile(ctx *gin.Context) {
+	// Leave room for the other fields and the multipart framing
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.maxBytes+4096)
+
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.tooLarge(ctx)
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > c.maxBytes {
+		c.tooLarge(ctx)
+		return
+	}
+
+	options := pii.Options{MinConfidence: pii.DefaultMinConfidence, Region: ctx.Request.FormValue("region")}
+	if value := ctx.Request.FormValue("minConfidence"); value != "" {
+		options.MinConfidence, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "minConfidence must be a number"})
+			return
+		}
+	}
+	if value := ctx.Request.FormValue("types"); value != "" {
+		for _, kind := range strings.Split(value, ",") {
+			options.Types = append(options.Types, strings.TrimSpace(kind))
+		}
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file"})
+		return
+	}
+	c.scan(ctx, string(data), pii.FormatFor(header.Filename), options)
+}
+
+func (c *PIIController) scan(ctx *gin.Context, input string, format string, options pii.Options) {
+	if err := options.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := pii.Scan(input, format, options)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the file as " + format + ": " + err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *PIIController) tooLarge(ctx *gin.Context) {
+	ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Input must be at most " + strconv.FormatInt(c.maxBytes>>20, 10) + "MB"})
+}
@@ -2,21 +2,37 @@ package controllers
 
 import (
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/siddhantgureja/safetrace/middleware"
 	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/risk"
+	"github.com/siddhantgureja/safetrace/services"
 )
 
 // RiskController handles operations for privacy risk analysis
-type RiskController struct{}
+type RiskController struct {
+	policies *risk.PolicyStore
+	evidence *services.RiskEvidence
+	history  *services.RiskHistory
+}
 
-// NewRiskController creates a new risk controller
-func NewRiskController() *RiskController {
-	return &RiskController{}
+// NewRiskController creates a new risk controller scoring with the policy
+// held by policies and the facts evidence verifies. Analyses by authenticated
+// users are recorded in history.
+func NewRiskController(policies *risk.PolicyStore, evidence *services.RiskEvidence, history *services.RiskHistory) *RiskController {
+	return &RiskController{policies: policies, evidence: evidence, history: history}
 }
 
-// AnalyzeRisk analyzes a user's privacy risk level
+// AnalyzeRisk analyzes a user's privacy risk level. Unless verify is false,
+// the email's breaches are looked up and, for authenticated users, the vault's
+// password health and monitored identities are checked; the answers given
+// only fill in what could not be verified. Factor IDs in whatIf are simulated
+// fixed together.
 func (c *RiskController) AnalyzeRisk(ctx *gin.Context) {
 	var request models.RiskAnalysisRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
@@ -24,146 +40,115 @@ func (c *RiskController) AnalyzeRisk(ctx *gin.Context) {
 		return
 	}
 
-	// Calculate risk score based on provided factors
-	score := calculateRiskScore(request)
-	riskLevel := getRiskLevel(score)
-	factors := getRiskFactors(request)
-	advice := getAdvice(request, factors)
-
-	response := models.RiskAnalysisResponse{
-		Score:     score,
-		RiskLevel: riskLevel,
-		Factors:   factors,
-		Advice:    advice,
-	}
-
-	ctx.JSON(http.StatusOK, response)
-}
-
-// calculateRiskScore calculates a risk score based on provided factors
-func calculateRiskScore(request models.RiskAnalysisRequest) int {
-	score := 50 // Base score
-
-	// Adjust based on password strength
-	if request.HasStrongPasswords {
-		score -= 15
-	} else {
-		score += 15
-	}
-
-	// Adjust based on 2FA usage
-	if request.Uses2FA {
-		score -= 20
-	} else {
-		score += 10
-	}
-
-	// Adjust based on social media presence
-	if request.UsesSocialMedia {
-		score += 5
-		score += len(request.PublicProfiles) * 2
-	}
-
-	// Adjust based on data breaches
-	if request.HasDataBreaches {
-		score += 25
+	userID := middleware.UserID(ctx)
+	var verified risk.Facts
+	if request.Verify == nil || *request.Verify {
+		verified = c.evidence.Gather(ctx.Request.Context(), userID, request.Email)
 	}
 
-	// Adjust based on personal info sharing
-	if request.SharesPersonalInfo {
-		score += 15
+	policy := c.policies.Policy()
+	facts := risk.FactsFor(request, verified)
+	response := policy.Evaluate(facts)
+	if len(request.WhatIf) > 0 {
+		simulation, err := policy.Simulate(facts, request.WhatIf)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "whatIf: " + err.Error()})
+			return
+		}
+		response.Simulation = &simulation
 	}
 
-	// Cap score between 0 and 100
-	if score < 0 {
-		score = 0
-	} else if score > 100 {
-		score = 100
+	if userID != "" {
+		factors := make([]string, 0, len(response.Factors))
+		for _, factor := range response.Factors {
+			factors = append(factors, factor.ID)
+		}
+		inputs, verifiedInputs := facts.Snapshot()
+		go c.history.Record(models.RiskAssessment{
+			UserID:        userID,
+			Score:         response.Score,
+			RiskLevel:     response.RiskLevel,
+			Factors:       factors,
+			Inputs:        inputs,
+			Verified:      verifiedInputs,
+			PolicyVersion: response.PolicyVersion,
+			CreatedAt:     time.Now(),
+		})
 	}
 
-	return score
-}
-
-// getRiskLevel determines the risk level based on the score
-func getRiskLevel(score int) string {
-	if score < 30 {
-		return "Low"
-	} else if score < 70 {
-		return "Medium"
-	} else {
-		return "High"
-	}
+	ctx.JSON(http.StatusOK, response)
 }
 
-// getRiskFactors identifies specific risk factors
-func getRiskFactors(request models.RiskAnalysisRequest) []string {
-	var factors []string
-
-	if !request.HasStrongPasswords {
-		factors = append(factors, "Weak password usage")
-	}
-
-	if !request.Uses2FA {
-		factors = append(factors, "No two-factor authentication")
-	}
-
-	if request.UsesSocialMedia && len(request.PublicProfiles) > 2 {
-		factors = append(factors, "High social media presence")
-	}
-
-	if request.HasDataBreaches {
-		factors = append(factors, "Involved in previous data breaches")
-	}
-
-	if request.SharesPersonalInfo {
-		factors = append(factors, "Shares sensitive personal information online")
+// GetHistory returns the authenticated user's risk scores over time with how
+// each assessment changed from the one before. ?since= takes an RFC 3339 time
+// and ?limit= caps the number of assessments, keeping the latest.
+func (c *RiskController) GetHistory(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "52"))
+	if limit < 1 || limit > 500 {
+		limit = 52
+	}
+
+	var since time.Time
+	if value := ctx.Query("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time, such as 2024-01-31T00:00:00Z"})
+			return
+		}
 	}
 
-	// Email domain risk factor
-	if strings.HasSuffix(request.Email, "@gmail.com") ||
-		strings.HasSuffix(request.Email, "@yahoo.com") ||
-		strings.HasSuffix(request.Email, "@hotmail.com") {
-		factors = append(factors, "Using common email provider")
+	response, err := c.history.Trend(ctx.Request.Context(), middleware.UserID(ctx), since, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch risk history"})
+		return
 	}
-
-	return factors
+	ctx.JSON(http.StatusOK, response)
 }
 
-// getAdvice provides personalized advice based on risk factors
-func getAdvice(request models.RiskAnalysisRequest, factors []string) []string {
-	var advice []string
-
-	// Password advice
-	if !request.HasStrongPasswords {
-		advice = append(advice, "Use stronger, unique passwords for each account. Consider a password manager.")
+// GetAssessment returns one of the authenticated user's assessments with the
+// inputs it was scored on
+func (c *RiskController) GetAssessment(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
 	}
 
-	// 2FA advice
-	if !request.Uses2FA {
-		advice = append(advice, "Enable two-factor authentication on all accounts that support it.")
+	assessment, err := c.history.Assessment(ctx.Request.Context(), middleware.UserID(ctx), id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch assessment"})
+		return
 	}
-
-	// Social media advice
-	if request.UsesSocialMedia {
-		if len(request.PublicProfiles) > 0 {
-			advice = append(advice, "Review privacy settings on your social media accounts. Consider making profiles private.")
-		}
-		advice = append(advice, "Be cautious about the information you share on social media.")
+	if assessment == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		return
 	}
+	ctx.JSON(http.StatusOK, assessment)
+}
 
-	// Data breach advice
-	if request.HasDataBreaches {
-		advice = append(advice, "Change passwords for all affected accounts and monitor for suspicious activity.")
+// ListDigests returns the authenticated user's weekly risk digests, newest
+// first
+func (c *RiskController) ListDigests(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "12"))
+	if limit < 1 || limit > 100 {
+		limit = 12
 	}
 
-	// Personal info advice
-	if request.SharesPersonalInfo {
-		advice = append(advice, "Limit the personal information you share online, especially on public forums.")
+	digests, err := c.history.Digests(ctx.Request.Context(), middleware.UserID(ctx), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch digests"})
+		return
 	}
+	ctx.JSON(http.StatusOK, digests)
+}
 
-	// General advice
-	advice = append(advice, "Regularly check for data breaches involving your accounts.")
-	advice = append(advice, "Use a VPN when connecting to public WiFi networks.")
-
-	return advice
-} 
\ No newline at end of file
+// GetPolicy returns the scoring policy in use, so a tuned policy can be
+// checked after it is reloaded
+func (c *RiskController) GetPolicy(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"source":   c.policies.Source(),
+		"loadedAt": c.policies.LoadedAt(),
+		"facts":    risk.FactNames(),
+		"policy":   c.policies.Policy(),
+	})
+}
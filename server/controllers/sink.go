@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/services"
+)
+
+// sinkHeartbeat is how often an idle message stream sends a keep-alive
+const sinkHeartbeat = 30 * time.Second
+
+// SinkController handles reading mail received by the built-in SMTP sink
+type SinkController struct {
+	sink *services.SMTPSink
+}
+
+// NewSinkController creates a new sink controller
+func NewSinkController(sink *services.SMTPSink) *SinkController {
+	return &SinkController{
+		sink: sink,
+	}
+}
+
+// checkAlias writes a not-found response and returns false unless the alias
+// in the path belongs to the signed-in user
+func (c *SinkController) checkAlias(ctx *gin.Context) bool {
+	err := c.sink.CheckOwner(ctx.Request.Context(), ctx.Param("alias"), middleware.UserID(ctx))
+	if errors.Is(err, services.ErrSinkAliasNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+		return false
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alias"})
+		return false
+	}
+	return true
+}
+
+// ListMessages retrieves an alias's messages, newest first, without bodies
+func (c *SinkController) ListMessages(ctx *gin.Context) {
+	if !c.checkAlias(ctx) {
+		return
+	}
+
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	messages, err := c.sink.Messages(ctx.Request.Context(), ctx.Param("alias"), int64(limit))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch messages"})
+		return
+	}
+	ctx.JSON(http.StatusOK, messages)
+}
+
+// GetMessage retrieves one message with its text and HTML bodies
+func (c *SinkController) GetMessage(ctx *gin.Context) {
+	if !c.checkAlias(ctx) {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	message, err := c.sink.Message(ctx.Request.Context(), ctx.Param("alias"), id)
+	if errors.Is(err, services.ErrSinkMessageNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message"})
+		return
+	}
+	ctx.JSON(http.StatusOK, message)
+}
+
+// GetAttachment downloads one attachment of a message
+func (c *SinkController) GetAttachment(ctx *gin.Context) {
+	if !c.checkAlias(ctx) {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	message, err := c.sink.Message(ctx.Request.Context(), ctx.Param("alias"), id)
+	if errors.Is(err, services.ErrSinkMessageNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch message"})
+		return
+	}
+
+	for _, attachment := range message.Attachments {
+		if attachment.ID != ctx.Param("attachmentId") {
+			continue
+		}
+		filename := attachment.Filename
+		if filename == "" {
+			filename = "attachment-" + attachment.ID
+		}
+		// Always download, so HTML or SVG attachments cannot run in our origin
+		ctx.Header("Content-Disposition", "attachment; filename="+strconv.Quote(filename))
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Data(http.StatusOK, "application/octet-stream", attachment.Data)
+		return
+	}
+	ctx.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+}
+
+// DeleteMessage deletes one message before its TTL runs out
+func (c *SinkController) DeleteMessage(ctx *gin.Context) {
+	if !c.checkAlias(ctx) {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	err = c.sink.DeleteMessage(ctx.Request.Context(), ctx.Param("alias"), id)
+	if errors.Is(err, services.ErrSinkMessageNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "Message deleted successfully"})
+}
+
+// StreamMessages sends each new message for an alias as a Server-Sent Event
+// named "message", with periodic "ping" events to keep the connection open
+func (c *SinkController) StreamMessages(ctx *gin.Context) {
+	if !c.checkAlias(ctx) {
+		return
+	}
+
+	messages, unsubscribe := c.sink.Subscribe(ctx.Param("alias"))
+	defer unsubscribe()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Writer.WriteHeaderNow()
+	ctx.Writer.Flush()
+	heartbeat := time.NewTicker(sinkHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case message := <-messages:
+			ctx.SSEvent("message", message)
+			return true
+		case <-heartbeat.C:
+			ctx.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
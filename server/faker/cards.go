@@ -0,0 +1,310 @@
+package faker
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Card is a generated payment card
+type Card struct {
+	Network string `json:"network"`
+	Number  string `json:"number"`
+	Expiry  string `json:"expiry"`
+	CVV     string `json:"cvv"`
+}
+
+// CardNetwork describes how a card network numbers its cards
+type CardNetwork struct {
+	Name      string
+	iins      []iinRange
+	lengths   []int
+	groups    []int // digits per group for the network's first length
+	cvvLength int
+	weight    int // how often the network is picked when none is requested
+	// testNumbers are numbers documented by payment processors as test cards
+	// that are never issued
+	testNumbers []string
+}
+
+// iinRange is an inclusive range of issuer identification number prefixes,
+// all with the same number of digits
+type iinRange struct {
+	from, to int
+}
+
+// CardNetworks are the supported networks, keyed by their query value
+var CardNetworks = map[string]*CardNetwork{
+	"visa": {
+		Name:        "Visa",
+		iins:        []iinRange{{4, 4}},
+		lengths:     []int{16},
+		groups:      []int{4, 4, 4, 4},
+		cvvLength:   3,
+		weight:      40,
+		testNumbers: []string{"4242424242424242", "4111111111111111", "4012888888881881", "4000056655665556"},
+	},
+	"mastercard": {
+		Name:        "Mastercard",
+		iins:        []iinRange{{51, 55}, {2221, 2720}},
+		lengths:     []int{16},
+		groups:      []int{4, 4, 4, 4},
+		cvvLength:   3,
+		weight:      30,
+		testNumbers: []string{"5555555555554444", "5105105105105100", "2223003122003222", "2223000048400011"},
+	},
+	"amex": {
+		Name:        "American Express",
+		iins:        []iinRange{{34, 34}, {37, 37}},
+		lengths:     []int{15},
+		groups:      []int{4, 6, 5},
+		cvvLength:   4,
+		weight:      10,
+		testNumbers: []string{"378282246310005", "371449635398431", "378734493671000"},
+	},
+	"discover": {
+		Name:        "Discover",
+		iins:        []iinRange{{6011, 6011}, {644, 649}, {65, 65}},
+		lengths:     []int{16},
+		groups:      []int{4, 4, 4, 4},
+		cvvLength:   3,
+		weight:      8,
+		testNumbers: []string{"6011111111111117", "6011000990139424", "6011981111111113"},
+	},
+	"jcb": {
+		Name:        "JCB",
+		iins:        []iinRange{{3528, 3589}},
+		lengths:     []int{16},
+		groups:      []int{4, 4, 4, 4},
+		cvvLength:   3,
+		weight:      6,
+		testNumbers: []string{"3530111333300000", "3566002020360505"},
+	},
+	"unionpay": {
+		Name: "UnionPay",
+		// 622126-622925 is shared with Discover, so it is left out to keep
+		// the network unambiguous
+		iins:        []iinRange{{620000, 622125}, {622926, 629999}},
+		lengths:     []int{16, 19},
+		groups:      []int{4, 4, 4, 4},
+		cvvLength:   3,
+		weight:      6,
+		testNumbers: []string{"6200000000000005", "6205500000000000004"},
+	},
+}
+
+// cardNetworkOrder fixes the order networks are picked in, since map order
+// is random and would break seeded output
+var cardNetworkOrder = []string{"visa", "mastercard", "amex", "discover", "jcb", "unionpay"}
+
+// CardOptions restricts the cards a generator produces
+type CardOptions struct {
+	// Network is a key of CardNetworks; empty picks a network at random
+	Network string
+	// TestOnly limits numbers to documented test card numbers
+	TestOnly bool
+}
+
+// SetCardOptions restricts the cards produced by Card, CreditCard and Record
+func (g *Generator) SetCardOptions(options CardOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+	g.cards = options
+	return nil
+}
+
+func (o CardOptions) validate() error {
+	if o.Network != "" && CardNetworks[o.Network] == nil {
+		return fmt.Errorf("card network must be one of %s", strings.Join(cardNetworkOrder, ", "))
+	}
+	return nil
+}
+
+// capacity estimates how many distinct numbers the options allow
+func (o CardOptions) capacity() float64 {
+	total := 0.0
+	for _, key := range cardNetworkOrder {
+		if o.Network != "" && key != o.Network {
+			continue
+		}
+		network := CardNetworks[key]
+		if o.TestOnly {
+			total += float64(len(network.testNumbers))
+			continue
+		}
+		for _, iin := range network.iins {
+			prefixes := float64(iin.to - iin.from + 1)
+			digits := len(strconv.Itoa(iin.from))
+			for _, length := range network.lengths {
+				total += prefixes * math.Pow(10, float64(length-digits-1))
+			}
+		}
+	}
+	return total
+}
+
+// Card generates a card with a valid number, an expiry date in the next five
+// years and a security code of the network's length
+func (g *Generator) Card() Card {
+	return g.card(g.cards)
+}
+
+func (g *Generator) card(options CardOptions) Card {
+	network := g.cardNetwork(options.Network)
+
+	var number string
+	if options.TestOnly {
+		number = g.pick(network.testNumbers)
+	} else {
+		number = g.cardNumber(network)
+	}
+
+	cvv := ""
+	for i := 0; i < network.cvvLength; i++ {
+		cvv += g.digit()
+	}
+
+	// Expiry dates are relative to the reference month, so they stay valid
+	// for form testing. Counting from the first of the month keeps AddDate
+	// from spilling into the following month.
+	expiry := g.month.AddDate(0, 1+g.rng.Intn(60), 0)
+
+	return Card{
+		Network: network.Name,
+		Number:  groupCardNumber(number, network),
+		Expiry:  expiry.Format("01/06"),
+		CVV:     cvv,
+	}
+}
+
+// CreditCard generates a card number grouped the way the network prints it
+func (g *Generator) CreditCard() string {
+	return g.Card().Number
+}
+
+// cardNetwork returns the named network or a weighted random one
+func (g *Generator) cardNetwork(name string) *CardNetwork {
+	if name != "" {
+		return CardNetworks[name]
+	}
+
+	total := 0
+	for _, key := range cardNetworkOrder {
+		total += CardNetworks[key].weight
+	}
+	n := g.rng.Intn(total)
+	for _, key := range cardNetworkOrder {
+		if n < CardNetworks[key].weight {
+			return CardNetworks[key]
+		}
+		n -= CardNetworks[key].weight
+	}
+	return CardNetworks["visa"]
+}
+
+// cardNumber generates an ungrouped number in one of the network's IIN
+// ranges, ending in a Luhn check digit
+func (g *Generator) cardNumber(network *CardNetwork) string {
+	iin := network.iins[g.rng.Intn(len(network.iins))]
+	length := network.lengths[g.rng.Intn(len(network.lengths))]
+
+	number := strconv.Itoa(iin.from + g.rng.Intn(iin.to-iin.from+1))
+	for len(number) < length-1 {
+		number += g.digit()
+	}
+	return number + string(luhnCheckDigit(number))
+}
+
+// luhnCheckDigit returns the digit that makes number pass the Luhn check
+func luhnCheckDigit(number string) byte {
+	sum := 0
+	double := true
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// ValidCardNumber reports whether number, written as digits only, has a
+// plausible length and passes the Luhn check
+func ValidCardNumber(number string) bool {
+	if len(number) < 12 || len(number) > 19 || strings.Trim(number, "0123456789") != "" {
+		return false
+	}
+	return luhnCheckDigit(number[:len(number)-1]) == number[len(number)-1]
+}
+
+// IdentifyCardNetwork returns the network whose IIN ranges and lengths match
+// a number written as digits only, or nil if none does
+func IdentifyCardNetwork(number string) *CardNetwork {
+	for _, key := range cardNetworkOrder {
+		network := CardNetworks[key]
+		if !containsInt(network.lengths, len(number)) {
+			continue
+		}
+		for _, iin := range network.iins {
+			width := len(strconv.Itoa(iin.from))
+			if len(number) < width {
+				continue
+			}
+			prefix, _ := strconv.Atoi(number[:width])
+			if prefix >= iin.from && prefix <= iin.to {
+				return network
+			}
+		}
+	}
+	return nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// groupCardNumber splits a number into the network's printed groups, falling
+// back to groups of four for other lengths
+func groupCardNumber(number string, network *CardNetwork) string {
+	groups := network.groups
+	if sumInts(groups) != len(number) {
+		groups = nil
+		for remaining := len(number); remaining > 0; remaining -= 4 {
+			groups = append(groups, minInt(4, remaining))
+		}
+	}
+
+	parts := make([]string, 0, len(groups))
+	for _, size := range groups {
+		parts = append(parts, number[:size])
+		number = number[size:]
+	}
+	return strings.Join(parts, " ")
+}
+
+func sumInts(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
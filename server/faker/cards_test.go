@@ -0,0 +1,103 @@
+package faker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardExpiry(t *testing.T) {
+	tests := []struct {
+		name  string
+		month time.Time
+		first string
+		last  string
+	}{
+		{"start of month", time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), "04/26", "03/31"},
+		{"31st of the month", time.Date(2026, time.January, 31, 23, 59, 0, 0, time.UTC), "02/26", "01/31"},
+		{"31st before a short month", time.Date(2026, time.August, 31, 12, 0, 0, 0, time.UTC), "09/26", "08/31"},
+		{"end of year", time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC), "01/27", "12/31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, _ := time.Parse("01/06", tt.first)
+			last, _ := time.Parse("01/06", tt.last)
+
+			for seed := int64(0); seed < 500; seed++ {
+				g := New(seed)
+				g.SetReferenceMonth(tt.month)
+				expiry, err := time.Parse("01/06", g.Card().Expiry)
+				if err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				if expiry.Before(first) || expiry.After(last) {
+					t.Fatalf("seed %d: expiry %s outside %s to %s", seed, expiry.Format("01/06"), tt.first, tt.last)
+				}
+			}
+		})
+	}
+}
+
+func TestCardExpiryReproducible(t *testing.T) {
+	month := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	for seed := int64(0); seed < 100; seed++ {
+		a, b := New(seed), New(seed)
+		a.SetReferenceMonth(month)
+		b.SetReferenceMonth(month.AddDate(0, 0, 12))
+		if a.Card() != b.Card() {
+			t.Fatalf("seed %d: cards differ within the same month", seed)
+		}
+	}
+}
+
+func TestCardNumbersMatchTheirNetwork(t *testing.T) {
+	for _, key := range cardNetworkOrder {
+		network := CardNetworks[key]
+		for _, testOnly := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s test only %v", key, testOnly), func(t *testing.T) {
+				g := New(1)
+				if err := g.SetCardOptions(CardOptions{Network: key, TestOnly: testOnly}); err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 200; i++ {
+					card := g.Card()
+					number := strings.ReplaceAll(card.Number, " ", "")
+					if card.Network != network.Name {
+						t.Fatalf("%s is a %s card, want %s", card.Number, card.Network, network.Name)
+					}
+					if !containsInt(network.lengths, len(number)) {
+						t.Fatalf("%s has %d digits, want one of %v", card.Number, len(number), network.lengths)
+					}
+					if !ValidCardNumber(number) {
+						t.Fatalf("%s fails the Luhn check", card.Number)
+					}
+					if identified := IdentifyCardNetwork(number); identified != network {
+						t.Fatalf("%s is identified as %v, want %s", card.Number, identified, network.Name)
+					}
+					if len(card.CVV) != network.cvvLength {
+						t.Fatalf("%s has the security code %s, want %d digits", card.Number, card.CVV, network.cvvLength)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCardNetworkPickedAtRandom(t *testing.T) {
+	g := New(1)
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		card := g.Card()
+		number := strings.ReplaceAll(card.Number, " ", "")
+		identified := IdentifyCardNetwork(number)
+		if !ValidCardNumber(number) || identified == nil || identified.Name != card.Network {
+			t.Fatalf("%s is not a valid %s number", card.Number, card.Network)
+		}
+		seen[card.Network] = true
+	}
+	if len(seen) != len(cardNetworkOrder) {
+		t.Errorf("1000 cards only came from %v", seen)
+	}
+}
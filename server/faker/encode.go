@@ -0,0 +1,221 @@
+package faker
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Output formats supported by NewEncoder
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatSQL    = "sql"
+)
+
+// ContentTypes maps each output format to its MIME type
+var ContentTypes = map[string]string{
+	FormatJSON:   "application/json; charset=utf-8",
+	FormatNDJSON: "application/x-ndjson; charset=utf-8",
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatSQL:    "application/sql; charset=utf-8",
+}
+
+// sqlIdentifier matches table names that are safe to use unquoted, optionally
+// qualified with a schema
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Encoder writes records one at a time, so output can be streamed without
+// holding every record in memory. Each record holds one value per column.
+type Encoder interface {
+	Begin() error
+	Encode(values []interface{}) error
+	End() error
+}
+
+// NewEncoder creates an encoder for format writing to w. table names the
+// table for SQL output and is ignored otherwise.
+func NewEncoder(format string, w io.Writer, columns []string, table string) (Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonEncoder{w: w, columns: columns}, nil
+	case FormatNDJSON:
+		return &jsonEncoder{w: w, columns: columns, lines: true}, nil
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w), columns: columns}, nil
+	case FormatSQL:
+		if !sqlIdentifier.MatchString(table) {
+			return nil, fmt.Errorf("table %q must be a plain SQL identifier", table)
+		}
+		return &sqlEncoder{w: w, columns: columns, table: table}, nil
+	default:
+		return nil, fmt.Errorf("format must be one of json, ndjson, csv or sql")
+	}
+}
+
+// jsonEncoder writes records as a JSON array, or as newline-delimited JSON
+// objects when lines is set. Keys keep the column order.
+type jsonEncoder struct {
+	w       io.Writer
+	columns []string
+	lines   bool
+	count   int
+}
+
+func (e *jsonEncoder) Begin() error {
+	if e.lines {
+		return nil
+	}
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonEncoder) Encode(values []interface{}) error {
+	var b strings.Builder
+	if !e.lines && e.count > 0 {
+		b.WriteString(",")
+	}
+	b.WriteString("{")
+	for i, column := range e.columns {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	if e.lines {
+		b.WriteString("\n")
+	}
+	e.count++
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *jsonEncoder) End() error {
+	if e.lines {
+		return nil
+	}
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// csvEncoder writes a header row followed by one row per record. Nested
+// values are written as JSON and nulls as empty cells.
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (e *csvEncoder) Begin() error {
+	return e.w.Write(e.columns)
+}
+
+func (e *csvEncoder) Encode(values []interface{}) error {
+	row := make([]string, len(values))
+	for i, value := range values {
+		cell, err := textValue(value)
+		if err != nil {
+			return err
+		}
+		row[i] = cell
+	}
+	if err := e.w.Write(row); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// sqlEncoder writes one INSERT statement per record
+type sqlEncoder struct {
+	w       io.Writer
+	columns []string
+	table   string
+	prefix  string
+}
+
+func (e *sqlEncoder) Begin() error {
+	quoted := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		quoted[i] = `"` + strings.ReplaceAll(column, `"`, `""`) + `"`
+	}
+	e.prefix = "INSERT INTO " + e.table + " (" + strings.Join(quoted, ", ") + ") VALUES ("
+	return nil
+}
+
+func (e *sqlEncoder) Encode(values []interface{}) error {
+	literals := make([]string, len(values))
+	for i, value := range values {
+		literal, err := sqlLiteral(value)
+		if err != nil {
+			return err
+		}
+		literals[i] = literal
+	}
+	_, err := io.WriteString(e.w, e.prefix+strings.Join(literals, ", ")+");\n")
+	return err
+}
+
+func (e *sqlEncoder) End() error {
+	return nil
+}
+
+// textValue formats a value for a CSV cell
+func textValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
+
+// sqlLiteral formats a value as a standard SQL literal
+func sqlLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int, int64, float64:
+		return textValue(v)
+	default:
+		text, err := textValue(v)
+		if err != nil {
+			return "", err
+		}
+		return "'" + strings.ReplaceAll(text, "'", "''") + "'", nil
+	}
+}
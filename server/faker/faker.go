@@ -0,0 +1,242 @@
+// Package faker generates realistic-looking fake personal data. Every
+// Generator owns its random source, so the same seed always produces the same
+// records and concurrent generators never share state.
+package faker
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// Generator produces fake data from its own seeded random source. A
+// Generator is not safe for concurrent use; create one per request.
+type Generator struct {
+	seed   int64
+	rng    *rand.Rand
+	locale *Locale
+	cards  CardOptions
+	// month is the first day of the month dates such as card expiries are
+	// relative to
+	month time.Time
+}
+
+// New creates a generator whose output is determined by seed and, for card
+// expiry dates, the reference month, which defaults to the current one
+func New(seed int64) *Generator {
+	locale, _ := LookupLocale(DefaultLocale)
+	return &Generator{
+		seed:   seed,
+		rng:    rand.New(rand.NewSource(seed)),
+		locale: locale,
+		month:  firstOfMonth(time.Now()),
+	}
+}
+
+// NewRandom creates a generator with an unpredictable seed, which can be read
+// back with Seed to reproduce its output
+func NewRandom() *Generator {
+	var buf [8]byte
+	crand.Read(buf[:])
+	// Keep seeds positive so they round-trip through query strings unchanged
+	return New(int64(binary.BigEndian.Uint64(buf[:]) >> 1))
+}
+
+// Seed returns the seed the generator was created with
+func (g *Generator) Seed() int64 {
+	return g.seed
+}
+
+// SetLocale switches the names, addresses and phone numbers the generator
+// produces to the locale with the given code
+func (g *Generator) SetLocale(code string) error {
+	locale, ok := LookupLocale(code)
+	if !ok {
+		return fmt.Errorf("locale must be one of %s", strings.Join(LocaleCodes(), ", "))
+	}
+	g.locale = locale
+	return nil
+}
+
+// SetReferenceMonth sets the month that generated dates are relative to.
+// Only the year and month of t are used.
+func (g *Generator) SetReferenceMonth(t time.Time) {
+	g.month = firstOfMonth(t)
+}
+
+// ReferenceMonth returns the first day of the month generated dates are
+// relative to
+func (g *Generator) ReferenceMonth() time.Time {
+	return g.month
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Locale returns the locale the generator uses
+func (g *Generator) Locale() *Locale {
+	return g.locale
+}
+
+// Record generates one complete fake identity
+func (g *Generator) Record() models.FakeDataResponse {
+	name := g.Name()
+	email := g.emailFor(name)
+	address := g.Address()
+	phone := g.Phone()
+	card := g.Card()
+
+	return models.FakeDataResponse{
+		Name:        name,
+		Email:       email,
+		Address:     address,
+		Phone:       phone,
+		CreditCard:  card.Number,
+		CardNetwork: card.Network,
+		CardExpiry:  card.Expiry,
+		CardCVV:     card.CVV,
+		Username:    g.Username(),
+		Password:    g.Password(),
+		IBAN:        g.localIdentifier("iban"),
+		NationalID:  g.localIdentifier(g.locale.NationalID),
+		Passport:    g.localIdentifier("passport"),
+	}
+}
+
+// localIdentifier generates an identifier of the given kind for the locale's
+// country, or "" if the kind has no format for it
+func (g *Generator) localIdentifier(kind string) string {
+	k := IdentifierKinds[kind]
+	if k == nil || (k.countries != nil && !containsString(k.countries, g.locale.Country)) {
+		return ""
+	}
+	value, _ := g.Identifier(kind, g.locale.Country)
+	return value
+}
+
+// RecordColumns are the fields of a record, in output order
+var RecordColumns = []string{"name", "email", "address", "phone", "creditCard", "cardNetwork", "cardExpiry", "cardCvv", "username", "password", "iban", "nationalId", "passport"}
+
+// RecordValues returns the fields of a record in RecordColumns order
+func RecordValues(record models.FakeDataResponse) []interface{} {
+	return []interface{}{
+		record.Name,
+		record.Email,
+		record.Address,
+		record.Phone,
+		record.CreditCard,
+		record.CardNetwork,
+		record.CardExpiry,
+		record.CardCVV,
+		record.Username,
+		record.Password,
+		record.IBAN,
+		record.NationalID,
+		record.Passport,
+	}
+}
+
+var usernameAdjectives = []string{
+	"happy", "sunny", "clever", "brave", "mighty", "kind", "swift", "bright",
+	"cool", "epic", "awesome", "super", "mega", "ultra", "hyper", "alpha",
+}
+
+var usernameNouns = []string{
+	"tiger", "eagle", "ninja", "wizard", "ranger", "warrior", "hunter", "falcon",
+	"dragon", "knight", "hero", "champion", "titan", "legend", "master", "chief",
+}
+
+const passwordCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+"
+
+// pick returns a random element of options
+func (g *Generator) pick(options []string) string {
+	return options[g.rng.Intn(len(options))]
+}
+
+// digit returns a random decimal digit
+func (g *Generator) digit() string {
+	return string(rune('0' + g.rng.Intn(10)))
+}
+
+// Name generates a full name, in the order the locale writes it
+func (g *Generator) Name() string {
+	first, last := g.FirstName(), g.LastName()
+	if g.locale.FamilyNameFirst {
+		return last + " " + first
+	}
+	return first + " " + last
+}
+
+// FirstName generates a given name
+func (g *Generator) FirstName() string {
+	return g.pick(g.locale.FirstNames)
+}
+
+// LastName generates a family name
+func (g *Generator) LastName() string {
+	return g.pick(g.locale.LastNames)
+}
+
+// UUID generates a random (version 4) UUID
+func (g *Generator) UUID() string {
+	var b [16]byte
+	g.rng.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Email generates an email address from a random name
+func (g *Generator) Email() string {
+	return g.emailFor(g.Name())
+}
+
+// emailFor generates an email address for the person called name
+func (g *Generator) emailFor(name string) string {
+	username := emailTransliterations.Replace(strings.ToLower(name))
+	username = strings.Replace(username, " ", ".", -1)
+	return username + "@" + g.pick(g.locale.EmailDomains)
+}
+
+// Address generates a single-line address whose city, region and postal
+// code belong together
+func (g *Generator) Address() string {
+	place := g.locale.Places[g.rng.Intn(len(g.locale.Places))]
+	return strings.NewReplacer(
+		"{number}", g.fill(g.pick(g.locale.BuildingNumbers)),
+		"{street}", g.pick(g.locale.streetsFor(place)),
+		"{city}", place.City,
+		"{region}", place.Region,
+		"{postcode}", g.fill(g.pick(place.Postcodes)),
+	).Replace(g.locale.AddressFormat)
+}
+
+// Phone generates a phone number in the locale's national format
+func (g *Generator) Phone() string {
+	return g.fill(g.pick(g.locale.PhoneFormats))
+}
+
+// Username generates a handle such as "swifttiger42"
+func (g *Generator) Username() string {
+	return g.pick(usernameAdjectives) +
+		g.pick(usernameNouns) +
+		g.digit() +
+		g.digit()
+}
+
+// Password generates a random password of 12 to 15 characters
+func (g *Generator) Password() string {
+	length := 12 + g.rng.Intn(4)
+	password := make([]byte, length)
+	for i := range password {
+		password[i] = passwordCharset[g.rng.Intn(len(passwordCharset))]
+	}
+	return string(password)
+}
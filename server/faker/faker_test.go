@@ -0,0 +1,24 @@
+package faker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecordEmailMatchesName(t *testing.T) {
+	for _, code := range LocaleCodes() {
+		t.Run(code, func(t *testing.T) {
+			g := New(1)
+			if err := g.SetLocale(code); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 50; i++ {
+				record := g.Record()
+				want := strings.Replace(emailTransliterations.Replace(strings.ToLower(record.Name)), " ", ".", -1) + "@"
+				if !strings.HasPrefix(record.Email, want) {
+					t.Fatalf("%s got the email %s, want one starting with %s", record.Name, record.Email, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package faker
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+// feistelRounds is the number of rounds used to permute a string, as in
+// NIST's FF1
+const feistelRounds = 10
+
+// permuteNumerals maps a string of numerals in the given radix to another of
+// the same length. For a given key and tweak the mapping is a bijection, so
+// distinct inputs never share an output. It is a Feistel network over the two
+// halves of the string in the style of FF1, not a certified implementation
+// of it.
+func (m *Masker) permuteNumerals(numerals []int, radix int, tweak string) []int {
+	n := len(numerals)
+	switch n {
+	case 0:
+		return numerals
+	case 1:
+		return []int{m.permuteNumeral(numerals[0], radix, tweak)}
+	}
+
+	u, v := n/2, n-n/2
+	a := numeralValue(numerals[:u], radix)
+	b := numeralValue(numerals[u:], radix)
+	for i := 0; i < feistelRounds; i++ {
+		size := u
+		if i%2 == 1 {
+			size = v
+		}
+		modulus := new(big.Int).Exp(big.NewInt(int64(radix)), big.NewInt(int64(size)), nil)
+		round := new(big.Int).SetBytes(m.mac("fpe", tweak, strconv.Itoa(n), strconv.Itoa(i), b.String()))
+		c := new(big.Int).Add(a, round)
+		a, b = b, c.Mod(c, modulus)
+	}
+	return append(numeralDigits(a, radix, u), numeralDigits(b, radix, v)...)
+}
+
+// permuteNumeral maps a single numeral through a keyed shuffle of the radix,
+// which a Feistel network cannot do with only one numeral to split
+func (m *Masker) permuteNumeral(numeral int, radix int, tweak string) int {
+	order := make([]int, radix)
+	keys := make([][]byte, radix)
+	for i := range order {
+		order[i] = i
+		keys[i] = m.mac("fpe", tweak, "1", strconv.Itoa(i))
+	}
+	sort.Slice(order, func(i, j int) bool { return bytes.Compare(keys[order[i]], keys[order[j]]) < 0 })
+	return order[numeral]
+}
+
+// numeralValue reads numerals as a number in the given radix
+func numeralValue(numerals []int, radix int) *big.Int {
+	value := new(big.Int)
+	base := big.NewInt(int64(radix))
+	for _, numeral := range numerals {
+		value.Mul(value, base).Add(value, big.NewInt(int64(numeral)))
+	}
+	return value
+}
+
+// numeralDigits writes value as exactly length numerals in the given radix
+func numeralDigits(value *big.Int, radix int, length int) []int {
+	numerals := make([]int, length)
+	rest := new(big.Int).Set(value)
+	base := big.NewInt(int64(radix))
+	digit := new(big.Int)
+	for i := length - 1; i >= 0; i-- {
+		rest.DivMod(rest, base, digit)
+		numerals[i] = int(digit.Int64())
+	}
+	return numerals
+}
+
+// preserveCharacters replaces the ASCII letters and digits of value with
+// others of the same kind and case, keeping its length and every other
+// character, so the result has the same format
+func (m *Masker) preserveCharacters(value string, tweak string) string {
+	runes := []rune(value)
+	var digits, letters []int
+	for _, r := range runes {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, int(r-'0'))
+		case r >= 'a' && r <= 'z':
+			letters = append(letters, int(r-'a'))
+		case r >= 'A' && r <= 'Z':
+			letters = append(letters, int(r-'A'))
+		}
+	}
+	digits = m.permuteNumerals(digits, 10, tweak+"/digits")
+	letters = m.permuteNumerals(letters, 26, tweak+"/letters")
+
+	for i, r := range runes {
+		switch {
+		case r >= '0' && r <= '9':
+			runes[i], digits = rune('0'+digits[0]), digits[1:]
+		case r >= 'a' && r <= 'z':
+			runes[i], letters = rune('a'+letters[0]), letters[1:]
+		case r >= 'A' && r <= 'Z':
+			runes[i], letters = rune('A'+letters[0]), letters[1:]
+		}
+	}
+	return string(runes)
+}
+
+// overlay writes chars over the characters of value matched by replace, in
+// order, leaving the rest of value as it is
+func overlay(value string, chars string, replace func(r rune) bool) string {
+	var b bytes.Buffer
+	next := 0
+	for _, r := range value {
+		if replace(r) && next < len(chars) {
+			b.WriteByte(chars[next])
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// keepOnly returns the characters of value matched by keep
+func keepOnly(value string, keep func(r rune) bool) string {
+	var b bytes.Buffer
+	for _, r := range value {
+		if keep(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isAlphanumeric(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// digitNumerals converts a string of decimal digits to numerals
+func digitNumerals(digits string) []int {
+	numerals := make([]int, len(digits))
+	for i := range digits {
+		numerals[i] = int(digits[i] - '0')
+	}
+	return numerals
+}
+
+// numeralString converts decimal numerals back to a string of digits
+func numeralString(numerals []int) string {
+	b := make([]byte, len(numerals))
+	for i, numeral := range numerals {
+		b[i] = byte('0' + numeral)
+	}
+	return string(b)
+}
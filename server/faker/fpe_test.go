@@ -0,0 +1,104 @@
+package faker
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPermuteNumeralsIsBijective(t *testing.T) {
+	masker := newTestMasker(t, `{"columns": {"a": "email"}}`)
+
+	tests := []struct {
+		radix  int
+		length int
+	}{
+		{10, 1}, {10, 2}, {10, 3}, {10, 4},
+		{26, 1}, {26, 2},
+		{2, 1}, {2, 5}, {2, 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("radix %d length %d", tt.radix, tt.length), func(t *testing.T) {
+			size := 1
+			for i := 0; i < tt.length; i++ {
+				size *= tt.radix
+			}
+
+			// Every input of the domain must map to a distinct output in it
+			seen := make(map[string]string, size)
+			for n := 0; n < size; n++ {
+				input := make([]int, tt.length)
+				for i, rest := tt.length-1, n; i >= 0; i, rest = i-1, rest/tt.radix {
+					input[i] = rest % tt.radix
+				}
+				output := masker.permuteNumerals(append([]int{}, input...), tt.radix, "tweak")
+				if len(output) != tt.length {
+					t.Fatalf("%v became %v of another length", input, output)
+				}
+				for _, numeral := range output {
+					if numeral < 0 || numeral >= tt.radix {
+						t.Fatalf("%v became %v, outside radix %d", input, output, tt.radix)
+					}
+				}
+				key := fmt.Sprint(output)
+				if previous, ok := seen[key]; ok {
+					t.Fatalf("%s and %v both became %s", previous, input, key)
+				}
+				seen[key] = fmt.Sprint(input)
+			}
+		})
+	}
+}
+
+func TestPermuteNumeralsDependsOnKeyAndTweak(t *testing.T) {
+	masker := newTestMasker(t, `{"columns": {"a": "email"}}`)
+	other, err := NewMasker(masker.config, testMaskKey+"-other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := digitNumerals("4111111111111111")
+	got := numeralString(masker.permuteNumerals(input, 10, "card"))
+	if again := numeralString(masker.permuteNumerals(input, 10, "card")); again != got {
+		t.Errorf("the same key and tweak gave %s and %s", got, again)
+	}
+	if tweaked := numeralString(masker.permuteNumerals(input, 10, "phone")); tweaked == got {
+		t.Errorf("another tweak gave the same %s", got)
+	}
+	if keyed := numeralString(other.permuteNumerals(input, 10, "card")); keyed == got {
+		t.Errorf("another key gave the same %s", got)
+	}
+}
+
+func TestPreserveCharactersKeepsFormat(t *testing.T) {
+	masker := newTestMasker(t, `{"columns": {"a": "email"}}`)
+
+	for _, value := range []string{"4111-1111-1111-1111", "+44 (20) 7946 0958", "AB12 cd-34", "x", "é-1"} {
+		masked := masker.preserveCharacters(value, "format")
+		runes, maskedRunes := []rune(value), []rune(masked)
+		if len(maskedRunes) != len(runes) {
+			t.Fatalf("%q became %q of another length", value, masked)
+		}
+		for i, r := range runes {
+			m := maskedRunes[i]
+			switch {
+			case r >= '0' && r <= '9':
+				if m < '0' || m > '9' {
+					t.Errorf("%q became %q: digit %d replaced with %q", value, masked, i, m)
+				}
+			case r >= 'a' && r <= 'z':
+				if m < 'a' || m > 'z' {
+					t.Errorf("%q became %q: lowercase letter %d replaced with %q", value, masked, i, m)
+				}
+			case r >= 'A' && r <= 'Z':
+				if m < 'A' || m > 'Z' {
+					t.Errorf("%q became %q: uppercase letter %d replaced with %q", value, masked, i, m)
+				}
+			default:
+				if m != r {
+					t.Errorf("%q became %q: %q at %d was not kept", value, masked, r, i)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,515 @@
+package faker
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// IdentifierKind generates and validates one kind of document number. The
+// generated numbers pass the kind's structural and check digit rules but are
+// random, so apart from the SSN ranges nothing stops one from matching a
+// real document.
+type IdentifierKind struct {
+	Name string
+	// countries lists the countries with a format of their own, or is nil
+	// for kinds that belong to a single country
+	countries      []string
+	defaultCountry string
+	generate       func(g *Generator, country string) string
+	// validate checks a normalized value, returning why it is invalid or ""
+	validate func(value string, country string) (reason string)
+	// format turns a normalized value back into its printed form
+	format func(value string) string
+	// note describes a valid value, if there is anything to add
+	note func(value string) string
+}
+
+// IdentifierKinds are the supported kinds, keyed by their query value
+var IdentifierKinds = map[string]*IdentifierKind{
+	"iban": {
+		Name:           "IBAN",
+		defaultCountry: "DE",
+		generate:       (*Generator).iban,
+		validate:       validateIBAN,
+	},
+	"ssn": {
+		Name:     "US Social Security number",
+		generate: func(g *Generator, _ string) string { return g.ssn() },
+		validate: func(value string, _ string) string { return validateSSN(value) },
+		format:   func(value string) string { return value[:3] + "-" + value[3:5] + "-" + value[5:] },
+		note:     ssnNote,
+	},
+	"nino": {
+		Name:     "UK National Insurance number",
+		generate: func(g *Generator, _ string) string { return g.nino() },
+		validate: func(value string, _ string) string { return validateNINO(value) },
+	},
+	"pan": {
+		Name:     "Indian Permanent Account Number",
+		generate: func(g *Generator, _ string) string { return g.pan() },
+		validate: func(value string, _ string) string { return validatePAN(value) },
+	},
+	"aadhaar": {
+		Name:     "Indian Aadhaar number",
+		generate: func(g *Generator, _ string) string { return g.aadhaar() },
+		validate: func(value string, _ string) string { return validateAadhaar(value) },
+		format:   func(value string) string { return value[:4] + " " + value[4:8] + " " + value[8:] },
+	},
+	"vat": {
+		Name:           "EU VAT ID",
+		defaultCountry: "DE",
+		generate:       (*Generator).vat,
+		validate:       validateVAT,
+	},
+	"passport": {
+		Name:           "Passport number",
+		defaultCountry: "US",
+		generate:       (*Generator).passport,
+		validate:       validatePassport,
+	},
+}
+
+func init() {
+	for country := range ibanFormats {
+		IdentifierKinds["iban"].countries = append(IdentifierKinds["iban"].countries, country)
+	}
+	for country := range vatFormats {
+		IdentifierKinds["vat"].countries = append(IdentifierKinds["vat"].countries, country)
+	}
+	for country := range passportFormats {
+		IdentifierKinds["passport"].countries = append(IdentifierKinds["passport"].countries, country)
+	}
+	for _, kind := range IdentifierKinds {
+		sort.Strings(kind.countries)
+	}
+}
+
+// identifierOrder lists the kinds in the order they are documented
+var identifierOrder = []string{"iban", "ssn", "nino", "pan", "aadhaar", "vat", "passport"}
+
+// IdentifierKindNames returns the query values of every kind
+func IdentifierKindNames() []string {
+	return append([]string{}, identifierOrder...)
+}
+
+// Countries returns the countries the kind has formats for, or nil if it
+// belongs to a single country
+func (k *IdentifierKind) Countries() []string {
+	return k.countries
+}
+
+// country resolves the requested country, falling back to the locale's
+// country and then the kind's default
+func (k *IdentifierKind) country(requested string, locale *Locale) (string, error) {
+	if k.countries == nil {
+		return "", nil
+	}
+	if requested != "" {
+		requested = strings.ToUpper(requested)
+		if !containsString(k.countries, requested) {
+			return "", fmt.Errorf("country must be one of %s", strings.Join(k.countries, ", "))
+		}
+		return requested, nil
+	}
+	if locale != nil && containsString(k.countries, locale.Country) {
+		return locale.Country, nil
+	}
+	return k.defaultCountry, nil
+}
+
+// Identifier generates a number of the given kind. country picks the format
+// for kinds that vary by country; when empty the locale's country is used if
+// the kind supports it.
+func (g *Generator) Identifier(kind string, country string) (string, error) {
+	k := IdentifierKinds[kind]
+	if k == nil {
+		return "", fmt.Errorf("type must be one of %s", strings.Join(identifierOrder, ", "))
+	}
+	country, err := k.country(country, g.locale)
+	if err != nil {
+		return "", err
+	}
+	value := k.generate(g, country)
+	if k.format != nil {
+		value = k.format(value)
+	}
+	return value, nil
+}
+
+// ValidateIdentifier checks value against the rules of the given kind. For
+// IBANs and VAT IDs the country comes from the value itself; passports need
+// one.
+func ValidateIdentifier(kind string, value string, country string) (models.IdentifierValidation, error) {
+	k := IdentifierKinds[kind]
+	if k == nil {
+		return models.IdentifierValidation{}, fmt.Errorf("type must be one of %s", strings.Join(identifierOrder, ", "))
+	}
+	if kind == "passport" {
+		var err error
+		if country, err = k.country(country, nil); err != nil {
+			return models.IdentifierValidation{}, err
+		}
+	}
+
+	normalized := normalizeIdentifier(value)
+	result := models.IdentifierValidation{Type: kind, Value: value, Normalized: normalized}
+	if kind == "iban" || kind == "vat" {
+		if len(normalized) >= 2 {
+			result.Country = normalized[:2]
+		}
+	} else if k.countries != nil {
+		result.Country = country
+	}
+
+	if result.Reason = k.validate(normalized, country); result.Reason != "" {
+		return result, nil
+	}
+	result.Valid = true
+	if k.format != nil {
+		result.Normalized = k.format(normalized)
+	}
+	if k.note != nil {
+		result.Note = k.note(normalized)
+	}
+	return result, nil
+}
+
+// normalizeIdentifier uppercases a value and drops the spaces, dots and
+// hyphens it is often printed with
+func normalizeIdentifier(value string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "", ".", "", "/", "").Replace(strings.TrimSpace(value)))
+}
+
+// ibanFormats are the BBAN structures of each country in SWIFT registry
+// notation: n digits, a capital letters, c letters or digits
+var ibanFormats = map[string]string{
+	"AD": "4n4n12c", "AT": "5n11n", "BE": "3n7n2n", "BG": "4a4n2n8c", "CH": "5n12c",
+	"CY": "3n5n16c", "CZ": "4n6n10n", "DE": "8n10n", "DK": "4n9n1n", "EE": "2n2n11n1n",
+	"ES": "4n4n1n1n10n", "FI": "3n11n", "FR": "5n5n11c2n", "GB": "4a6n8n", "GR": "3n4n16c",
+	"HR": "7n10n", "HU": "3n4n1n15n1n", "IE": "4a6n8n", "IS": "4n2n6n10n", "IT": "1a5n5n12c",
+	"LI": "5n12c", "LT": "5n11n", "LU": "3n13c", "LV": "4a13c", "MC": "5n5n11c2n",
+	"MT": "4a5n18c", "NL": "4a10n", "NO": "4n6n1n", "PL": "8n16n", "PT": "4n4n11n2n",
+	"RO": "4a16c", "SE": "3n16n1n", "SI": "5n8n2n", "SK": "4n6n10n", "SM": "1a5n5n12c",
+}
+
+// Patterns of the identifiers whose format does not vary by country
+var (
+	ibanSegment    = regexp.MustCompile(`(\d+)([nac])`)
+	ssnPattern     = regexp.MustCompile(`^\d{9}$`)
+	ninoPattern    = regexp.MustCompile(`^[A-Z]{2}\d{6}[A-D]$`)
+	panPattern     = regexp.MustCompile(`^[A-Z]{5}\d{4}[A-Z]$`)
+	aadhaarPattern = regexp.MustCompile(`^\d{12}$`)
+)
+
+// iban generates an IBAN in electronic form with correct check digits
+func (g *Generator) iban(country string) string {
+	var bban strings.Builder
+	for _, segment := range ibanSegment.FindAllStringSubmatch(ibanFormats[country], -1) {
+		n, _ := strconv.Atoi(segment[1])
+		for i := 0; i < n; i++ {
+			switch segment[2] {
+			case "n":
+				bban.WriteString(g.digit())
+			case "a":
+				bban.WriteByte(byte('A' + g.rng.Intn(26)))
+			default:
+				bban.WriteByte(alphanumeric[g.rng.Intn(len(alphanumeric))])
+			}
+		}
+	}
+	return country + ibanCheckDigits(country, bban.String()) + bban.String()
+}
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// ibanCheckDigits computes the ISO 7064 mod 97-10 check digits of an IBAN
+func ibanCheckDigits(country string, bban string) string {
+	remainder := mod97(bban + country + "00")
+	return fmt.Sprintf("%02d", 98-remainder)
+}
+
+// mod97 returns the remainder of the number formed by replacing each letter
+// of s with its value (A=10 to Z=35)
+func mod97(s string) int {
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	return int(new(big.Int).Mod(n, big.NewInt(97)).Int64())
+}
+
+func validateIBAN(value string, _ string) string {
+	if len(value) < 5 {
+		return "too short"
+	}
+	country := value[:2]
+	format, ok := ibanFormats[country]
+	if !ok {
+		return "unsupported country " + country
+	}
+	if value[2] < '0' || value[2] > '9' || value[3] < '0' || value[3] > '9' {
+		return "check digits must be numeric"
+	}
+	if !ibanPattern(format).MatchString(value[4:]) {
+		return fmt.Sprintf("does not match the %s account format", country)
+	}
+	if mod97(value[4:]+value[:4]) != 1 {
+		return "wrong check digits"
+	}
+	return ""
+}
+
+// ibanPattern compiles a BBAN structure into a regular expression
+func ibanPattern(format string) *regexp.Regexp {
+	pattern := "^"
+	for _, segment := range ibanSegment.FindAllStringSubmatch(format, -1) {
+		class := map[string]string{"n": `\d`, "a": `[A-Z]`, "c": `[A-Z0-9]`}[segment[2]]
+		pattern += class + "{" + segment[1] + "}"
+	}
+	return regexp.MustCompile(pattern + "$")
+}
+
+// ssnTestGroups are group numbers that, with an area number from 900 to 999,
+// are used neither for SSNs nor for ITINs
+var ssnTestGroups = func() []int {
+	var groups []int
+	for group := 1; group <= 99; group++ {
+		if !isITINGroup(group) {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}()
+
+// isITINGroup reports whether group is one the IRS uses for ITINs
+func isITINGroup(group int) bool {
+	return (group >= 50 && group <= 65) || (group >= 70 && group <= 88) || (group >= 90 && group <= 92) || group >= 94
+}
+
+// ssn generates a number in the SSN format with an area number from 900 to
+// 999, which the SSA never issues, and a group outside the ITIN ranges
+func (g *Generator) ssn() string {
+	area := 900 + g.rng.Intn(100)
+	group := ssnTestGroups[g.rng.Intn(len(ssnTestGroups))]
+	serial := 1 + g.rng.Intn(9999)
+	return fmt.Sprintf("%03d%02d%04d", area, group, serial)
+}
+
+func validateSSN(value string) string {
+	if !ssnPattern.MatchString(value) {
+		return "must be 9 digits"
+	}
+	area, group, serial := value[:3], value[3:5], value[5:]
+	switch {
+	case area == "000" || area == "666":
+		return "area number " + area + " is never used"
+	case group == "00":
+		return "group number 00 is never used"
+	case serial == "0000":
+		return "serial number 0000 is never used"
+	}
+	return ""
+}
+
+func ssnNote(value string) string {
+	if value[0] != '9' {
+		return "This number is in the range the SSA issues and may belong to a real person"
+	}
+	group, _ := strconv.Atoi(value[3:5])
+	if isITINGroup(group) {
+		return "Area numbers from 900 are never issued as SSNs, but this one is in an ITIN range"
+	}
+	return "Area numbers from 900 are never issued as SSNs"
+}
+
+// National Insurance number prefix letters
+const (
+	ninoFirstLetters  = "ABCEGHJKLMNOPRSTWXYZ"
+	ninoSecondLetters = "ABCEGHJKLMNPRSTWXYZ"
+)
+
+// ninoUnusedPrefixes are prefixes that are never allocated
+var ninoUnusedPrefixes = []string{"BG", "GB", "KN", "NK", "NT", "TN", "ZZ"}
+
+// nino generates a National Insurance number with an allocatable prefix
+func (g *Generator) nino() string {
+	for {
+		prefix := string(ninoFirstLetters[g.rng.Intn(len(ninoFirstLetters))]) +
+			string(ninoSecondLetters[g.rng.Intn(len(ninoSecondLetters))])
+		if containsString(ninoUnusedPrefixes, prefix) {
+			continue
+		}
+		number := fmt.Sprintf("%06d", g.rng.Intn(1000000))
+		return prefix + number + string(rune('A'+g.rng.Intn(4)))
+	}
+}
+
+func validateNINO(value string) string {
+	if !ninoPattern.MatchString(value) {
+		return "must be two letters, six digits and a letter from A to D"
+	}
+	switch {
+	case !strings.ContainsRune(ninoFirstLetters, rune(value[0])):
+		return "the first letter cannot be " + value[:1]
+	case !strings.ContainsRune(ninoSecondLetters, rune(value[1])):
+		return "the second letter cannot be " + value[1:2]
+	case containsString(ninoUnusedPrefixes, value[:2]):
+		return "prefix " + value[:2] + " is never allocated"
+	}
+	return ""
+}
+
+// panHolderTypes are the fourth letters of a PAN, which give the kind of
+// holder: P for a person, C for a company and so on
+const panHolderTypes = "ABCFGHJLPT"
+
+// pan generates a Permanent Account Number for an individual
+func (g *Generator) pan() string {
+	letters := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte('A' + g.rng.Intn(26))
+		}
+		return string(b)
+	}
+	return letters(3) + "P" + letters(1) + fmt.Sprintf("%04d", 1+g.rng.Intn(9999)) + letters(1)
+}
+
+func validatePAN(value string) string {
+	if !panPattern.MatchString(value) {
+		return "must be five letters, four digits and a letter"
+	}
+	if !strings.ContainsRune(panHolderTypes, rune(value[3])) {
+		return "the fourth letter must be one of " + panHolderTypes
+	}
+	if value[5:9] == "0000" {
+		return "the sequence number cannot be 0000"
+	}
+	return ""
+}
+
+// Verhoeff check digit tables
+var (
+	verhoeffMultiply = [10][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 2, 3, 4, 0, 6, 7, 8, 9, 5},
+		{2, 3, 4, 0, 1, 7, 8, 9, 5, 6},
+		{3, 4, 0, 1, 2, 8, 9, 5, 6, 7},
+		{4, 0, 1, 2, 3, 9, 5, 6, 7, 8},
+		{5, 9, 8, 7, 6, 0, 4, 3, 2, 1},
+		{6, 5, 9, 8, 7, 1, 0, 4, 3, 2},
+		{7, 6, 5, 9, 8, 2, 1, 0, 4, 3},
+		{8, 7, 6, 5, 9, 3, 2, 1, 0, 4},
+		{9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+	verhoeffPermute = [8][10]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		{1, 5, 7, 6, 2, 8, 3, 0, 9, 4},
+		{5, 8, 0, 3, 7, 9, 6, 1, 4, 2},
+		{8, 9, 1, 6, 0, 4, 3, 5, 2, 7},
+		{9, 4, 5, 3, 1, 2, 7, 6, 0, 8},
+		{4, 2, 8, 6, 5, 7, 3, 9, 0, 1},
+		{2, 7, 9, 3, 8, 0, 6, 4, 1, 5},
+		{7, 0, 4, 6, 9, 1, 3, 2, 5, 8},
+	}
+	verhoeffInverse = [10]int{0, 4, 3, 2, 1, 5, 6, 7, 8, 9}
+)
+
+// verhoeff runs the Verhoeff algorithm over digits, with offset 1 to compute
+// a check digit and 0 to validate a number that ends in one
+func verhoeff(digits string, offset int) int {
+	c := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		c = verhoeffMultiply[c][verhoeffPermute[(i+offset)%8][d]]
+	}
+	return c
+}
+
+// aadhaar generates a 12-digit Aadhaar number ending in a Verhoeff check digit
+func (g *Generator) aadhaar() string {
+	number := string(rune('2' + g.rng.Intn(8)))
+	for len(number) < 11 {
+		number += g.digit()
+	}
+	return number + strconv.Itoa(verhoeffInverse[verhoeff(number, 1)])
+}
+
+func validateAadhaar(value string) string {
+	if !aadhaarPattern.MatchString(value) {
+		return "must be 12 digits"
+	}
+	if value[0] == '0' || value[0] == '1' {
+		return "cannot start with 0 or 1"
+	}
+	if verhoeff(value, 0) != 0 {
+		return "wrong check digit"
+	}
+	return ""
+}
+
+// passportFormats are the passport number patterns of each country, where
+// # is a digit, A a letter and X either
+var passportFormats = map[string][]string{
+	"AU": {"A#######", "AA#######"},
+	"CA": {"AA######"},
+	"DE": {"CXXXXXXXX"},
+	"ES": {"AAA######"},
+	"FR": {"##AA#####"},
+	"GB": {"#########"},
+	"IN": {"A#######"},
+	"IT": {"AA#######"},
+	"JP": {"AA#######"},
+	"US": {"#########", "A########"},
+}
+
+// passport generates a passport number in one of the country's formats
+func (g *Generator) passport(country string) string {
+	formats := passportFormats[country]
+	format := formats[g.rng.Intn(len(formats))]
+
+	var b strings.Builder
+	for _, r := range format {
+		switch r {
+		case '#':
+			b.WriteString(g.digit())
+		case 'A':
+			b.WriteByte(byte('A' + g.rng.Intn(26)))
+		case 'X':
+			b.WriteByte("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"[g.rng.Intn(36)])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validatePassport(value string, country string) string {
+	for _, format := range passportFormats[country] {
+		pattern := strings.NewReplacer("#", `\d`, "A", `[A-Z]`, "X", `[0-9A-Z]`).Replace(format)
+		if regexp.MustCompile("^" + pattern + "$").MatchString(value) {
+			return ""
+		}
+	}
+	return "does not match the " + country + " passport number format"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
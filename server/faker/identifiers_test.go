@@ -0,0 +1,82 @@
+package faker
+
+import "testing"
+
+func TestGeneratedIdentifiersValidate(t *testing.T) {
+	for _, kind := range IdentifierKindNames() {
+		countries := IdentifierKinds[kind].Countries()
+		if countries == nil {
+			countries = []string{""}
+		}
+		for _, country := range countries {
+			t.Run(kind+" "+country, func(t *testing.T) {
+				g := New(1)
+				for i := 0; i < 500; i++ {
+					value, err := g.Identifier(kind, country)
+					if err != nil {
+						t.Fatalf("Identifier: %v", err)
+					}
+					result, err := ValidateIdentifier(kind, value, country)
+					if err != nil {
+						t.Fatalf("ValidateIdentifier(%q): %v", value, err)
+					}
+					if !result.Valid {
+						t.Fatalf("generated %s %q is invalid: %s", kind, value, result.Reason)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestValidateIdentifier(t *testing.T) {
+	tests := []struct {
+		kind  string
+		value string
+		valid bool
+	}{
+		{"iban", "DE89 3704 0044 0532 0130 00", true},
+		{"iban", "GB82 WEST 1234 5698 7654 32", true},
+		{"iban", "DE89 3704 0044 0532 0130 01", false},
+		{"vat", "ATU13585627", true},
+		{"vat", "ATU13585628", false},
+		{"vat", "BE0776091951", true},
+		{"vat", "BE0776091952", false},
+		{"vat", "DE136695976", true},
+		{"vat", "DE136695977", false},
+		{"vat", "DK13585628", true},
+		{"vat", "DK13585629", false},
+		{"vat", "FI20774740", true},
+		{"vat", "FI20774741", false},
+		{"vat", "FR40303265045", true},
+		{"vat", "FR41303265045", false},
+		{"vat", "IT00743110157", true},
+		{"vat", "IT00743110158", false},
+		{"vat", "LU26375245", true},
+		{"vat", "LU26375246", false},
+		// An RSIN-based number, checked with mod 11
+		{"vat", "NL004495445B01", true},
+		{"vat", "NL004495446B01", false},
+		// A number issued since 2020, checked with mod 97
+		{"vat", "NL123456789B13", true},
+		{"vat", "NL123456789B14", false},
+		{"vat", "PL5260001246", true},
+		{"vat", "PL5260001247", false},
+		{"vat", "PT501964843", true},
+		{"vat", "PT501964844", false},
+		{"vat", "SE556188840401", true},
+		{"vat", "SE556188840501", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kind+" "+tt.value, func(t *testing.T) {
+			result, err := ValidateIdentifier(tt.kind, tt.value, "")
+			if err != nil {
+				t.Fatalf("ValidateIdentifier: %v", err)
+			}
+			if result.Valid != tt.valid {
+				t.Errorf("valid = %v (%s), want %v", result.Valid, result.Reason, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package faker
+
+import (
+	"embed"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Locale packs, one JSON file per locale named after its code
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// DefaultLocale is used when no locale is requested
+const DefaultLocale = "en_US"
+
+// Locale holds the names, places and formats used to build identities for
+// one country. In patterns # is any digit, % is a digit from 2 to 9 and ? is
+// a letter.
+type Locale struct {
+	Code            string   `json:"code"`
+	Name            string   `json:"name"`
+	Country         string   `json:"country"`    // ISO 3166 code
+	NationalID      string   `json:"nationalId"` // identifier kind for Record, if any
+	FirstNames      []string `json:"firstNames"`
+	LastNames       []string `json:"lastNames"`
+	FamilyNameFirst bool     `json:"familyNameFirst"`
+	EmailDomains    []string `json:"emailDomains"`
+	Streets         []string `json:"streets"`
+	BuildingNumbers []string `json:"buildingNumbers"` // patterns
+	AddressFormat   string   `json:"addressFormat"`
+	Places          []Place  `json:"places"`
+	PhoneFormats    []string `json:"phoneFormats"` // patterns, in national format
+}
+
+// Place is a city with its region and the postal codes that belong to it
+type Place struct {
+	City      string   `json:"city"`
+	Region    string   `json:"region"`
+	Postcodes []string `json:"postcodes"` // patterns
+	Streets   []string `json:"streets"`   // overrides the locale's streets
+}
+
+var (
+	localesOnce sync.Once
+	locales     map[string]*Locale
+)
+
+// loadLocales parses the embedded locale packs on first use
+func loadLocales() map[string]*Locale {
+	localesOnce.Do(func() {
+		locales = make(map[string]*Locale)
+		files, _ := localeFiles.ReadDir("locales")
+		for _, file := range files {
+			data, err := localeFiles.ReadFile("locales/" + file.Name())
+			if err != nil {
+				panic("faker: missing locale " + file.Name())
+			}
+
+			var locale Locale
+			if err := json.Unmarshal(data, &locale); err != nil {
+				panic("faker: invalid locale " + file.Name() + ": " + err.Error())
+			}
+			locales[locale.Code] = &locale
+		}
+	})
+	return locales
+}
+
+// LookupLocale returns the locale pack for code, accepting both en_US and
+// en-US forms
+func LookupLocale(code string) (*Locale, bool) {
+	locale, ok := loadLocales()[strings.Replace(code, "-", "_", 1)]
+	return locale, ok
+}
+
+// LocaleCodes returns the codes of every available locale, sorted
+func LocaleCodes() []string {
+	var codes []string
+	for code := range loadLocales() {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// streetsFor returns the streets to use for a place
+func (l *Locale) streetsFor(place Place) []string {
+	if len(place.Streets) > 0 {
+		return place.Streets
+	}
+	return l.Streets
+}
+
+// addressCapacity estimates how many distinct addresses the locale can produce
+func (l *Locale) addressCapacity() float64 {
+	numbers := 0.0
+	for _, pattern := range l.BuildingNumbers {
+		numbers += fillCapacity(pattern)
+	}
+
+	total := 0.0
+	for _, place := range l.Places {
+		postcodes := 0.0
+		for _, pattern := range place.Postcodes {
+			postcodes += fillCapacity(pattern)
+		}
+		total += float64(len(l.streetsFor(place))) * numbers * postcodes
+	}
+	return total
+}
+
+// phoneCapacity estimates how many distinct phone numbers the locale can produce
+func (l *Locale) phoneCapacity() float64 {
+	total := 0.0
+	for _, pattern := range l.PhoneFormats {
+		total += fillCapacity(pattern)
+	}
+	return total
+}
+
+// postcodeLetters are the letters used in generated postcodes, leaving out
+// ones easily mistaken for digits
+const postcodeLetters = "ABDEFGHJLNPQRSTUWXYZ"
+
+// fill replaces the placeholders in a pattern with random characters
+func (g *Generator) fill(pattern string) string {
+	var b strings.Builder
+	for _, char := range pattern {
+		switch char {
+		case '#':
+			b.WriteString(g.digit())
+		case '%':
+			b.WriteByte(byte('2' + g.rng.Intn(8)))
+		case '?':
+			b.WriteByte(postcodeLetters[g.rng.Intn(len(postcodeLetters))])
+		default:
+			b.WriteRune(char)
+		}
+	}
+	return b.String()
+}
+
+// fillCapacity counts the strings fill can produce from a pattern
+func fillCapacity(pattern string) float64 {
+	total := 1.0
+	for _, char := range pattern {
+		switch char {
+		case '#':
+			total *= 10
+		case '%':
+			total *= 8
+		case '?':
+			total *= float64(len(postcodeLetters))
+		}
+	}
+	return total
+}
+
+// emailTransliterations spell accented letters in ASCII for email addresses
+var emailTransliterations = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+	"à", "a", "â", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "û", "u", "ù", "u", "ç", "c", "œ", "oe",
+)
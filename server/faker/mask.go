@@ -0,0 +1,695 @@
+package faker
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// MinMaskKeyLength is the shortest key accepted for a masking run. Shorter
+// keys would let someone holding masked data guess the key and reverse the
+// mapping of values with few possibilities, such as phone numbers.
+const MinMaskKeyLength = 16
+
+// RedactedValue replaces values of columns masked with the redact type
+const RedactedValue = "[REDACTED]"
+
+// MaskTypes are the replacements a masked column can use. Most are fake data
+// generators; format replaces letters and digits in place, hash gives a keyed
+// digest and redact a fixed placeholder.
+var MaskTypes = []string{
+	"name", "firstName", "lastName", "email", "username", "phone", "address",
+	"creditCard", "iban", "ssn", "nino", "pan", "aadhaar", "vat", "passport",
+	"uuid", "format", "hash", "redact",
+}
+
+// distinctTypes identify people, so distinct values must never share a fake.
+// Their fakes carry a keyed tag, since the generators alone only have a few
+// thousand names to pick from.
+var distinctTypes = []string{"name", "lastName", "email", "username"}
+
+// Letters of the syllables that make up a tag, and the syllables per tag,
+// which allow 80^6 (about 2.6e11) tags for each generated value
+const (
+	tagConsonants = "bdfghjklmnprstvz"
+	tagVowels     = "aeiou"
+	tagSyllables  = 6
+)
+
+// ErrMaskCollision is returned when two distinct values of a distinctTypes
+// namespace would get the same fake. It is vanishingly unlikely, and
+// masking with another key avoids it.
+var ErrMaskCollision = errors.New("two distinct values would get the same fake; mask with a different key")
+
+// preservableTypes can keep the format of the original value instead of
+// generating a new one
+var preservableTypes = []string{"email", "phone", "creditCard", "iban"}
+
+// MaskRule says how to replace the values of one column
+type MaskRule struct {
+	Type string `json:"type"`
+	// PreserveFormat keeps the value's length and layout, replacing only its
+	// letters and digits
+	PreserveFormat bool `json:"preserveFormat"`
+	// Namespace defaults to the type. Columns sharing a namespace map equal
+	// values to equal fakes, in any file masked with the same key.
+	Namespace string `json:"namespace"`
+	// Country picks the format of generated IBANs, VAT IDs and passports
+	Country string `json:"country"`
+}
+
+// MaskConfig maps column names to the rules used to mask them. Nested JSON
+// fields are named by their path, such as "billing.email"; a path through an
+// array applies to each of its elements.
+type MaskConfig struct {
+	locale  *Locale
+	rules   map[string]MaskRule
+	columns []string
+}
+
+// ParseMaskConfig compiles a masking config such as
+//
+//	{"locale": "en_GB", "columns": {
+//	  "email":          "email",
+//	  "contact_email":  "email",
+//	  "card":           {"type": "creditCard", "preserveFormat": true},
+//	  "billing.phone":  {"type": "phone", "preserveFormat": true}
+//	}}
+//
+// A rule is either a type name or an object with a type and its options.
+// Every problem found is reported as a SchemaErrors.
+func ParseMaskConfig(data []byte) (*MaskConfig, error) {
+	var document struct {
+		Locale  string                     `json:"locale"`
+		Columns map[string]json.RawMessage `json:"columns"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, SchemaErrors{{Path: "$", Message: describeJSONError(err)}}
+	}
+
+	var errs SchemaErrors
+	config := &MaskConfig{rules: map[string]MaskRule{}}
+
+	localeCode := document.Locale
+	if localeCode == "" {
+		localeCode = DefaultLocale
+	}
+	locale, ok := LookupLocale(localeCode)
+	if !ok {
+		errs = append(errs, SchemaError{Path: "locale", Message: "locale must be one of " + strings.Join(LocaleCodes(), ", ")})
+	}
+	config.locale = locale
+
+	if len(document.Columns) == 0 {
+		errs = append(errs, SchemaError{Path: "columns", Message: "at least one column is required"})
+	}
+	if len(document.Columns) > maxSchemaFields {
+		errs = append(errs, SchemaError{Path: "columns", Message: fmt.Sprintf("at most %d columns can be masked", maxSchemaFields)})
+	}
+
+	for column := range document.Columns {
+		config.columns = append(config.columns, column)
+	}
+	sort.Strings(config.columns)
+	for _, column := range config.columns {
+		rule, err := parseMaskRule(document.Columns[column])
+		if err != nil {
+			errs = append(errs, SchemaError{Path: column, Message: err.Error()})
+			continue
+		}
+		config.rules[column] = rule
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return config, nil
+}
+
+// parseMaskRule reads and checks the rule of one column
+func parseMaskRule(raw json.RawMessage) (MaskRule, error) {
+	var rule MaskRule
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '"' {
+		if err := json.Unmarshal(trimmed, &rule.Type); err != nil {
+			return rule, errors.New(describeJSONError(err))
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&rule); err != nil {
+			return rule, errors.New(describeJSONError(err))
+		}
+	}
+
+	if !containsString(MaskTypes, rule.Type) {
+		return rule, fmt.Errorf("type must be one of %s", strings.Join(MaskTypes, ", "))
+	}
+	if rule.PreserveFormat && !containsString(preservableTypes, rule.Type) {
+		return rule, fmt.Errorf("preserveFormat is only supported for %s; use format for other values", strings.Join(preservableTypes, ", "))
+	}
+	if rule.Country != "" {
+		kind := IdentifierKinds[rule.Type]
+		if kind == nil || kind.countries == nil {
+			return rule, errors.New("country is only supported for iban, vat and passport")
+		}
+		country, err := kind.country(rule.Country, nil)
+		if err != nil {
+			return rule, err
+		}
+		rule.Country = country
+	}
+	if rule.Namespace == "" {
+		rule.Namespace = rule.Type
+	}
+	return rule, nil
+}
+
+// MaskStats summarises a masking run
+type MaskStats struct {
+	Format    string   // FormatCSV, FormatJSON or FormatNDJSON
+	Records   int      // records read
+	Unmatched []string // configured columns the input did not contain
+}
+
+// Masker replaces personal data with fakes. Every replacement is derived from
+// the key and the original value, so the same value always gets the same
+// fake, across columns and files, and re-running with the key reproduces the
+// output exactly. A Masker is safe for concurrent use.
+type Masker struct {
+	config *MaskConfig
+	key    []byte
+
+	mu sync.Mutex
+	// issued maps the fakes of distinctTypes namespaces to the canonical
+	// values they were made for, to catch collisions
+	issued map[string]map[string]string
+}
+
+// NewMasker creates a masker for config. An empty key picks a random one, so
+// the output only stays consistent within that masker.
+func NewMasker(config *MaskConfig, key string) (*Masker, error) {
+	if key == "" {
+		random := make([]byte, 32)
+		crand.Read(random)
+		return &Masker{config: config, key: random, issued: map[string]map[string]string{}}, nil
+	}
+	if len(key) < MinMaskKeyLength {
+		return nil, fmt.Errorf("key must be at least %d characters", MinMaskKeyLength)
+	}
+	return &Masker{config: config, key: []byte(key), issued: map[string]map[string]string{}}, nil
+}
+
+// mac returns the keyed digest of parts
+func (m *Masker) mac(parts ...string) []byte {
+	h := hmac.New(sha256.New, m.key)
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
+
+// Value masks a single value with rule. Empty values are left empty. It
+// fails with ErrMaskCollision rather than give two distinct names, emails or
+// usernames the same fake.
+func (m *Masker) Value(rule MaskRule, value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return value, nil
+	}
+
+	switch {
+	case rule.Type == "redact":
+		return RedactedValue, nil
+	case rule.Type == "hash":
+		return hex.EncodeToString(m.mac("hash", rule.Namespace, value)[:16]), nil
+	case rule.Type == "format":
+		return m.preserveCharacters(value, rule.Namespace), nil
+	case rule.PreserveFormat:
+		return m.preserve(rule, value), nil
+	}
+
+	// Seed a generator from the value, so equal values get equal fakes
+	canonical := m.canonical(rule, value)
+	digest := m.mac("generate", rule.Namespace, canonical)
+	g := New(int64(binary.BigEndian.Uint64(digest) >> 1))
+	g.locale = m.config.locale
+
+	switch rule.Type {
+	case "name":
+		first, last := g.FirstName(), g.LastName()+"-"+m.tag(rule.Namespace, canonical)
+		if g.locale.FamilyNameFirst {
+			return m.issue(rule.Namespace, last+" "+first, canonical)
+		}
+		return m.issue(rule.Namespace, first+" "+last, canonical)
+	case "firstName":
+		return g.FirstName(), nil
+	case "lastName":
+		return m.issue(rule.Namespace, g.LastName()+"-"+m.tag(rule.Namespace, canonical), canonical)
+	case "email":
+		email := g.Email()
+		at := strings.LastIndex(email, "@")
+		return m.issue(rule.Namespace, email[:at]+"."+strings.ToLower(m.tag(rule.Namespace, canonical))+email[at:], canonical)
+	case "username":
+		return m.issue(rule.Namespace, g.Username()+"_"+strings.ToLower(m.tag(rule.Namespace, canonical)), canonical)
+	case "phone":
+		return g.Phone(), nil
+	case "address":
+		return g.Address(), nil
+	case "creditCard":
+		return g.CreditCard(), nil
+	case "uuid":
+		return g.UUID(), nil
+	default:
+		identifier, _ := g.Identifier(rule.Type, rule.Country)
+		return identifier, nil
+	}
+}
+
+// tag returns a capitalised string of keyed syllables for a canonical value,
+// drawn independently of the generator so it multiplies the fakes possible
+func (m *Masker) tag(namespace string, canonical string) string {
+	digest := m.mac("tag", namespace, canonical)
+	tag := make([]byte, 0, 2*tagSyllables)
+	for i := 0; i < tagSyllables; i++ {
+		n := int(binary.BigEndian.Uint16(digest[2*i:]))
+		tag = append(tag, tagConsonants[n%len(tagConsonants)], tagVowels[n/len(tagConsonants)%len(tagVowels)])
+	}
+	tag[0] -= 'a' - 'A'
+	return string(tag)
+}
+
+// issue records that fake was given to canonical in namespace, failing if it
+// was already given to another value
+func (m *Masker) issue(namespace string, fake string, canonical string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issued := m.issued[namespace]
+	if issued == nil {
+		issued = map[string]string{}
+		m.issued[namespace] = issued
+	}
+	if previous, ok := issued[fake]; ok && previous != canonical {
+		return "", ErrMaskCollision
+	}
+	issued[fake] = canonical
+	return fake, nil
+}
+
+// canonical reduces a value to the form that identifies it, so different
+// spellings of the same email, name or number get the same fake
+func (m *Masker) canonical(rule MaskRule, value string) string {
+	switch rule.Type {
+	case "email", "name", "firstName", "lastName", "username", "address":
+		return strings.Join(strings.Fields(strings.ToLower(value)), " ")
+	case "phone":
+		if phone, err := utils.NormalizePhone(value, m.config.locale.Country); err == nil {
+			return phone.E164
+		}
+		return keepOnly(value, isDigit)
+	case "uuid":
+		return strings.ToLower(strings.TrimSpace(value))
+	default:
+		return normalizeIdentifier(value)
+	}
+}
+
+// preserve replaces the letters and digits of a value, keeping what makes it
+// look like its type: an email's domain, a phone number's country code, a
+// card's issuer and valid check digit and an IBAN's country and check digits
+func (m *Masker) preserve(rule MaskRule, value string) string {
+	switch rule.Type {
+	case "email":
+		at := strings.LastIndex(value, "@")
+		if at < 0 {
+			break
+		}
+		return m.preserveCharacters(value[:at], rule.Namespace) + value[at:]
+
+	case "phone":
+		digits := keepOnly(value, isDigit)
+		keep := 0
+		if strings.HasPrefix(strings.TrimSpace(value), "+") {
+			if phone, err := utils.NormalizePhone(value, ""); err == nil && len(phone.CountryCode) <= len(digits) {
+				keep = len(phone.CountryCode)
+			}
+		}
+		masked := digits[:keep] + numeralString(m.permuteNumerals(digitNumerals(digits[keep:]), 10, rule.Namespace))
+		return overlay(value, masked, isDigit)
+
+	case "creditCard":
+		digits := keepOnly(value, isDigit)
+		if len(digits) < 12 || len(digits) > 19 {
+			break
+		}
+		// The first six digits identify the issuer, not the cardholder
+		body := digits[:6] + numeralString(m.permuteNumerals(digitNumerals(digits[6:len(digits)-1]), 10, rule.Namespace))
+		return overlay(value, body+string(luhnCheckDigit(body)), isDigit)
+
+	case "iban":
+		characters := strings.ToUpper(keepOnly(value, isAlphanumeric))
+		if len(characters) < 5 || ibanFormats[characters[:2]] == "" {
+			break
+		}
+		country := characters[:2]
+		bban := m.preserveCharacters(characters[4:], rule.Namespace)
+		return overlay(value, country+ibanCheckDigits(country, bban)+bban, isAlphanumeric)
+	}
+	return m.preserveCharacters(value, rule.Namespace)
+}
+
+// MaskInputFormat guesses the format of a file from its name, returning ""
+// when the content has to be inspected
+func MaskInputFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".json", ".ndjson", ".jsonl":
+		return FormatJSON
+	}
+	return ""
+}
+
+// Mask reads CSV or JSON records from r and writes them to w with the
+// configured columns masked. format is FormatCSV, FormatJSON or "" to decide
+// from the content. JSON input is either an array of objects or a stream of
+// objects, one per line, and is written back in the same form.
+func (m *Masker) Mask(r io.Reader, w io.Writer, format string) (MaskStats, error) {
+	reader := bufio.NewReader(r)
+	if format == "" {
+		format = FormatCSV
+		if first := firstNonSpace(reader); first == '[' || first == '{' {
+			format = FormatJSON
+		}
+	}
+
+	var stats MaskStats
+	matched := map[string]bool{}
+	var err error
+	switch format {
+	case FormatCSV:
+		stats.Format = FormatCSV
+		stats.Records, err = m.maskCSV(reader, w, matched)
+	case FormatJSON:
+		stats.Format, stats.Records, err = m.maskJSON(reader, w, matched)
+	default:
+		return stats, fmt.Errorf("format must be csv or json")
+	}
+
+	for _, column := range m.config.columns {
+		if !matched[column] {
+			stats.Unmatched = append(stats.Unmatched, column)
+		}
+	}
+	return stats, err
+}
+
+// firstNonSpace peeks at the first character after any byte order mark and
+// whitespace, without consuming anything
+func firstNonSpace(reader *bufio.Reader) byte {
+	head, _ := reader.Peek(512)
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) == 0 {
+		return 0
+	}
+	return head[0]
+}
+
+// maskCSV masks a CSV file whose first row names the columns
+func (m *Masker) maskCSV(r io.Reader, w io.Writer, matched map[string]bool) (int, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	writer := csv.NewWriter(w)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading the header: %v", err)
+	}
+	header = append([]string(nil), header...)
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	rules := make([]*MaskRule, len(header))
+	for i, column := range header {
+		if rule, ok := m.config.rules[column]; ok {
+			rules[i] = &rule
+			matched[column] = true
+		}
+	}
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	records := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return records, err
+		}
+		for i, rule := range rules {
+			if rule != nil {
+				if record[i], err = m.Value(*rule, record[i]); err != nil {
+					return records, fmt.Errorf("record %d: %s: %w", records+1, header[i], err)
+				}
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return records, err
+		}
+		records++
+	}
+	writer.Flush()
+	return records, writer.Error()
+}
+
+// maskJSON masks an array of JSON objects or a stream of them, returning
+// which of the two it was
+func (m *Masker) maskJSON(r io.Reader, w io.Writer, matched map[string]bool) (string, int, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return FormatNDJSON, 0, nil
+	}
+	if err != nil {
+		return FormatJSON, 0, err
+	}
+
+	if token == json.Delim('[') {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return FormatJSON, 0, err
+		}
+		records := 0
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return FormatJSON, records, err
+			}
+			separator := "\n"
+			if records > 0 {
+				separator = ",\n"
+			}
+			if err := m.maskJSONRecord(decoder, token, w, separator, matched, records); err != nil {
+				return FormatJSON, records, err
+			}
+			records++
+		}
+		if _, err := decoder.Token(); err != nil {
+			return FormatJSON, records, err
+		}
+		closing := "]\n"
+		if records > 0 {
+			closing = "\n]\n"
+		}
+		_, err := io.WriteString(w, closing)
+		return FormatJSON, records, err
+	}
+
+	records := 0
+	for {
+		if err := m.maskJSONRecord(decoder, token, w, "", matched, records); err != nil {
+			return FormatNDJSON, records, err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return FormatNDJSON, records, err
+		}
+		records++
+		if token, err = decoder.Token(); err == io.EOF {
+			return FormatNDJSON, records, nil
+		} else if err != nil {
+			return FormatNDJSON, records, err
+		}
+	}
+}
+
+// maskJSONRecord decodes the object starting with token, masks it and writes
+// it after prefix
+func (m *Masker) maskJSONRecord(decoder *json.Decoder, token json.Token, w io.Writer, prefix string, matched map[string]bool, index int) error {
+	value, err := decodeOrdered(decoder, token)
+	if err != nil {
+		return err
+	}
+	record, ok := value.(Object)
+	if !ok {
+		return fmt.Errorf("record %d is not an object", index+1)
+	}
+
+	for _, column := range m.config.columns {
+		path := []string{column}
+		if _, ok := record.get(column); !ok {
+			path = strings.Split(column, ".")
+		}
+		found, err := m.maskPath(record, path, m.config.rules[column], column)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", index+1, err)
+		}
+		if found {
+			matched[column] = true
+		}
+	}
+
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, prefix); err != nil {
+		return err
+	}
+	_, err = w.Write(encoded)
+	return err
+}
+
+// maskPath masks the values at path inside value in place, reporting whether
+// the path exists
+func (m *Masker) maskPath(value interface{}, path []string, rule MaskRule, column string) (bool, error) {
+	switch v := value.(type) {
+	case Object:
+		i, ok := v.get(path[0])
+		if !ok {
+			return false, nil
+		}
+		if len(path) > 1 {
+			return m.maskPath(v.Values[i], path[1:], rule, column)
+		}
+		masked, err := m.maskLeaf(v.Values[i], rule, column)
+		v.Values[i] = masked
+		return true, err
+	case []interface{}:
+		found := false
+		for _, item := range v {
+			itemFound, err := m.maskPath(item, path, rule, column)
+			if err != nil {
+				return found, err
+			}
+			found = found || itemFound
+		}
+		return found, nil
+	}
+	return false, nil
+}
+
+// maskLeaf masks the value found at a column's path. Numbers and booleans
+// are masked as text and become strings, since their fakes rarely fit the
+// original type.
+func (m *Masker) maskLeaf(value interface{}, rule MaskRule, column string) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return m.Value(rule, v)
+	case json.Number:
+		return m.Value(rule, v.String())
+	case bool:
+		return m.Value(rule, strconv.FormatBool(v))
+	case []interface{}:
+		for i, item := range v {
+			masked, err := m.maskLeaf(item, rule, column)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = masked
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is an object; map its fields instead", column)
+}
+
+// get returns the index of key in the object
+func (o Object) get(key string) (int, bool) {
+	for i, k := range o.Keys {
+		if k == key {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// decodeOrdered decodes the JSON value starting with token, keeping the order
+// of object keys so masked records look like the originals
+func decodeOrdered(decoder *json.Decoder, token json.Token) (interface{}, error) {
+	switch token {
+	case json.Delim('{'):
+		object := Object{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			next, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder, next)
+			if err != nil {
+				return nil, err
+			}
+			object.Keys = append(object.Keys, key.(string))
+			object.Values = append(object.Values, value)
+		}
+		_, err := decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			next, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder, next)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
@@ -0,0 +1,94 @@
+package faker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testMaskKey = "0123456789abcdef-test-key"
+
+func newTestMasker(t *testing.T, config string) *Masker {
+	t.Helper()
+	parsed, err := ParseMaskConfig([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	masker, err := NewMasker(parsed, testMaskKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return masker
+}
+
+func TestMaskDistinctValuesStayDistinct(t *testing.T) {
+	tests := []struct {
+		maskType string
+		value    func(i int) string
+	}{
+		{"email", func(i int) string { return fmt.Sprintf("customer%d@example.com", i) }},
+		{"username", func(i int) string { return fmt.Sprintf("user%d", i) }},
+		{"name", func(i int) string { return fmt.Sprintf("Customer Number%d", i) }},
+		{"lastName", func(i int) string { return fmt.Sprintf("Surname%d", i) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.maskType, func(t *testing.T) {
+			masker := newTestMasker(t, `{"columns": {"a": "`+tt.maskType+`"}}`)
+			rule := masker.config.rules["a"]
+
+			fakes := map[string]string{}
+			for i := 0; i < 5000; i++ {
+				value := tt.value(i)
+				fake, err := masker.Value(rule, value)
+				if err != nil {
+					t.Fatalf("%s: %v", value, err)
+				}
+				if previous, ok := fakes[fake]; ok {
+					t.Fatalf("%s and %s both became %s", previous, value, fake)
+				}
+				fakes[fake] = value
+			}
+		})
+	}
+}
+
+func TestMaskConsistentAcrossColumnsAndRuns(t *testing.T) {
+	config := `{"columns": {"email": "email", "contact": "email", "name": "name"}}`
+	input := "email,contact,name\nJane@Example.com,jane@example.com,Jane  Doe\n"
+
+	var outputs []string
+	for run := 0; run < 2; run++ {
+		var output strings.Builder
+		if _, err := newTestMasker(t, config).Mask(strings.NewReader(input), &output, FormatCSV); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, output.String())
+	}
+	if outputs[0] != outputs[1] {
+		t.Fatalf("runs with the same key differ:\n%s\n%s", outputs[0], outputs[1])
+	}
+
+	row := strings.Split(strings.Split(outputs[0], "\n")[1], ",")
+	if row[0] != row[1] {
+		t.Errorf("equal emails in two columns got %q and %q", row[0], row[1])
+	}
+	if strings.Contains(strings.ToLower(outputs[0]), "jane") {
+		t.Errorf("output still contains the original values: %s", outputs[0])
+	}
+}
+
+func TestMaskCollisionFails(t *testing.T) {
+	masker := newTestMasker(t, `{"columns": {"a": "email"}}`)
+	rule := masker.config.rules["a"]
+
+	fake, err := masker.Value(rule, "one@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pretend the fake was issued for another value first
+	masker.issued[rule.Namespace][fake] = "other@example.com"
+	if _, err := masker.Value(rule, "one@example.com"); err != ErrMaskCollision {
+		t.Fatalf("got %v, want ErrMaskCollision", err)
+	}
+}
@@ -0,0 +1,246 @@
+package faker
+
+import (
+	"fmt"
+	"math"
+	"regexp/syntax"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxPatternLength bounds user-supplied patterns
+	maxPatternLength = 200
+	// maxUnboundedRepeat caps *, + and {n,} so generated strings stay short
+	maxUnboundedRepeat = 8
+	// maxPatternOutput bounds the characters one generated string can have,
+	// which nested repeats such as ((x+)+)+ would otherwise multiply
+	maxPatternOutput = 1000
+)
+
+// Pattern generates strings matching a regular expression
+type Pattern struct {
+	re *syntax.Regexp
+}
+
+// CompilePattern parses a regular expression for generation. Anchors and word
+// boundaries are accepted and ignored, since every generated string is a
+// complete match.
+func CompilePattern(pattern string) (*Pattern, error) {
+	if len(pattern) > maxPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d characters", maxPatternLength)
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %v", err)
+	}
+	re = re.Simplify()
+	if err := checkPattern(re); err != nil {
+		return nil, err
+	}
+	if patternMaxLength(re) > maxPatternOutput {
+		return nil, fmt.Errorf("pattern can generate strings longer than %d characters; bound or unnest its repeats", maxPatternOutput)
+	}
+	return &Pattern{re: re}, nil
+}
+
+// checkPattern rejects constructs that cannot be generated
+func checkPattern(re *syntax.Regexp) error {
+	if re.Op == syntax.OpNoMatch {
+		return fmt.Errorf("pattern can never match")
+	}
+	for _, sub := range re.Sub {
+		if err := checkPattern(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generate returns a random string matching the pattern
+func (p *Pattern) Generate(g *Generator) string {
+	var b strings.Builder
+	g.writePattern(&b, p.re)
+	return b.String()
+}
+
+// MaxLength returns the most characters a generated string can have
+func (p *Pattern) MaxLength() float64 {
+	return patternMaxLength(p.re)
+}
+
+// Capacity returns how many distinct strings the pattern can produce, or
+// +Inf when it is effectively unlimited
+func (p *Pattern) Capacity() float64 {
+	return patternCapacity(p.re)
+}
+
+func (g *Generator) writePattern(b *strings.Builder, re *syntax.Regexp) {
+	// CompilePattern rejects longer patterns, so this is only a backstop
+	if b.Len() >= maxPatternOutput*utf8.UTFMax {
+		return
+	}
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			for _, r := range re.Rune {
+				if g.rng.Intn(2) == 0 {
+					b.WriteString(strings.ToUpper(string(r)))
+				} else {
+					b.WriteString(strings.ToLower(string(r)))
+				}
+			}
+			return
+		}
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		b.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		b.WriteRune(rune(' ' + g.rng.Intn('~'-' '+1)))
+	case syntax.OpCapture:
+		g.writePattern(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.writePattern(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.writePattern(b, re.Sub[g.rng.Intn(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatBounds(re)
+		for i := min + g.rng.Intn(max-min+1); i > 0; i-- {
+			g.writePattern(b, re.Sub[0])
+		}
+	}
+	// Empty matches, anchors and word boundaries produce nothing
+}
+
+// classRune picks a rune from a character class given as ranges, preferring
+// printable ASCII so that negated classes such as [^a-z] stay readable
+func (g *Generator) classRune(ranges []rune) rune {
+	ranges = printableRanges(ranges)
+	n := g.rng.Intn(rangesSize(ranges))
+	for i := 0; i < len(ranges); i += 2 {
+		if size := int(ranges[i+1] - ranges[i] + 1); n >= size {
+			n -= size
+			continue
+		}
+		return ranges[i] + rune(n)
+	}
+	return ranges[0]
+}
+
+// printableRanges clamps character class ranges to printable ASCII, keeping
+// the first range as is when none of them are printable
+func printableRanges(ranges []rune) []rune {
+	var printable []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			printable = append(printable, lo, hi)
+		}
+	}
+	if len(printable) == 0 {
+		return ranges[:2]
+	}
+	return printable
+}
+
+// rangesSize counts the runes in a list of ranges
+func rangesSize(ranges []rune) int {
+	total := 0
+	for i := 0; i < len(ranges); i += 2 {
+		total += int(ranges[i+1] - ranges[i] + 1)
+	}
+	return total
+}
+
+// repeatBounds returns the number of repetitions allowed by a repeat operator
+func repeatBounds(re *syntax.Regexp) (int, int) {
+	switch re.Op {
+	case syntax.OpStar:
+		return 0, maxUnboundedRepeat
+	case syntax.OpPlus:
+		return 1, maxUnboundedRepeat
+	case syntax.OpQuest:
+		return 0, 1
+	default:
+		if re.Max < 0 {
+			return re.Min, re.Min + maxUnboundedRepeat
+		}
+		return re.Min, re.Max
+	}
+}
+
+// patternMaxLength returns the most characters writePattern can write for re
+func patternMaxLength(re *syntax.Regexp) float64 {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return float64(len(re.Rune))
+	case syntax.OpCharClass, syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return 1
+	case syntax.OpCapture:
+		return patternMaxLength(re.Sub[0])
+	case syntax.OpConcat:
+		sum := 0.0
+		for _, sub := range re.Sub {
+			sum += patternMaxLength(sub)
+		}
+		return sum
+	case syntax.OpAlternate:
+		longest := 0.0
+		for _, sub := range re.Sub {
+			longest = math.Max(longest, patternMaxLength(sub))
+		}
+		return longest
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		_, max := repeatBounds(re)
+		return float64(max) * patternMaxLength(re.Sub[0])
+	default:
+		return 0
+	}
+}
+
+func patternCapacity(re *syntax.Regexp) float64 {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return math.Pow(2, float64(len(re.Rune)))
+		}
+		return 1
+	case syntax.OpCharClass:
+		return float64(rangesSize(printableRanges(re.Rune)))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		return '~' - ' ' + 1
+	case syntax.OpCapture:
+		return patternCapacity(re.Sub[0])
+	case syntax.OpConcat:
+		product := 1.0
+		for _, sub := range re.Sub {
+			product *= patternCapacity(sub)
+		}
+		return product
+	case syntax.OpAlternate:
+		sum := 0.0
+		for _, sub := range re.Sub {
+			sum += patternCapacity(sub)
+		}
+		return sum
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := repeatBounds(re)
+		each := patternCapacity(re.Sub[0])
+		sum := 0.0
+		for i := min; i <= max; i++ {
+			sum += math.Pow(each, float64(i))
+		}
+		return sum
+	default:
+		return 1
+	}
+}
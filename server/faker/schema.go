@@ -0,0 +1,958 @@
+package faker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema limits, so a single request cannot ask for unbounded work
+const (
+	maxSchemaFields   = 200
+	maxSchemaDepth    = 5
+	maxArrayItems     = 100
+	maxUniqueAttempts = 1000
+	// maxGeneratedValues caps the values a request can generate, counting
+	// every array at its maxItems
+	maxGeneratedValues = 1000000
+	// maxGeneratedPatternChars caps the characters a request's regex fields
+	// can generate, counted the same way
+	maxGeneratedPatternChars = 20000000
+)
+
+// SchemaError describes one problem with a schema. Path names the field, with
+// nested fields joined by dots and array items marked with [].
+type SchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// SchemaErrors lists every problem found in a schema
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Path + ": " + err.Message
+	}
+	return "invalid schema: " + strings.Join(messages, "; ")
+}
+
+// Object is a generated record or nested object. It encodes to JSON with its
+// keys in schema order.
+type Object struct {
+	Keys   []string
+	Values []interface{}
+}
+
+// MarshalJSON encodes the object with its keys in order
+func (o Object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, key := range o.Keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		encodedKey, _ := json.Marshal(key)
+		value, err := json.Marshal(o.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(encodedKey)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// Schema generates records shaped like a user-defined template. A schema
+// tracks the values of unique fields, so use a fresh one for each dataset.
+type Schema struct {
+	fields []schemaField
+}
+
+type schemaField struct {
+	name string
+	path string
+	node schemaNode
+}
+
+// schemaNode generates the values of one field
+type schemaNode interface {
+	generate(g *Generator) (interface{}, error)
+	capacity(l *Locale) float64 // number of distinct values, +Inf if unlimited
+}
+
+// SchemaTypes are the generator types a field can use
+var SchemaTypes = []string{
+	"name", "firstName", "lastName", "email", "username", "password", "phone",
+	"address", "creditCard", "uuid", "bool", "int", "float", "date", "enum",
+	"regex", "object", "array", "iban", "ssn", "nino", "pan", "aadhaar", "vat",
+	"passport",
+}
+
+// schemaProperties lists the properties each type accepts besides the ones
+// every field accepts
+var schemaProperties = map[string][]string{
+	"int":        {"min", "max"},
+	"float":      {"min", "max", "decimals"},
+	"date":       {"from", "to", "format"},
+	"enum":       {"values"},
+	"regex":      {"pattern"},
+	"object":     {"fields"},
+	"array":      {"items", "minItems", "maxItems"},
+	"creditCard": {"network", "testOnly"},
+	"iban":       {"country"},
+	"vat":        {"country"},
+	"passport":   {"country"},
+}
+
+var commonProperties = []string{"type", "unique", "nullable", "nullRate"}
+
+// fieldSpec is a field definition as written in a schema
+type fieldSpec struct {
+	Type     string          `json:"type"`
+	Min      *float64        `json:"min"`
+	Max      *float64        `json:"max"`
+	Decimals *int            `json:"decimals"`
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Format   string          `json:"format"`
+	Values   []interface{}   `json:"values"`
+	Pattern  string          `json:"pattern"`
+	Fields   json.RawMessage `json:"fields"`
+	Items    json.RawMessage `json:"items"`
+	MinItems *int            `json:"minItems"`
+	MaxItems *int            `json:"maxItems"`
+	Unique   bool            `json:"unique"`
+	Nullable bool            `json:"nullable"`
+	NullRate *float64        `json:"nullRate"`
+	Network  string          `json:"network"`
+	TestOnly bool            `json:"testOnly"`
+	Country  string          `json:"country"`
+}
+
+// ParseSchema compiles a schema such as
+//
+//	{"fields": {
+//	  "id":    "uuid",
+//	  "email": {"type": "email", "unique": true},
+//	  "age":   {"type": "int", "min": 18, "max": 99},
+//	  "tags":  {"type": "array", "items": {"type": "enum", "values": ["a", "b"]}, "maxItems": 3}
+//	}}
+//
+// A field is either a type name or an object with a type and its options.
+// Every problem found is reported as a SchemaErrors.
+func ParseSchema(data []byte) (*Schema, error) {
+	var document struct {
+		Fields json.RawMessage `json:"fields"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, SchemaErrors{{Path: "$", Message: describeJSONError(err)}}
+	}
+
+	c := &schemaCompiler{}
+	fields := c.fields(document.Fields, "", 0)
+	if len(c.errors) > 0 {
+		return nil, c.errors
+	}
+	return &Schema{fields: fields}, nil
+}
+
+// Columns returns the names of the top-level fields, in schema order
+func (s *Schema) Columns() []string {
+	columns := make([]string, len(s.fields))
+	for i, field := range s.fields {
+		columns[i] = field.name
+	}
+	return columns
+}
+
+// CheckCount reports unique fields that cannot produce count distinct values
+// in the given locale. Unique fields inside arrays only need to be unique
+// within each array, so they are checked against the array's maxItems. It
+// also rejects count records that could hold more than maxGeneratedValues
+// values or maxGeneratedPatternChars characters of regex fields.
+func (s *Schema) CheckCount(count int, locale *Locale) error {
+	var errs SchemaErrors
+	if values := s.maxValues() * float64(count); values > maxGeneratedValues {
+		errs = append(errs, SchemaError{
+			Path:    "$",
+			Message: fmt.Sprintf("%d records of this schema could hold %.0f values, more than the limit of %d; lower count or maxItems", count, values, maxGeneratedValues),
+		})
+	}
+	if chars := s.maxPatternChars() * float64(count); chars > maxGeneratedPatternChars {
+		errs = append(errs, SchemaError{
+			Path:    "$",
+			Message: fmt.Sprintf("%d records of this schema could hold %.0f characters of regex fields, more than the limit of %d; lower count, maxItems or the patterns' repeats", count, chars, maxGeneratedPatternChars),
+		})
+	}
+	var walk func(path string, node schemaNode, demand int, limit string)
+	walk = func(path string, node schemaNode, demand int, limit string) {
+		node = unwrap(node)
+		if unique, ok := node.(*uniqueNode); ok {
+			if capacity := unique.inner.capacity(locale); capacity < float64(demand) {
+				errs = append(errs, SchemaError{
+					Path:    path,
+					Message: fmt.Sprintf("only %.0f unique values are possible but %s is %d", capacity, limit, demand),
+				})
+			}
+			node = unique.inner
+		}
+
+		switch n := node.(type) {
+		case *objectNode:
+			for _, field := range n.fields {
+				walk(field.path, field.node, demand, limit)
+			}
+		case *arrayNode:
+			walk(path+"[]", n.item, n.maxItems, "maxItems")
+		}
+	}
+	for _, field := range s.fields {
+		walk(field.path, field.node, count, "count")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// maxValues returns the most values one record can hold, counting each
+// field and array item
+func (s *Schema) maxValues() float64 {
+	var values func(node schemaNode) float64
+	values = func(node schemaNode) float64 {
+		switch n := unwrap(node).(type) {
+		case *uniqueNode:
+			return values(n.inner)
+		case *objectNode:
+			total := 1.0
+			for _, field := range n.fields {
+				total += values(field.node)
+			}
+			return total
+		case *arrayNode:
+			return 1 + float64(n.maxItems)*values(n.item)
+		}
+		return 1
+	}
+
+	total := 0.0
+	for _, field := range s.fields {
+		total += values(field.node)
+	}
+	return total
+}
+
+// maxPatternChars returns the most characters the regex fields of one record
+// can generate, counting each array item
+func (s *Schema) maxPatternChars() float64 {
+	var chars func(node schemaNode) float64
+	chars = func(node schemaNode) float64 {
+		switch n := unwrap(node).(type) {
+		case *uniqueNode:
+			return chars(n.inner)
+		case *patternNode:
+			return n.pattern.MaxLength()
+		case *objectNode:
+			total := 0.0
+			for _, field := range n.fields {
+				total += chars(field.node)
+			}
+			return total
+		case *arrayNode:
+			return float64(n.maxItems) * chars(n.item)
+		}
+		return 0
+	}
+
+	total := 0.0
+	for _, field := range s.fields {
+		total += chars(field.node)
+	}
+	return total
+}
+
+// Generate returns the values of one record, in Columns order
+func (s *Schema) Generate(g *Generator) ([]interface{}, error) {
+	values := make([]interface{}, len(s.fields))
+	for i, field := range s.fields {
+		value, err := field.node.generate(g)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+type schemaCompiler struct {
+	errors     SchemaErrors
+	fieldCount int
+}
+
+func (c *schemaCompiler) fail(path string, format string, args ...interface{}) {
+	c.errors = append(c.errors, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// fields compiles an object of field definitions, keeping their order
+func (c *schemaCompiler) fields(raw json.RawMessage, path string, depth int) []schemaField {
+	location := path
+	if location == "" {
+		location = "$"
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		c.fail(location, "fields is required")
+		return nil
+	}
+	if depth > maxSchemaDepth {
+		c.fail(location, "objects and arrays can be nested at most %d levels deep", maxSchemaDepth)
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		c.fail(location, "fields must be an object mapping field names to types")
+		return nil
+	}
+
+	var fields []schemaField
+	seen := map[string]bool{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			c.fail(location, describeJSONError(err))
+			return nil
+		}
+		name := token.(string)
+
+		var definition json.RawMessage
+		if err := decoder.Decode(&definition); err != nil {
+			c.fail(location, describeJSONError(err))
+			return nil
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		if strings.TrimSpace(name) == "" {
+			c.fail(location, "field names must not be empty")
+			continue
+		}
+		if seen[name] {
+			c.fail(fieldPath, "field is defined more than once")
+			continue
+		}
+		seen[name] = true
+
+		c.fieldCount++
+		if c.fieldCount > maxSchemaFields {
+			c.fail(fieldPath, "schemas can have at most %d fields", maxSchemaFields)
+			return nil
+		}
+
+		if node := c.field(definition, fieldPath, depth); node != nil {
+			fields = append(fields, schemaField{name: name, path: fieldPath, node: node})
+		}
+	}
+
+	if len(seen) == 0 {
+		c.fail(location, "at least one field is required")
+	}
+	return fields
+}
+
+// field compiles one field definition
+func (c *schemaCompiler) field(raw json.RawMessage, path string, depth int) schemaNode {
+	var spec fieldSpec
+	var typeName string
+	if err := json.Unmarshal(raw, &typeName); err == nil {
+		spec.Type = typeName
+	} else {
+		var properties map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &properties); err != nil {
+			c.fail(path, "field must be a type name or an object with a type")
+			return nil
+		}
+		if err := json.Unmarshal(raw, &spec); err != nil {
+			c.fail(path, describeJSONError(err))
+			return nil
+		}
+		if !c.checkProperties(path, spec.Type, properties) {
+			return nil
+		}
+	}
+
+	node := c.typeNode(spec, path, depth)
+	if node == nil {
+		return nil
+	}
+
+	if spec.NullRate != nil && !spec.Nullable {
+		c.fail(path, "nullRate requires nullable to be true")
+		return nil
+	}
+	if spec.Unique {
+		node = &uniqueNode{inner: node, path: path, seen: map[string]bool{}}
+	}
+	if spec.Nullable {
+		rate := 0.1
+		if spec.NullRate != nil {
+			rate = *spec.NullRate
+			if rate < 0 || rate > 1 {
+				c.fail(path, "nullRate must be between 0 and 1")
+				return nil
+			}
+		}
+		node = &nullableNode{inner: node, rate: rate}
+	}
+	return node
+}
+
+// checkProperties rejects unknown properties and ones the type does not use
+func (c *schemaCompiler) checkProperties(path string, typeName string, properties map[string]json.RawMessage) bool {
+	if _, ok := properties["type"]; !ok {
+		c.fail(path, "type is required")
+		return false
+	}
+
+	allowed := map[string]bool{}
+	for _, property := range commonProperties {
+		allowed[property] = true
+	}
+	for _, property := range schemaProperties[typeName] {
+		allowed[property] = true
+	}
+
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ok := true
+	for _, name := range names {
+		if allowed[name] {
+			continue
+		}
+		ok = false
+		if isKnownProperty(name) {
+			c.fail(path, "%s is not used by type %s", name, typeName)
+		} else {
+			c.fail(path, "unknown property %q", name)
+		}
+	}
+	return ok
+}
+
+func isKnownProperty(name string) bool {
+	for _, properties := range schemaProperties {
+		for _, property := range properties {
+			if property == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeNode compiles the generator for a field's type
+func (c *schemaCompiler) typeNode(spec fieldSpec, path string, depth int) schemaNode {
+	switch spec.Type {
+	case "name":
+		return &simpleNode{
+			size: func(l *Locale) float64 { return float64(len(l.FirstNames) * len(l.LastNames)) },
+			gen:  func(g *Generator) interface{} { return g.Name() },
+		}
+	case "firstName":
+		return &simpleNode{
+			size: func(l *Locale) float64 { return float64(len(l.FirstNames)) },
+			gen:  func(g *Generator) interface{} { return g.FirstName() },
+		}
+	case "lastName":
+		return &simpleNode{
+			size: func(l *Locale) float64 { return float64(len(l.LastNames)) },
+			gen:  func(g *Generator) interface{} { return g.LastName() },
+		}
+	case "email":
+		return &simpleNode{
+			size: func(l *Locale) float64 { return float64(len(l.FirstNames) * len(l.LastNames) * len(l.EmailDomains)) },
+			gen:  func(g *Generator) interface{} { return g.Email() },
+		}
+	case "username":
+		return &simpleNode{
+			size: fixedSize(float64(len(usernameAdjectives) * len(usernameNouns) * 100)),
+			gen:  func(g *Generator) interface{} { return g.Username() },
+		}
+	case "password":
+		return &simpleNode{size: fixedSize(math.Inf(1)), gen: func(g *Generator) interface{} { return g.Password() }}
+	case "phone":
+		return &simpleNode{
+			size: func(l *Locale) float64 { return l.phoneCapacity() },
+			gen:  func(g *Generator) interface{} { return g.Phone() },
+		}
+	case "address":
+		return &simpleNode{
+			size: func(l *Locale) float64 { return l.addressCapacity() },
+			gen:  func(g *Generator) interface{} { return g.Address() },
+		}
+	case "creditCard":
+		options := CardOptions{Network: spec.Network, TestOnly: spec.TestOnly}
+		if err := options.validate(); err != nil {
+			c.fail(path, "%v", err)
+			return nil
+		}
+		return &simpleNode{size: fixedSize(options.capacity()), gen: func(g *Generator) interface{} { return g.card(options).Number }}
+	case "iban", "ssn", "nino", "pan", "aadhaar", "vat", "passport":
+		kind := spec.Type
+		if _, err := IdentifierKinds[kind].country(spec.Country, nil); err != nil {
+			c.fail(path, "%v", err)
+			return nil
+		}
+		return &simpleNode{size: fixedSize(math.Inf(1)), gen: func(g *Generator) interface{} {
+			value, _ := g.Identifier(kind, spec.Country)
+			return value
+		}}
+	case "uuid":
+		return &simpleNode{size: fixedSize(math.Inf(1)), gen: func(g *Generator) interface{} { return g.UUID() }}
+	case "bool":
+		return &simpleNode{size: fixedSize(2), gen: func(g *Generator) interface{} { return g.rng.Intn(2) == 1 }}
+	case "int":
+		return c.intNode(spec, path)
+	case "float":
+		return c.floatNode(spec, path)
+	case "date":
+		return c.dateNode(spec, path)
+	case "enum":
+		return c.enumNode(spec, path)
+	case "regex":
+		return c.patternNode(spec, path)
+	case "object":
+		fields := c.fields(spec.Fields, path, depth+1)
+		if fields == nil {
+			return nil
+		}
+		return &objectNode{fields: fields}
+	case "array":
+		return c.arrayNode(spec, path, depth)
+	case "":
+		c.fail(path, "type is required")
+		return nil
+	default:
+		c.fail(path, "unknown type %q, expected one of %s", spec.Type, strings.Join(SchemaTypes, ", "))
+		return nil
+	}
+}
+
+func (c *schemaCompiler) intNode(spec fieldSpec, path string) schemaNode {
+	min, max := 0.0, 1000.0
+	if spec.Min != nil {
+		min = *spec.Min
+	}
+	if spec.Max != nil {
+		max = *spec.Max
+	}
+	if min != math.Trunc(min) || max != math.Trunc(max) {
+		c.fail(path, "min and max must be whole numbers for type int")
+		return nil
+	}
+	if math.Abs(min) > 1<<53 || math.Abs(max) > 1<<53 {
+		c.fail(path, "min and max must be between -2^53 and 2^53")
+		return nil
+	}
+	if min > max {
+		c.fail(path, "min (%.0f) must not be greater than max (%.0f)", min, max)
+		return nil
+	}
+	return &intNode{min: int64(min), max: int64(max)}
+}
+
+func (c *schemaCompiler) floatNode(spec fieldSpec, path string) schemaNode {
+	node := &floatNode{min: 0, max: 1, decimals: 2}
+	if spec.Min != nil {
+		node.min = *spec.Min
+	}
+	if spec.Max != nil {
+		node.max = *spec.Max
+	}
+	if spec.Decimals != nil {
+		node.decimals = *spec.Decimals
+	}
+	if node.min > node.max {
+		c.fail(path, "min (%g) must not be greater than max (%g)", node.min, node.max)
+		return nil
+	}
+	if node.decimals < 0 || node.decimals > 10 {
+		c.fail(path, "decimals must be between 0 and 10")
+		return nil
+	}
+	// Values are scaled by 10^decimals to round them, and that scaled
+	// range must stay finite or the values would come out as infinities
+	scale := math.Pow(10, float64(node.decimals))
+	if math.IsInf((node.max-node.min)*scale, 0) || math.IsInf(math.Max(-node.min, node.max)*scale, 0) {
+		c.fail(path, "min (%g) and max (%g) are too large for %d decimals", node.min, node.max, node.decimals)
+		return nil
+	}
+	return node
+}
+
+// dateLayouts are the accepted forms of from and to
+var dateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// maxDateYears keeps a date range within the roughly 292 years a
+// time.Duration can hold
+const maxDateYears = 290
+
+func (c *schemaCompiler) dateNode(spec fieldSpec, path string) schemaNode {
+	node := &dateNode{
+		from:   time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+		to:     time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC),
+		format: spec.Format,
+	}
+	onlyDates := true
+
+	parse := func(name string, value string, target *time.Time) bool {
+		if value == "" {
+			return true
+		}
+		for i, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				*target = parsed.UTC()
+				onlyDates = onlyDates && i == 0
+				return true
+			}
+		}
+		c.fail(path, "%s must be a date (2006-01-02) or timestamp (2006-01-02T15:04:05Z)", name)
+		return false
+	}
+	if !parse("from", spec.From, &node.from) || !parse("to", spec.To, &node.to) {
+		return nil
+	}
+
+	if node.from.After(node.to) {
+		c.fail(path, "from (%s) must not be after to (%s)", spec.From, spec.To)
+		return nil
+	}
+	if node.to.After(node.from.AddDate(maxDateYears, 0, 0)) {
+		c.fail(path, "from and to must be at most %d years apart", maxDateYears)
+		return nil
+	}
+
+	switch node.format {
+	case "":
+		node.format = "datetime"
+		if onlyDates {
+			node.format = "date"
+		}
+	case "date", "datetime", "unix":
+	default:
+		c.fail(path, "format must be date, datetime or unix")
+		return nil
+	}
+	return node
+}
+
+func (c *schemaCompiler) enumNode(spec fieldSpec, path string) schemaNode {
+	if len(spec.Values) == 0 {
+		c.fail(path, "values must list at least one value for type enum")
+		return nil
+	}
+
+	distinct := map[string]bool{}
+	for _, value := range spec.Values {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			c.fail(path, "values must be strings, numbers, booleans or null")
+			return nil
+		}
+		key, _ := json.Marshal(value)
+		distinct[string(key)] = true
+	}
+	return &enumNode{values: spec.Values, distinct: len(distinct)}
+}
+
+func (c *schemaCompiler) patternNode(spec fieldSpec, path string) schemaNode {
+	if spec.Pattern == "" {
+		c.fail(path, "pattern is required for type regex")
+		return nil
+	}
+	pattern, err := CompilePattern(spec.Pattern)
+	if err != nil {
+		c.fail(path, "%v", err)
+		return nil
+	}
+	return &patternNode{pattern: pattern}
+}
+
+func (c *schemaCompiler) arrayNode(spec fieldSpec, path string, depth int) schemaNode {
+	if len(spec.Items) == 0 || string(spec.Items) == "null" {
+		c.fail(path, "items is required for type array")
+		return nil
+	}
+
+	node := &arrayNode{minItems: 1, maxItems: 5}
+	if spec.MinItems != nil {
+		node.minItems = *spec.MinItems
+	}
+	if spec.MaxItems != nil {
+		node.maxItems = *spec.MaxItems
+	} else if node.minItems > node.maxItems {
+		node.maxItems = node.minItems
+	}
+	if node.minItems < 0 || node.maxItems > maxArrayItems {
+		c.fail(path, "minItems and maxItems must be between 0 and %d", maxArrayItems)
+		return nil
+	}
+	if node.minItems > node.maxItems {
+		c.fail(path, "minItems (%d) must not be greater than maxItems (%d)", node.minItems, node.maxItems)
+		return nil
+	}
+	if depth+1 > maxSchemaDepth {
+		c.fail(path, "objects and arrays can be nested at most %d levels deep", maxSchemaDepth)
+		return nil
+	}
+
+	itemPath := path + "[]"
+	node.item = c.field(spec.Items, itemPath, depth+1)
+	if node.item == nil {
+		return nil
+	}
+	return node
+}
+
+// resetUnique forgets the values seen by every unique field under node
+func resetUnique(node schemaNode) {
+	node = unwrap(node)
+	if unique, ok := node.(*uniqueNode); ok {
+		unique.seen = map[string]bool{}
+		node = unique.inner
+	}
+
+	switch n := node.(type) {
+	case *objectNode:
+		for _, field := range n.fields {
+			resetUnique(field.node)
+		}
+	case *arrayNode:
+		resetUnique(n.item)
+	}
+}
+
+// unwrap returns the node under a nullable wrapper
+func unwrap(node schemaNode) schemaNode {
+	if nullable, ok := node.(*nullableNode); ok {
+		return nullable.inner
+	}
+	return node
+}
+
+// describeJSONError turns a JSON decoding error into a schema message
+func describeJSONError(err error) string {
+	if errors.Is(err, io.EOF) {
+		return "a JSON schema is required"
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field != "" {
+			return fmt.Sprintf("%s must be of type %s, not %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)
+		}
+		return fmt.Sprintf("expected %s, not %s", jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("malformed JSON at byte %d: %v", syntaxErr.Offset, syntaxErr)
+	}
+	if message := err.Error(); strings.HasPrefix(message, "json: unknown field ") {
+		return "unknown property " + strings.TrimPrefix(message, "json: unknown field ")
+	}
+	return err.Error()
+}
+
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "int", "int64", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	case "slice":
+		return "array"
+	case "map", "struct":
+		return "object"
+	default:
+		return kind
+	}
+}
+
+// simpleNode wraps one of the built-in generators
+type simpleNode struct {
+	size func(l *Locale) float64
+	gen  func(g *Generator) interface{}
+}
+
+// fixedSize is the capacity of generators that do not depend on the locale
+func fixedSize(size float64) func(l *Locale) float64 {
+	return func(*Locale) float64 { return size }
+}
+
+func (n *simpleNode) generate(g *Generator) (interface{}, error) { return n.gen(g), nil }
+func (n *simpleNode) capacity(l *Locale) float64                 { return n.size(l) }
+
+type intNode struct {
+	min, max int64
+}
+
+func (n *intNode) generate(g *Generator) (interface{}, error) {
+	return n.min + g.rng.Int63n(n.max-n.min+1), nil
+}
+func (n *intNode) capacity(l *Locale) float64 { return float64(n.max-n.min) + 1 }
+
+type floatNode struct {
+	min, max float64
+	decimals int
+}
+
+func (n *floatNode) generate(g *Generator) (interface{}, error) {
+	scale := math.Pow(10, float64(n.decimals))
+	value := n.min + g.rng.Float64()*(n.max-n.min)
+	return math.Round(value*scale) / scale, nil
+}
+func (n *floatNode) capacity(l *Locale) float64 {
+	return math.Floor((n.max-n.min)*math.Pow(10, float64(n.decimals))) + 1
+}
+
+type dateNode struct {
+	from, to time.Time
+	format   string
+}
+
+func (n *dateNode) generate(g *Generator) (interface{}, error) {
+	if n.format == "date" {
+		days := int64(n.to.Sub(n.from).Hours() / 24)
+		return n.from.AddDate(0, 0, int(g.rng.Int63n(days+1))).Format("2006-01-02"), nil
+	}
+	seconds := int64(n.to.Sub(n.from).Seconds())
+	value := n.from.Add(time.Duration(g.rng.Int63n(seconds+1)) * time.Second)
+	if n.format == "unix" {
+		return value.Unix(), nil
+	}
+	return value.Format(time.RFC3339), nil
+}
+func (n *dateNode) capacity(l *Locale) float64 {
+	if n.format == "date" {
+		return math.Floor(n.to.Sub(n.from).Hours()/24) + 1
+	}
+	return math.Floor(n.to.Sub(n.from).Seconds()) + 1
+}
+
+type enumNode struct {
+	values   []interface{}
+	distinct int
+}
+
+func (n *enumNode) generate(g *Generator) (interface{}, error) {
+	return n.values[g.rng.Intn(len(n.values))], nil
+}
+func (n *enumNode) capacity(l *Locale) float64 { return float64(n.distinct) }
+
+type patternNode struct {
+	pattern *Pattern
+}
+
+func (n *patternNode) generate(g *Generator) (interface{}, error) { return n.pattern.Generate(g), nil }
+func (n *patternNode) capacity(l *Locale) float64                 { return n.pattern.Capacity() }
+
+type objectNode struct {
+	fields []schemaField
+}
+
+func (n *objectNode) generate(g *Generator) (interface{}, error) {
+	object := Object{Keys: make([]string, len(n.fields)), Values: make([]interface{}, len(n.fields))}
+	for i, field := range n.fields {
+		value, err := field.node.generate(g)
+		if err != nil {
+			return nil, err
+		}
+		object.Keys[i], object.Values[i] = field.name, value
+	}
+	return object, nil
+}
+func (n *objectNode) capacity(l *Locale) float64 {
+	product := 1.0
+	for _, field := range n.fields {
+		product *= field.node.capacity(l)
+	}
+	return product
+}
+
+type arrayNode struct {
+	item               schemaNode
+	minItems, maxItems int
+}
+
+func (n *arrayNode) generate(g *Generator) (interface{}, error) {
+	// Unique values inside an array are unique within that array
+	resetUnique(n.item)
+
+	items := make([]interface{}, n.minItems+g.rng.Intn(n.maxItems-n.minItems+1))
+	for i := range items {
+		value, err := n.item.generate(g)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = value
+	}
+	return items, nil
+}
+func (n *arrayNode) capacity(l *Locale) float64 { return math.Inf(1) }
+
+// uniqueNode retries its inner generator until it produces an unseen value
+type uniqueNode struct {
+	inner schemaNode
+	path  string
+	seen  map[string]bool
+}
+
+func (n *uniqueNode) generate(g *Generator) (interface{}, error) {
+	for attempt := 0; attempt < maxUniqueAttempts; attempt++ {
+		value, err := n.inner.generate(g)
+		if err != nil {
+			return nil, err
+		}
+		key, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if !n.seen[string(key)] {
+			n.seen[string(key)] = true
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: ran out of unique values after %d attempts", n.path, maxUniqueAttempts)
+}
+func (n *uniqueNode) capacity(l *Locale) float64 { return n.inner.capacity(l) }
+
+// nullableNode produces null at the given rate
+type nullableNode struct {
+	inner schemaNode
+	rate  float64
+}
+
+func (n *nullableNode) generate(g *Generator) (interface{}, error) {
+	if g.rng.Float64() < n.rate {
+		return nil, nil
+	}
+	return n.inner.generate(g)
+}
+func (n *nullableNode) capacity(l *Locale) float64 { return n.inner.capacity(l) }
@@ -0,0 +1,62 @@
+package faker
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaLimits(t *testing.T) {
+	nestedArrays := func(levels int, items int) string {
+		field := `"uuid"`
+		for i := 0; i < levels; i++ {
+			field = `{"type": "array", "minItems": ` + strconv.Itoa(items) + `, "maxItems": ` + strconv.Itoa(items) + `, "items": ` + field + `}`
+		}
+		return `{"fields": {"ids": ` + field + `}}`
+	}
+
+	tests := []struct {
+		name    string
+		schema  string
+		count   int
+		wantErr string
+	}{
+		{"flat", `{"fields": {"id": "uuid", "email": "email"}}`, 10000, ""},
+		{"arrays within depth and budget", nestedArrays(2, 10), 1000, ""},
+		{"arrays nested too deep", nestedArrays(8, 100), 1, "nested at most"},
+		{"objects nested too deep", `{"fields": {"a": {"type": "object", "fields": {"b": {"type": "object", "fields": {"c": {"type": "object", "fields": {"d": {"type": "object", "fields": {"e": {"type": "object", "fields": {"f": {"type": "object", "fields": {"g": "uuid"}}}}}}}}}}}}}}`, 1, "nested at most"},
+		{"one record over budget", nestedArrays(3, 100), 1, "more than the limit"},
+		{"count over budget", nestedArrays(2, 100), 100, "more than the limit"},
+		{"nested unbounded repeats", `{"fields": {"code": {"type": "regex", "pattern": "((((((((((x+)+)+)+)+)+)+)+)+)+)+"}}}`, 1, "longer than 1000 characters"},
+		{"pattern within its length", `{"fields": {"code": {"type": "regex", "pattern": "[A-Z]{3}-\\d{4}"}}}`, 10000, ""},
+		{"dates within the range", `{"fields": {"born": {"type": "date", "from": "1800-01-01", "to": "2089-12-31"}}}`, 1, ""},
+		{"dates too far apart", `{"fields": {"born": {"type": "date", "from": "0001-01-01", "to": "9999-12-31"}}}`, 1, "at most 290 years apart"},
+		{"timestamps too far apart", `{"fields": {"at": {"type": "date", "from": "1700-01-01T00:00:00Z", "to": "2000-01-01T00:00:00Z"}}}`, 1, "at most 290 years apart"},
+		{"large floats", `{"fields": {"x": {"type": "float", "min": -1e200, "max": 1e200}}}`, 1, ""},
+		{"float range overflowing", `{"fields": {"x": {"type": "float", "min": -1e308, "max": 1e308, "decimals": 0}}}`, 1, "too large for"},
+		{"float overflowing when rounded", `{"fields": {"x": {"type": "float", "min": 1e300, "max": 1e300, "decimals": 10}}}`, 1, "too large for"},
+		{"pattern characters over budget", `{"fields": {"codes": {"type": "array", "maxItems": 100, "items": {"type": "regex", "pattern": "x{1000}"}}}}`, 1000, "characters of regex fields"},
+	}
+
+	locale, _ := LookupLocale(DefaultLocale)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSchema([]byte(tt.schema))
+			if err == nil {
+				err = schema.CheckCount(tt.count, locale)
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var schemaErrors SchemaErrors
+			if !errors.As(err, &schemaErrors) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package faker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// vatFormat describes the VAT IDs of one country, without the country prefix
+type vatFormat struct {
+	pattern  *regexp.Regexp
+	generate func(g *Generator) string
+	valid    func(number string) bool
+}
+
+// vatFormats are the supported countries, keyed by their VAT prefix
+var vatFormats = map[string]vatFormat{
+	// U and eight digits, the last a weighted check digit
+	"AT": {
+		pattern: regexp.MustCompile(`^U\d{8}$`),
+		generate: func(g *Generator) string {
+			body := g.digits(7)
+			return "U" + body + strconv.Itoa(austrianCheckDigit(body))
+		},
+		valid: func(number string) bool {
+			return austrianCheckDigit(number[1:8]) == int(number[8]-'0')
+		},
+	},
+	// Ten digits starting with 0 or 1, the last two being 97 minus the rest
+	// mod 97
+	"BE": {
+		pattern: regexp.MustCompile(`^[01]\d{9}$`),
+		generate: func(g *Generator) string {
+			body := strconv.Itoa(g.rng.Intn(2)) + g.nonZeroDigit() + g.digits(6)
+			n, _ := strconv.Atoi(body)
+			return body + fmt.Sprintf("%02d", 97-n%97)
+		},
+		valid: func(number string) bool {
+			n, _ := strconv.Atoi(number[:8])
+			check, _ := strconv.Atoi(number[8:])
+			return 97-n%97 == check
+		},
+	},
+	// Nine digits with an ISO 7064 MOD 11,10 check digit
+	"DE": {
+		pattern: regexp.MustCompile(`^[1-9]\d{8}$`),
+		generate: func(g *Generator) string {
+			body := g.nonZeroDigit() + g.digits(7)
+			return body + strconv.Itoa(iso7064Mod1110(body))
+		},
+		valid: func(number string) bool {
+			return iso7064Mod1110(number[:8]) == int(number[8]-'0')
+		},
+	},
+	// Eight digits whose weighted sum is divisible by 11
+	"DK": {
+		pattern: regexp.MustCompile(`^[1-9]\d{7}$`),
+		generate: func(g *Generator) string {
+			for {
+				body := g.nonZeroDigit() + g.digits(6)
+				check := (11 - weightedSum(body, []int{2, 7, 6, 5, 4, 3, 2})%11) % 11
+				if check < 10 {
+					return body + strconv.Itoa(check)
+				}
+			}
+		},
+		valid: func(number string) bool {
+			return weightedSum(number, []int{2, 7, 6, 5, 4, 3, 2, 1})%11 == 0
+		},
+	},
+	// Eight digits with a weighted mod 11 check digit
+	"FI": {
+		pattern: regexp.MustCompile(`^\d{8}$`),
+		generate: func(g *Generator) string {
+			for {
+				body := g.digits(7)
+				if check := finnishCheckDigit(body); check >= 0 {
+					return body + strconv.Itoa(check)
+				}
+			}
+		},
+		valid: func(number string) bool {
+			return finnishCheckDigit(number[:7]) == int(number[7]-'0')
+		},
+	},
+	// A two-digit key followed by the company's SIREN, which has a Luhn
+	// check digit
+	"FR": {
+		pattern: regexp.MustCompile(`^\d{11}$`),
+		generate: func(g *Generator) string {
+			body := g.nonZeroDigit() + g.digits(7)
+			siren := body + string(luhnCheckDigit(body))
+			return frenchVATKey(siren) + siren
+		},
+		valid: func(number string) bool {
+			siren := number[2:]
+			return luhnCheckDigit(siren[:8]) == siren[8] && frenchVATKey(siren) == number[:2]
+		},
+	},
+	// Seven digits, a three-digit office code and a Luhn check digit
+	"IT": {
+		pattern: regexp.MustCompile(`^\d{11}$`),
+		generate: func(g *Generator) string {
+			body := g.nonZeroDigit() + g.digits(6) + fmt.Sprintf("%03d", 1+g.rng.Intn(100))
+			return body + string(luhnCheckDigit(body))
+		},
+		valid: func(number string) bool {
+			return luhnCheckDigit(number[:10]) == number[10]
+		},
+	},
+	// Six digits followed by their remainder mod 89
+	"LU": {
+		pattern: regexp.MustCompile(`^\d{8}$`),
+		generate: func(g *Generator) string {
+			body := g.nonZeroDigit() + g.digits(5)
+			n, _ := strconv.Atoi(body)
+			return body + fmt.Sprintf("%02d", n%89)
+		},
+		valid: func(number string) bool {
+			n, _ := strconv.Atoi(number[:6])
+			check, _ := strconv.Atoi(number[6:])
+			return n%89 == check
+		},
+	},
+	// Nine digits, B and two digits. Numbers issued since 2020 pass an
+	// ISO 7064 mod 97-10 check over NL and the number; older ones are an
+	// RSIN, whose nine digits pass a weighted mod 11 check.
+	"NL": {
+		pattern: regexp.MustCompile(`^\d{9}B\d{2}$`),
+		generate: func(g *Generator) string {
+			body := g.digits(9) + "B"
+			check := (98 - mod97("NL"+body+"00")) % 97
+			if check == 0 {
+				check = 97
+			}
+			return body + fmt.Sprintf("%02d", check)
+		},
+		valid: func(number string) bool {
+			return mod97("NL"+number) == 1 || dutchRSINValid(number[:9])
+		},
+	},
+	// Ten digits with a weighted mod 11 check digit
+	"PL": {
+		pattern: regexp.MustCompile(`^\d{10}$`),
+		generate: func(g *Generator) string {
+			for {
+				body := g.nonZeroDigit() + g.digits(8)
+				if check := weightedSum(body, []int{6, 5, 7, 2, 3, 4, 5, 6, 7}) % 11; check < 10 {
+					return body + strconv.Itoa(check)
+				}
+			}
+		},
+		valid: func(number string) bool {
+			check := weightedSum(number[:9], []int{6, 5, 7, 2, 3, 4, 5, 6, 7}) % 11
+			return check < 10 && check == int(number[9]-'0')
+		},
+	},
+	// Nine digits with a weighted mod 11 check digit
+	"PT": {
+		pattern: regexp.MustCompile(`^[1-9]\d{8}$`),
+		generate: func(g *Generator) string {
+			body := g.nonZeroDigit() + g.digits(7)
+			return body + strconv.Itoa(portugueseCheckDigit(body))
+		},
+		valid: func(number string) bool {
+			return portugueseCheckDigit(number[:8]) == int(number[8]-'0')
+		},
+	},
+	// A ten-digit organisation number with a Luhn check digit, then 01
+	"SE": {
+		pattern: regexp.MustCompile(`^\d{10}01$`),
+		generate: func(g *Generator) string {
+			body := strconv.Itoa(5+g.rng.Intn(5)) + g.digits(8)
+			return body + string(luhnCheckDigit(body)) + "01"
+		},
+		valid: func(number string) bool {
+			return luhnCheckDigit(number[:9]) == number[9]
+		},
+	},
+}
+
+// vat generates a VAT ID, with its country prefix, that passes the country's
+// check digit rules
+func (g *Generator) vat(country string) string {
+	return country + vatFormats[country].generate(g)
+}
+
+func validateVAT(value string, _ string) string {
+	if len(value) < 3 {
+		return "too short"
+	}
+	country := value[:2]
+	format, ok := vatFormats[country]
+	if !ok {
+		return "unsupported country " + country
+	}
+	if !format.pattern.MatchString(value[2:]) {
+		return fmt.Sprintf("does not match the %s VAT ID format", country)
+	}
+	if !format.valid(value[2:]) {
+		return "wrong check digits"
+	}
+	return ""
+}
+
+// digits returns n random decimal digits
+func (g *Generator) digits(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('0' + g.rng.Intn(10))
+	}
+	return string(b)
+}
+
+// nonZeroDigit returns a random digit from 1 to 9
+func (g *Generator) nonZeroDigit() string {
+	return strconv.Itoa(1 + g.rng.Intn(9))
+}
+
+// weightedSum multiplies each digit by its weight and adds the products
+func weightedSum(digits string, weights []int) int {
+	sum := 0
+	for i, weight := range weights {
+		sum += int(digits[i]-'0') * weight
+	}
+	return sum
+}
+
+func austrianCheckDigit(body string) int {
+	sum := 0
+	for i := 0; i < 7; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d = d*2/10 + d*2%10
+		}
+		sum += d
+	}
+	return (10 - (sum+4)%10) % 10
+}
+
+func iso7064Mod1110(body string) int {
+	product := 10
+	for i := 0; i < len(body); i++ {
+		sum := (int(body[i]-'0') + product) % 10
+		if sum == 0 {
+			sum = 10
+		}
+		product = sum * 2 % 11
+	}
+	return (11 - product) % 10
+}
+
+// finnishCheckDigit returns the check digit, or -1 if none is valid
+func finnishCheckDigit(body string) int {
+	remainder := weightedSum(body, []int{7, 9, 10, 5, 8, 4, 2}) % 11
+	switch remainder {
+	case 0:
+		return 0
+	case 1:
+		return -1
+	}
+	return 11 - remainder
+}
+
+func frenchVATKey(siren string) string {
+	n, _ := strconv.Atoi(siren)
+	return fmt.Sprintf("%02d", (12+3*(n%97))%97)
+}
+
+func portugueseCheckDigit(body string) int {
+	check := 11 - weightedSum(body, []int{9, 8, 7, 6, 5, 4, 3, 2})%11
+	if check >= 10 {
+		return 0
+	}
+	return check
+}
+
+// dutchRSINValid reports whether the weighted sum of the first eight digits
+// mod 11 is the ninth digit
+func dutchRSINValid(rsin string) bool {
+	check := weightedSum(rsin, []int{9, 8, 7, 6, 5, 4, 3, 2}) % 11
+	return check < 10 && check == int(rsin[8]-'0')
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,6 +15,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/siddhantgureja/safetrace/controllers"
+	"github.com/siddhantgureja/safetrace/middleware"
+	"github.com/siddhantgureja/safetrace/risk"
+	"github.com/siddhantgureja/safetrace/services"
 )
 
 var client *mongo.Client
@@ -49,22 +53,114 @@ func main() {
 	// Initialize router
 	router := gin.Default()
 
+	// Only trust X-Forwarded-For from known proxies, so clients cannot pick
+	// the IP they are rate limited under
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal(err)
+	}
+
 	// Set up CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "X-Fake-Data-Seed", "X-Fake-Data-Month"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Resolve the authenticated user from the bearer token, if any
+	router.Use(middleware.Auth())
+
 	// Initialize controllers
-	fakeDataController := controllers.NewFakeDataController()
-	breachCheckController := controllers.NewBreachCheckController()
+	inboxService := services.NewInboxService(client, services.NewMailTMClient())
+	smtpSink := services.NewSMTPSink(client)
+	maskedEmail := services.NewMaskedEmailService(client)
+	fakeDataController := controllers.NewFakeDataController(inboxService, smtpSink)
+	maskController := controllers.NewMaskController()
+	piiController := controllers.NewPIIController()
+	inboxController := controllers.NewInboxController(inboxService)
 	vaultController := controllers.NewVaultController(client)
 	newsController := controllers.NewNewsController()
-	riskController := controllers.NewRiskController()
+	passwordController := controllers.NewPasswordController()
+	monitorController := controllers.NewMonitorController(client)
+	notifier := services.NewNotifier(client)
+	notificationController := controllers.NewNotificationController(client, notifier)
+
+	breachProvider, err := services.NewBreachProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+	breachLookup, err := services.NewBreachLookup(breachProvider, services.NewBreachCache(client))
+	if err != nil {
+		log.Fatal(err)
+	}
+	breachHistory := services.NewBreachHistory(client)
+	breachCheckController := controllers.NewBreachCheckController(breachLookup, breachHistory)
+	historyController := controllers.NewHistoryController(client, breachLookup)
+	domainController := controllers.NewDomainController(client, breachProvider, services.NewTXTResolver())
+
+	riskPolicies, err := risk.NewPolicyStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	riskHistory := services.NewRiskHistory(client, riskPolicies, notifier)
+	riskController := controllers.NewRiskController(riskPolicies, services.NewRiskEvidence(client, breachLookup), riskHistory)
+
+	// Rate limits, overridable per route with RATE_LIMIT_<ROUTE>
+	rateLimitStore := services.NewRateLimitStore(client)
+	rateLimit := func(route string, def services.RouteLimits) gin.HandlerFunc {
+		limits, err := services.RouteLimitsFromEnv(route, def)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return middleware.RateLimit(rateLimitStore, route, limits)
+	}
+	breachCheckLimits := services.RouteLimits{
+		User: services.RateLimitPolicy{Rate: 30.0 / 60, Burst: 10},
+		IP:   services.RateLimitPolicy{Rate: 10.0 / 60, Burst: 5},
+	}
+	// Each inbox is a mailbox on a third-party service or in our database,
+	// so they are limited far more tightly than plain fake data
+	inboxLimit := rateLimit("fake_data_inbox", services.RouteLimits{
+		User: services.RateLimitPolicy{Rate: 20.0 / 3600, Burst: 5},
+		IP:   services.RateLimitPolicy{Rate: 40.0 / 3600, Burst: 10},
+	})
+	limitInboxes := func(ctx *gin.Context) {
+		if ctx.DefaultQuery("inbox", "false") != "false" {
+			inboxLimit(ctx)
+		}
+	}
+
+	// Start background breach monitoring
+	monitorScheduler := services.NewMonitorScheduler(client, breachProvider, notifier)
+	go monitorScheduler.Run(context.Background())
+
+	// Purge breach check history past its retention period
+	go breachHistory.Run(context.Background())
+
+	// Delete disposable inboxes once their TTL has passed
+	go inboxService.Run(context.Background())
+
+	// Receive mail for fake identities when SMTP_SINK_DOMAIN is set
+	if smtpSink != nil {
+		go smtpSink.Run(context.Background())
+	}
+
+	// Forward mail sent to masked aliases when ALIAS_DOMAIN is set
+	if maskedEmail != nil {
+		go maskedEmail.Run(context.Background())
+	}
+
+	// Reload the risk policy when RISK_POLICY_FILE changes
+	go riskPolicies.Run(context.Background())
+
+	// Send weekly risk digests and purge old assessments
+	go riskHistory.Run(context.Background())
 
 	// Health check endpoint
 	router.GET("/api/health", func(c *gin.Context) {
@@ -77,14 +173,73 @@ func main() {
 		// Fake data routes
 		fakeData := api.Group("/fake-data")
 		{
-			fakeData.GET("/generate", fakeDataController.GenerateFakeData)
+			fakeData.GET("/generate", limitInboxes, fakeDataController.GenerateFakeData)
+			fakeData.POST("/schema", fakeDataController.GenerateFromSchema)
+			fakeData.GET("/identifiers", fakeDataController.ListIdentifierTypes)
+			fakeData.GET("/identifiers/:type", fakeDataController.GenerateIdentifiers)
+			fakeData.POST("/identifiers/:type/validate", fakeDataController.ValidateIdentifier)
+			fakeData.POST("/mask", maskController.MaskData)
+		}
+
+		// PII scanner routes
+		piiScan := api.Group("/pii")
+		{
+			piiScan.POST("/scan", piiController.ScanText)
+			piiScan.POST("/scan/file", piiController.ScanFile)
+		}
+
+		// Disposable inbox routes
+		inboxes := api.Group("/inboxes", middleware.RequireAuth())
+		{
+			inboxes.GET("/:id", inboxController.GetInbox)
+			inboxes.DELETE("/:id", inboxController.DeleteInbox)
+			inboxes.GET("/:id/messages", inboxController.ListMessages)
+			inboxes.GET("/:id/messages/:messageId", inboxController.GetMessage)
+		}
+
+		// Built-in SMTP sink routes
+		if smtpSink != nil {
+			sinkController := controllers.NewSinkController(smtpSink)
+			sink := api.Group("/sink/:alias", middleware.RequireAuth())
+			{
+				sink.GET("/messages", sinkController.ListMessages)
+				sink.GET("/messages/:id", sinkController.GetMessage)
+				sink.DELETE("/messages/:id", sinkController.DeleteMessage)
+				sink.GET("/messages/:id/attachments/:attachmentId", sinkController.GetAttachment)
+				sink.GET("/stream", sinkController.StreamMessages)
+			}
+		}
+
+		// Masked email alias routes
+		if maskedEmail != nil {
+			aliasController := controllers.NewAliasController(client, maskedEmail)
+			api.GET("/alias-verification", aliasController.VerifyForwarding)
+			aliases := api.Group("/aliases", middleware.RequireAuth())
+			{
+				aliases.GET("/", aliasController.ListAliases)
+				aliases.POST("/", aliasController.CreateAlias)
+				aliases.GET("/:id", aliasController.GetAlias)
+				aliases.PATCH("/:id", aliasController.UpdateAlias)
+				aliases.DELETE("/:id", aliasController.DeleteAlias)
+				aliases.POST("/:id/verify", aliasController.ResendVerification)
+			}
 		}
 
 		// Breach check routes
 		breachCheck := api.Group("/breach-check")
 		{
-			breachCheck.POST("/email", breachCheckController.CheckEmail)
-			breachCheck.POST("/password", breachCheckController.CheckPassword)
+			breachCheck.POST("/email", rateLimit("breach_check_email", breachCheckLimits), breachCheckController.CheckEmail)
+			breachCheck.POST("/password", rateLimit("breach_check_password", breachCheckLimits), breachCheckController.CheckPassword)
+			breachCheck.POST("/phone", rateLimit("breach_check_phone", breachCheckLimits), breachCheckController.CheckPhone)
+			breachCheck.POST("/username", rateLimit("breach_check_username", breachCheckLimits), breachCheckController.CheckUsername)
+		}
+
+		// Password analysis routes
+		password := api.Group("/password")
+		{
+			password.POST("/strength", passwordController.CheckStrength)
+			password.POST("/generate", passwordController.GeneratePassword)
+			password.POST("/passphrase", passwordController.GeneratePassphrase)
 		}
 
 		// Vault routes
@@ -105,7 +260,53 @@ func main() {
 		// Risk analysis routes
 		risk := api.Group("/risk")
 		{
-			risk.POST("/analyze", riskController.AnalyzeRisk)
+			risk.POST("/analyze", rateLimit("risk_analyze", breachCheckLimits), riskController.AnalyzeRisk)
+			risk.GET("/policy", riskController.GetPolicy)
+			risk.GET("/history", middleware.RequireAuth(), riskController.GetHistory)
+			risk.GET("/history/:id", middleware.RequireAuth(), riskController.GetAssessment)
+			risk.GET("/digests", middleware.RequireAuth(), riskController.ListDigests)
+		}
+
+		// Breach check history routes
+		history := api.Group("/history", middleware.RequireAuth())
+		{
+			history.GET("/", historyController.ListHistory)
+			history.POST("/search", historyController.SearchHistory)
+			history.GET("/diff", historyController.DiffChecks)
+		}
+
+		// Breach monitoring routes
+		monitor := api.Group("/monitor", middleware.RequireAuth())
+		{
+			monitor.GET("/identities", monitorController.ListIdentities)
+			monitor.POST("/identities", monitorController.AddIdentity)
+			monitor.DELETE("/identities/:id", monitorController.DeleteIdentity)
+			monitor.GET("/alerts", monitorController.ListAlerts)
+			monitor.POST("/alerts/:id/acknowledge", monitorController.AcknowledgeAlert)
+		}
+
+		// Alert notification routes
+		api.GET("/notification-verification", notificationController.VerifyChannel)
+		notifications := api.Group("/notifications", middleware.RequireAuth())
+		{
+			notifications.GET("/channels", notificationController.ListChannels)
+			notifications.POST("/channels", notificationController.CreateChannel)
+			notifications.PUT("/channels/:id", notificationController.UpdateChannel)
+			notifications.DELETE("/channels/:id", notificationController.DeleteChannel)
+			notifications.POST("/channels/:id/test", notificationController.TestChannel)
+			notifications.POST("/channels/:id/verify", notificationController.ResendVerification)
+			notifications.GET("/dead-letters", notificationController.ListDeadLetters)
+			notifications.POST("/dead-letters/:id/retry", notificationController.RetryDeadLetter)
+		}
+
+		// Organization domain routes
+		domains := api.Group("/domains", middleware.RequireAuth())
+		{
+			domains.GET("/", domainController.ListDomains)
+			domains.POST("/", domainController.ClaimDomain)
+			domains.POST("/:id/verify", domainController.VerifyDomain)
+			domains.DELETE("/:id", domainController.DeleteDomain)
+			domains.GET("/:id/breaches", domainController.GetDomainBreaches)
 		}
 	}
 
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// userIDKey is the gin context key holding the authenticated user's ID
+const userIDKey = "userId"
+
+// Auth reads a bearer token from the Authorization header and, if it is a
+// valid JWT signed with JWT_SECRET, stores the user ID from its claims on the
+// context. Requests without a token are passed through unauthenticated.
+func Auth() gin.HandlerFunc {
+	secret := []byte(os.Getenv("JWT_SECRET"))
+
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") || len(secret) == 0 {
+			ctx.Next()
+			return
+		}
+
+		tokenString := strings.TrimPrefix(header, "Bearer ")
+		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, jwt.ErrSignatureInvalid
+			}
+			return secret, nil
+		})
+		if err != nil || !token.Valid {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if sub, ok := claims["sub"].(string); ok && sub != "" {
+				ctx.Set(userIDKey, sub)
+			} else if uid, ok := claims["user_id"].(string); ok && uid != "" {
+				ctx.Set(userIDKey, uid)
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireAuth rejects requests that were not authenticated by Auth
+func RequireAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if UserID(ctx) == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// UserID returns the authenticated user's ID, or an empty string
+func UserID(ctx *gin.Context) string {
+	return ctx.GetString(userIDKey)
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddhantgureja/safetrace/services"
+)
+
+// RateLimit limits requests to a route with token buckets: one per client IP
+// for every request and, for authenticated requests, one per user as well,
+// so neither signing in nor switching accounts escapes the IP limit. name
+// keeps each route's buckets apart. Every response carries RateLimit-*
+// headers for whichever bucket is closer to empty, and limited requests get
+// a 429 with Retry-After. Requests are let through if the store fails, so an
+// outage does not take the route down.
+func RateLimit(store services.RateLimitStore, name string, limits services.RouteLimits) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		policy := limits.IP
+		decision, err := store.Take(ctx.Request.Context(), name+":ip:"+ctx.ClientIP(), policy)
+		if err != nil {
+			log.Printf("Rate limit: %s: %v", name, err)
+			ctx.Next()
+			return
+		}
+
+		// A request the IP bucket refuses does not spend the user's tokens
+		if userID := UserID(ctx); userID != "" && decision.Allowed {
+			userDecision, err := store.Take(ctx.Request.Context(), name+":user:"+userID, limits.User)
+			if err != nil {
+				log.Printf("Rate limit: %s: %v", name, err)
+				ctx.Next()
+				return
+			}
+			if !userDecision.Allowed || userDecision.Remaining < decision.Remaining {
+				policy, decision = limits.User, userDecision
+			}
+		}
+
+		ctx.Header("RateLimit-Policy", strconv.Itoa(policy.Burst)+";w="+ceilSeconds(policy.Window()))
+		ctx.Header("RateLimit-Limit", strconv.Itoa(policy.Burst))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		ctx.Header("RateLimit-Reset", ceilSeconds(decision.Reset))
+
+		if !decision.Allowed {
+			ctx.Header("Retry-After", ceilSeconds(decision.RetryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ceilSeconds formats a duration as whole seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddhantgureja/safetrace/services"
+)
+
+func TestRateLimitTakesFromIPAndUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limits := services.RouteLimits{
+		User: services.RateLimitPolicy{Rate: 1.0 / 3600, Burst: 3},
+		IP:   services.RateLimitPolicy{Rate: 1.0 / 3600, Burst: 5},
+	}
+
+	type request struct {
+		user string
+		ip   string
+		want int
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{"anonymous uses the IP bucket", []request{
+			{"", "192.0.2.1", 200}, {"", "192.0.2.1", 200}, {"", "192.0.2.1", 200},
+			{"", "192.0.2.1", 200}, {"", "192.0.2.1", 200}, {"", "192.0.2.1", 429},
+		}},
+		{"signed-in user is held to the user bucket", []request{
+			{"alice", "192.0.2.1", 200}, {"alice", "192.0.2.1", 200}, {"alice", "192.0.2.1", 200},
+			{"alice", "192.0.2.2", 429},
+		}},
+		{"switching accounts does not escape the IP bucket", []request{
+			{"alice", "192.0.2.1", 200}, {"bob", "192.0.2.1", 200}, {"carol", "192.0.2.1", 200},
+			{"dave", "192.0.2.1", 200}, {"erin", "192.0.2.1", 200}, {"frank", "192.0.2.1", 429},
+		}},
+		{"signing in does not escape the IP bucket", []request{
+			{"", "192.0.2.1", 200}, {"", "192.0.2.1", 200}, {"", "192.0.2.1", 200},
+			{"", "192.0.2.1", 200}, {"", "192.0.2.1", 200}, {"alice", "192.0.2.1", 429},
+			{"alice", "192.0.2.2", 200},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(ctx *gin.Context) {
+				if user := ctx.GetHeader("X-Test-User"); user != "" {
+					ctx.Set(userIDKey, user)
+				}
+			})
+			router.GET("/", RateLimit(services.NewMemoryRateLimitStore(), "test", limits), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = r.ip + ":1234"
+				req.Header.Set("X-Test-User", r.user)
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				if recorder.Code != r.want {
+					t.Fatalf("request %d (user %q, ip %s) = %d, want %d", i, r.user, r.ip, recorder.Code, r.want)
+				}
+			}
+		})
+	}
+}
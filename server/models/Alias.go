@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaskedAlias represents a permanent email alias that forwards to a user's
+// real address, usually created for a single site. Mail is only forwarded
+// once the owner of the address has confirmed it from a verification link.
+type MaskedAlias struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID          string              `bson:"userId" json:"userId"`
+	Address         string              `bson:"address" json:"address"`
+	ForwardTo       string              `bson:"forwardTo" json:"forwardTo"`
+	ForwardVerified bool                `bson:"forwardVerified" json:"forwardVerified"`
+	VerifyTokenHash string              `bson:"verifyTokenHash,omitempty" json:"-"`
+	VerifySentAt    *time.Time          `bson:"verifySentAt,omitempty" json:"verifySentAt,omitempty"`
+	Site            string              `bson:"site" json:"site"`
+	Note            string              `bson:"note" json:"note"`
+	VaultItemID     *primitive.ObjectID `bson:"vaultItemId,omitempty" json:"vaultItemId,omitempty"`
+	Status          string              `bson:"status" json:"status"` // active, blocked, disabled
+	Stats           AliasStats          `bson:"stats" json:"stats"`
+	CreatedAt       time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+// AliasStats counts what happened to mail sent to an alias
+type AliasStats struct {
+	Forwarded      int        `bson:"forwarded" json:"forwarded"`
+	Blocked        int        `bson:"blocked" json:"blocked"`   // accepted and dropped while blocked
+	Rejected       int        `bson:"rejected" json:"rejected"` // refused while disabled
+	LastReceivedAt *time.Time `bson:"lastReceivedAt,omitempty" json:"lastReceivedAt,omitempty"`
+	LastSender     string     `bson:"lastSender,omitempty" json:"lastSender,omitempty"`
+}
+
+// AliasRequest represents a request to create or update a masked alias
+type AliasRequest struct {
+	ForwardTo   *string `json:"forwardTo"`
+	Site        *string `json:"site"`
+	Note        *string `json:"note"`
+	VaultItemID *string `json:"vaultItemId"` // empty string unlinks
+	Status      *string `json:"status"`
+}
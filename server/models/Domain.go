@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DomainVerification represents a domain an organization admin has claimed
+// and proves ownership of with a DNS TXT record
+type DomainVerification struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID     string             `bson:"userId" json:"userId"`
+	Domain     string             `bson:"domain" json:"domain"`
+	Token      string             `bson:"token" json:"token"`
+	TXTRecord  string             `bson:"txtRecord" json:"txtRecord"` // value to publish at the domain
+	Verified   bool               `bson:"verified" json:"verified"`
+	VerifiedAt time.Time          `bson:"verifiedAt,omitempty" json:"verifiedAt,omitempty"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// DomainRequest represents a request to claim a domain
+type DomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// DomainBreachRecord represents a breached address on a verified domain
+type DomainBreachRecord struct {
+	Email    string   `json:"email"`
+	Count    int      `json:"count"`
+	Breaches []string `json:"breaches"`
+	Severity string   `json:"severity"`
+}
+
+// DomainBreachResponse represents every breached address found on a domain
+type DomainBreachResponse struct {
+	Domain    string               `json:"domain"`
+	Count     int                  `json:"count"`
+	Source    string               `json:"source"`
+	Addresses []DomainBreachRecord `json:"addresses"`
+}
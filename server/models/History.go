@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BreachCheckRecord represents one breach check made by a user. The checked
+// identity is never stored: emails, phones and usernames are kept as a keyed
+// hash and passwords as the 5 character SHA-1 prefix sent for k-anonymity.
+type BreachCheckRecord struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID       string             `bson:"userId" json:"userId"`
+	Kind         string             `bson:"kind" json:"kind"` // email, password, phone, username
+	IdentityHash string             `bson:"identityHash" json:"identityHash"`
+	Found        bool               `bson:"found" json:"found"`
+	Count        int                `bson:"count" json:"count"`
+	Severity     string             `bson:"severity" json:"severity"`
+	Source       string             `bson:"source" json:"source"`
+	Breaches     []string           `bson:"breaches" json:"breaches"`
+	CheckedAt    time.Time          `bson:"checkedAt" json:"checkedAt"`
+}
+
+// BreachHistoryPage represents one page of a user's breach check history
+type BreachHistoryPage struct {
+	Items []BreachCheckRecord `json:"items"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+	Total int64               `json:"total"`
+}
+
+// BreachHistorySearch represents a request for the checks of one identity
+type BreachHistorySearch struct {
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Region string `json:"region"` // region for phone numbers without a country code, as given when checking
+	Page   int    `json:"page"`
+	Limit  int    `json:"limit"`
+}
+
+// BreachCheckDiff represents how the result for an identity changed between
+// two checks
+type BreachCheckDiff struct {
+	Kind           string            `json:"kind"`
+	IdentityHash   string            `json:"identityHash"`
+	From           BreachCheckRecord `json:"from"`
+	To             BreachCheckRecord `json:"to"`
+	Added          []string          `json:"added"`
+	Removed        []string          `json:"removed"`
+	Unchanged      []string          `json:"unchanged"`
+	CountChange    int               `json:"countChange"`
+	SeverityChange string            `json:"severityChange,omitempty"` // e.g. "Low -> High"
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// DisposableInbox is a real mailbox provisioned on a Mail.tm-compatible
+// service for a fake identity. Its ID is random and only its owner can read it.
+type DisposableInbox struct {
+	ID        string    `bson:"_id" json:"id"`
+	UserID    string    `bson:"userId,omitempty" json:"-"`
+	Address   string    `bson:"address" json:"address"`
+	AccountID string    `bson:"accountId" json:"-"`
+	Password  string    `bson:"password" json:"-"` // encrypted
+	Token     string    `bson:"token" json:"-"`    // encrypted
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+// MailAddress is a sender or recipient of a message
+type MailAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name"`
+}
+
+// InboxMessageSummary is a message as listed in an inbox
+type InboxMessageSummary struct {
+	ID             string        `json:"id"`
+	From           MailAddress   `json:"from"`
+	To             []MailAddress `json:"to"`
+	Subject        string        `json:"subject"`
+	Intro          string        `json:"intro"`
+	Seen           bool          `json:"seen"`
+	HasAttachments bool          `json:"hasAttachments"`
+	Size           int           `json:"size"`
+	CreatedAt      time.Time     `json:"createdAt"`
+}
+
+// InboxMessage is a complete message with its bodies
+type InboxMessage struct {
+	InboxMessageSummary
+	CC          []MailAddress     `json:"cc"`
+	Text        string            `json:"text"`
+	HTML        []string          `json:"html"`
+	Attachments []InboxAttachment `json:"attachments"`
+}
+
+// InboxAttachment describes a file attached to a message
+type InboxAttachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MonitoredIdentity represents an email, username or phone number that is
+// periodically re-checked for new breaches
+type MonitoredIdentity struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID         string             `bson:"userId" json:"userId"`
+	Kind           string             `bson:"kind" json:"kind"` // email, username, phone
+	Value          string             `bson:"value" json:"value"`
+	KnownBreaches  []string           `bson:"knownBreaches" json:"knownBreaches"`
+	LastCheckedAt  time.Time          `bson:"lastCheckedAt" json:"lastCheckedAt"`
+	NextCheckAt    time.Time          `bson:"nextCheckAt" json:"nextCheckAt"`
+	FailureCount   int                `bson:"failureCount" json:"failureCount"`
+	LastError      string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	LeaseOwner     string             `bson:"leaseOwner,omitempty" json:"-"`
+	LeaseExpiresAt time.Time          `bson:"leaseExpiresAt" json:"-"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt      time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// MonitorRequest represents a request to start monitoring an identity
+type MonitorRequest struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// BreachAlert represents new breaches found for a monitored identity
+type BreachAlert struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID       string             `bson:"userId" json:"userId"`
+	IdentityID   primitive.ObjectID `bson:"identityId" json:"identityId"`
+	Kind         string             `bson:"kind" json:"kind"`
+	Value        string             `bson:"value" json:"value"`
+	NewBreaches  []string           `bson:"newBreaches" json:"newBreaches"`
+	TotalCount   int                `bson:"totalCount" json:"totalCount"`
+	Severity     string             `bson:"severity" json:"severity"`
+	Source       string             `bson:"source" json:"source"`
+	Acknowledged bool               `bson:"acknowledged" json:"acknowledged"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+}
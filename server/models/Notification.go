@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationChannel represents a destination that breach alerts are pushed
+// to. Nothing is sent to an email channel until the owner of the address has
+// confirmed it from a verification link.
+type NotificationChannel struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID          string             `bson:"userId" json:"userId"`
+	Type            string             `bson:"type" json:"type"` // webhook, email
+	Name            string             `bson:"name" json:"name"`
+	URL             string             `bson:"url,omitempty" json:"url,omitempty"`       // webhook only
+	Secret          string             `bson:"secret,omitempty" json:"secret,omitempty"` // webhook only, encrypted at rest
+	Email           string             `bson:"email,omitempty" json:"email,omitempty"`   // email only
+	EmailVerified   bool               `bson:"emailVerified" json:"emailVerified"`       // email only
+	VerifyTokenHash string             `bson:"verifyTokenHash,omitempty" json:"-"`
+	VerifySentAt    *time.Time         `bson:"verifySentAt,omitempty" json:"verifySentAt,omitempty"`
+	MinSeverity     string             `bson:"minSeverity" json:"minSeverity"` // Low, Medium, High
+	Enabled         bool               `bson:"enabled" json:"enabled"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// NotificationChannelUpdate represents a request to update a notification
+// channel. A channel keeps its enabled state when enabled is left out.
+type NotificationChannelUpdate struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Email       string `json:"email"`
+	MinSeverity string `json:"minSeverity"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// DeadLetter represents an alert that could not be delivered to a channel
+type DeadLetter struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    string             `bson:"userId" json:"userId"`
+	ChannelID primitive.ObjectID `bson:"channelId" json:"channelId"`
+	Alert     BreachAlert        `bson:"alert" json:"alert"`
+	Attempts  int                `bson:"attempts" json:"attempts"`
+	LastError string             `bson:"lastError" json:"lastError"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// PIIScanRequest represents text to scan for personal data and secrets
+type PIIScanRequest struct {
+	Text          string   `json:"text"`
+	MinConfidence *float64 `json:"minConfidence"` // 0 to 1, default 0.5
+	Types         []string `json:"types"`         // empty for every type
+	Region        string   `json:"region"`        // for phone numbers without a country code
+}
+
+// PIIFinding represents one piece of personal data or a secret found by a scan
+type PIIFinding struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Start       int      `json:"start"` // byte offsets into the scanned text or file
+	End         int      `json:"end"`
+	Line        int      `json:"line"`
+	Location    string   `json:"location,omitempty"` // CSV row and column or JSON path
+	Preview     string   `json:"preview"`            // the value with most of it hidden
+	Confidence  float64  `json:"confidence"`         // 0 to 1
+	Reasons     []string `json:"reasons"`
+}
+
+// PIIScanResponse represents the result of scanning text or a file
+type PIIScanResponse struct {
+	Format    string         `json:"format"` // text, csv or json
+	Findings  []PIIFinding   `json:"findings"`
+	Counts    map[string]int `json:"counts"`    // findings per type
+	Truncated bool           `json:"truncated"` // more findings than were returned
+	Redacted  string         `json:"redacted"`  // the input with every finding replaced
+}
@@ -0,0 +1,88 @@
+package models
+
+// PasswordStrengthRequest represents a request to estimate password strength
+type PasswordStrengthRequest struct {
+	Password   string   `json:"password"`
+	Name       string   `json:"name"`
+	Email      string   `json:"email"`
+	UserInputs []string `json:"userInputs"` // other personal words to penalize
+}
+
+// PasswordStrengthResponse represents the estimated strength of a password
+type PasswordStrengthResponse struct {
+	Score        int                          `json:"score"` // 0 (weakest) to 4 (strongest)
+	Guesses      float64                      `json:"guesses"`
+	GuessesLog10 float64                      `json:"guessesLog10"`
+	EntropyBits  float64                      `json:"entropyBits"`
+	CrackTimes   map[string]CrackTimeEstimate `json:"crackTimes"`
+	Feedback     PasswordFeedback             `json:"feedback"`
+	Matches      []PasswordMatch              `json:"matches"`
+}
+
+// CrackTimeEstimate represents how long an attacker needs to guess a password
+type CrackTimeEstimate struct {
+	GuessesPerSecond float64 `json:"guessesPerSecond"`
+	Seconds          float64 `json:"seconds"`
+	Display          string  `json:"display"`
+}
+
+// PasswordFeedback represents advice for improving a password
+type PasswordFeedback struct {
+	Warning     string   `json:"warning,omitempty"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// PasswordMatch represents a guessable pattern found in a password
+type PasswordMatch struct {
+	Pattern    string  `json:"pattern"` // dictionary, spatial, repeat, sequence, date, bruteforce
+	Token      string  `json:"token"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	Guesses    float64 `json:"guesses"`
+	Dictionary string  `json:"dictionary,omitempty"`
+	Word       string  `json:"word,omitempty"`
+	Rank       int     `json:"rank,omitempty"`
+	L33t       bool    `json:"l33t,omitempty"`
+	Reversed   bool    `json:"reversed,omitempty"`
+}
+
+// PasswordGenerateRequest represents a request to generate random passwords.
+// Character classes are used unless turned off.
+type PasswordGenerateRequest struct {
+	Length            int                `json:"length"` // default 20
+	Lowercase         *bool              `json:"lowercase"`
+	Uppercase         *bool              `json:"uppercase"`
+	Digits            *bool              `json:"digits"`
+	Symbols           *bool              `json:"symbols"`
+	MinLowercase      int                `json:"minLowercase"`
+	MinUppercase      int                `json:"minUppercase"`
+	MinDigits         int                `json:"minDigits"`
+	MinSymbols        int                `json:"minSymbols"`
+	ExcludeLookAlikes bool               `json:"excludeLookAlikes"` // 0, O, o, 1, l, I and |
+	Exclude           string             `json:"exclude"`           // other characters to leave out
+	Site              *PasswordSiteRules `json:"site"`
+	Count             int                `json:"count"` // default 1
+}
+
+// PasswordSiteRules represents the password rules a site enforces
+type PasswordSiteRules struct {
+	MinLength           int    `json:"minLength"`
+	MaxLength           int    `json:"maxLength"`
+	AllowedSymbols      string `json:"allowedSymbols"` // the only symbols the site accepts
+	ForbiddenCharacters string `json:"forbiddenCharacters"`
+}
+
+// PassphraseGenerateRequest represents a request to generate diceware passphrases
+type PassphraseGenerateRequest struct {
+	Words         int     `json:"words"`     // default 6
+	Separator     *string `json:"separator"` // default "-"
+	Capitalize    bool    `json:"capitalize"`
+	IncludeNumber bool    `json:"includeNumber"` // append a digit to one word
+	Count         int     `json:"count"`         // default 1
+}
+
+// GeneratedPassword represents a generated password or passphrase
+type GeneratedPassword struct {
+	Value       string  `json:"value"`
+	EntropyBits float64 `json:"entropyBits"`
+}
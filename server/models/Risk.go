@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RiskAssessment represents a stored risk analysis of a signed-in user. The
+// inputs are the facts it was scored on, without the email address itself.
+type RiskAssessment struct {
+	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        string                 `bson:"userId" json:"userId"`
+	Score         int                    `bson:"score" json:"score"`
+	RiskLevel     string                 `bson:"riskLevel" json:"riskLevel"`
+	Factors       []string               `bson:"factors" json:"factors"` // rule IDs, returned as factor labels
+	Inputs        map[string]interface{} `bson:"inputs" json:"inputs"`
+	Verified      []string               `bson:"verified" json:"verified"` // inputs verified rather than self-reported
+	PolicyVersion string                 `bson:"policyVersion" json:"policyVersion"`
+	CreatedAt     time.Time              `bson:"createdAt" json:"createdAt"`
+}
+
+// RiskHistoryPoint represents one assessment in a user's risk history and how
+// it changed from the one before
+type RiskHistoryPoint struct {
+	ID            primitive.ObjectID `json:"id"`
+	Score         int                `json:"score"`
+	RiskLevel     string             `json:"riskLevel"`
+	Factors       []string           `json:"factors"`
+	Delta         int                `json:"delta"`    // score change since the previous assessment
+	Appeared      []string           `json:"appeared"` // factors the previous assessment did not have
+	Resolved      []string           `json:"resolved"` // factors of the previous assessment that are gone
+	PolicyVersion string             `json:"policyVersion"`
+	CreatedAt     time.Time          `json:"createdAt"`
+}
+
+// RiskHistoryResponse represents a user's risk scores over time, oldest
+// first, with the latest change summarized
+type RiskHistoryResponse struct {
+	Points   []RiskHistoryPoint `json:"points"`
+	Score    *int               `json:"score"` // latest score, null without assessments
+	Delta    int                `json:"delta"`
+	Appeared []string           `json:"appeared"`
+	Resolved []string           `json:"resolved"`
+}
+
+// RiskDigest represents a weekly summary of how a user's risk changed. Its
+// factors are stored as rule IDs and returned as factor labels.
+type RiskDigest struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID        string             `bson:"userId" json:"userId"`
+	PeriodStart   time.Time          `bson:"periodStart" json:"periodStart"`
+	PeriodEnd     time.Time          `bson:"periodEnd" json:"periodEnd"`
+	Assessments   int                `bson:"assessments" json:"assessments"`     // analyses during the period
+	PreviousScore *int               `bson:"previousScore" json:"previousScore"` // latest score before the period, if any
+	Score         int                `bson:"score" json:"score"`
+	Delta         int                `bson:"delta" json:"delta"`
+	RiskLevel     string             `bson:"riskLevel" json:"riskLevel"`
+	LevelChange   string             `bson:"levelChange,omitempty" json:"levelChange,omitempty"` // e.g. "High -> Medium"
+	Appeared      []string           `bson:"appeared" json:"appeared"`
+	Resolved      []string           `bson:"resolved" json:"resolved"`
+	Factors       []string           `bson:"factors" json:"factors"` // factors at the end of the period
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SinkAlias is an address on the built-in SMTP sink's domain. Only the user
+// who created it can read its mail.
+type SinkAlias struct {
+	Alias     string    `bson:"_id" json:"alias"`
+	UserID    string    `bson:"userId" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+// SinkMessage is an email received by the built-in SMTP sink for one alias
+type SinkMessage struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Alias        string             `bson:"alias" json:"alias"`
+	EnvelopeFrom string             `bson:"envelopeFrom" json:"envelopeFrom"`
+	From         string             `bson:"from" json:"from"`
+	To           []string           `bson:"to" json:"to"`
+	Subject      string             `bson:"subject" json:"subject"`
+	Text         string             `bson:"text,omitempty" json:"text,omitempty"`
+	HTML         string             `bson:"html,omitempty" json:"html,omitempty"`
+	Attachments  []SinkAttachment   `bson:"attachments" json:"attachments"`
+	Size         int                `bson:"size" json:"size"`
+	ReceivedAt   time.Time          `bson:"receivedAt" json:"receivedAt"`
+	ExpiresAt    time.Time          `bson:"expiresAt" json:"expiresAt"`
+}
+
+// SinkAttachment is a file attached to a sink message
+type SinkAttachment struct {
+	ID          string `bson:"id" json:"id"`
+	Filename    string `bson:"filename" json:"filename"`
+	ContentType string `bson:"contentType" json:"contentType"`
+	ContentID   string `bson:"contentId,omitempty" json:"contentId,omitempty"`
+	Inline      bool   `bson:"inline" json:"inline"`
+	Size        int    `bson:"size" json:"size"`
+	Data        []byte `bson:"data,omitempty" json:"-"`
+}
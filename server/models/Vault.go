@@ -46,14 +46,23 @@ type NoteVaultData struct {
 type BreachCheckRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Phone    string `json:"phone"`
+	Region   string `json:"region"` // region for phone numbers without a country code, e.g. "GB"
+	Username string `json:"username"`
 }
 
 // BreachCheckResponse represents a response from the breach check service
 type BreachCheckResponse struct {
-	Found    bool   `json:"found"`
-	Count    int    `json:"count"`
-	Source   string `json:"source,omitempty"`
-	Severity string `json:"severity,omitempty"`
+	Found           bool       `json:"found"`
+	Count           int        `json:"count"`
+	Source          string     `json:"source,omitempty"`
+	Severity        string     `json:"severity,omitempty"`
+	Breaches        []string   `json:"breaches,omitempty"`
+	CheckedEmail    string     `json:"checkedEmail,omitempty"` // canonical form sent upstream
+	CheckedPhone    string     `json:"checkedPhone,omitempty"` // E.164 form sent upstream
+	CheckedUsername string     `json:"checkedUsername,omitempty"`
+	Cached          bool       `json:"cached"`
+	CheckedAt       *time.Time `json:"checkedAt,omitempty"`
 }
 
 // NewsItem represents a news article
@@ -68,30 +77,88 @@ type NewsItem struct {
 
 // RiskAnalysisRequest represents a request for risk analysis
 type RiskAnalysisRequest struct {
-	Email            string   `json:"email"`
+	Email              string   `json:"email"`
 	HasStrongPasswords bool     `json:"hasStrongPasswords"`
-	Uses2FA           bool     `json:"uses2FA"`
-	UsesSocialMedia   bool     `json:"usesSocialMedia"`
-	PublicProfiles    []string `json:"publicProfiles"`
-	HasDataBreaches   bool     `json:"hasDataBreaches"`
-	SharesPersonalInfo bool    `json:"sharesPersonalInfo"`
+	Uses2FA            bool     `json:"uses2FA"`
+	UsesSocialMedia    bool     `json:"usesSocialMedia"`
+	PublicProfiles     []string `json:"publicProfiles"`
+	HasDataBreaches    bool     `json:"hasDataBreaches"`
+	SharesPersonalInfo bool     `json:"sharesPersonalInfo"`
+	Verify             *bool    `json:"verify"` // default true; false scores the answers alone
+	WhatIf             []string `json:"whatIf"` // factor IDs to simulate fixing together
 }
 
-// RiskAnalysisResponse represents a response from the risk analysis service
+// RiskAnalysisResponse represents a response from the risk analysis service.
+// The base score plus every factor's and mitigation's contribution gives the
+// score, before it is capped between 0 and 100.
 type RiskAnalysisResponse struct {
-	Score      int      `json:"score"`
-	RiskLevel  string   `json:"riskLevel"` // Low, Medium, High
-	Factors    []string `json:"factors"`
-	Advice     []string `json:"advice"`
+	Score         int          `json:"score"`
+	RiskLevel     string       `json:"riskLevel"` // named by the policy, by default Low, Medium or High
+	BaseScore     int          `json:"baseScore"`
+	Factors       []RiskFactor `json:"factors"`              // what raises the score, in policy order
+	Mitigations   []RiskFactor `json:"mitigations"`          // what lowers it, such as two-factor authentication
+	Advice        []string     `json:"advice"`               // general advice not tied to a factor
+	WhatIf        []RiskWhatIf `json:"whatIf"`               // each factor fixed on its own, most improvement first
+	Simulation    *RiskWhatIf  `json:"simulation,omitempty"` // the request's whatIf factors fixed together
+	PolicyVersion string       `json:"policyVersion"`        // version of the scoring policy used
+}
+
+// RiskFactor represents something that changed a risk score, how much it did
+// and how to address it
+type RiskFactor struct {
+	ID           string   `json:"id"` // the policy rule that found it
+	Factor       string   `json:"factor"`
+	Category     string   `json:"category,omitempty"`
+	Severity     string   `json:"severity,omitempty"` // Low, Medium or High; mitigations have none
+	Contribution int      `json:"contribution"`       // points added to the score, negative for mitigations
+	Source       string   `json:"source"`             // verified or self-reported
+	Evidence     []string `json:"evidence,omitempty"` // what verified it, such as a breach lookup
+	Advice       string   `json:"advice,omitempty"`
+	Remediation  []string `json:"remediation,omitempty"` // steps that address it
+	Fixable      bool     `json:"fixable"`               // whether it can be simulated fixed in whatIf
+}
+
+// RiskWhatIf represents the score a user would have with some factors fixed
+type RiskWhatIf struct {
+	FactorIDs   []string `json:"factorIds"`
+	Score       int      `json:"score"`
+	RiskLevel   string   `json:"riskLevel"`
+	Improvement int      `json:"improvement"` // points the score would drop by, before capping at 0 and 100
 }
 
 // FakeDataResponse represents generated fake data
 type FakeDataResponse struct {
-	Name       string `json:"name"`
-	Email      string `json:"email"`
-	Address    string `json:"address"`
-	Phone      string `json:"phone"`
-	CreditCard string `json:"creditCard"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-} 
\ No newline at end of file
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Address     string `json:"address"`
+	Phone       string `json:"phone"`
+	CreditCard  string `json:"creditCard"`
+	CardNetwork string `json:"cardNetwork"`
+	CardExpiry  string `json:"cardExpiry"`
+	CardCVV     string `json:"cardCvv"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	IBAN        string `json:"iban"`       // empty for countries without IBANs
+	NationalID  string `json:"nationalId"` // empty for locales without a supported kind
+	Passport    string `json:"passport"`
+	// Set when the email address is a real disposable inbox
+	InboxID        string     `json:"inboxId,omitempty"`
+	InboxExpiresAt *time.Time `json:"inboxExpiresAt,omitempty"`
+}
+
+// IdentifierValidation represents the result of checking a document number
+type IdentifierValidation struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Normalized string `json:"normalized"`
+	Country    string `json:"country,omitempty"`
+	Valid      bool   `json:"valid"`
+	Reason     string `json:"reason,omitempty"` // why the value is invalid
+	Note       string `json:"note,omitempty"`
+}
+
+// IdentifierValidationRequest represents a request to validate a document number
+type IdentifierValidationRequest struct {
+	Value   string `json:"value"`
+	Country string `json:"country"` // passports only
+}
@@ -0,0 +1,109 @@
+package passgen
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// Passphrase length limits, in words
+const (
+	DefaultWords = 6
+	MinWords     = 3
+	MaxWords     = 20
+)
+
+// dicewareWords is the number of words in a list indexed by five dice rolls
+const dicewareWords = 7776
+
+// The EFF large word list, one "<rolls>\t<word>" per line, unchanged from
+// https://www.eff.org/files/2016/07/18/eff_large_wordlist.txt. It is licensed
+// under CC BY 3.0 US; see wordlists/README.md.
+//
+//go:embed wordlists/eff_large_wordlist.txt
+var dicewareFile string
+
+var (
+	wordsOnce sync.Once
+	words     []string
+)
+
+// loadWords parses the embedded word list on first use
+func loadWords() []string {
+	wordsOnce.Do(func() {
+		seen := map[string]bool{}
+		scanner := bufio.NewScanner(strings.NewReader(dicewareFile))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) != 2 || seen[fields[1]] {
+				panic("passgen: corrupt word list line " + strconv.Quote(line))
+			}
+			seen[fields[1]] = true
+			words = append(words, fields[1])
+		}
+		if len(words) != dicewareWords {
+			panic("passgen: word list must have " + strconv.Itoa(dicewareWords) + " words")
+		}
+	})
+	return words
+}
+
+// PassphrasePolicy describes the passphrases to generate
+type PassphrasePolicy struct {
+	Words         int // 0 picks DefaultWords
+	Separator     string
+	Capitalize    bool
+	IncludeNumber bool // append a random digit to a random word
+}
+
+// Passphrase generates a passphrase of words picked uniformly from the word
+// list, the equivalent of rolling five dice per word
+func Passphrase(policy PassphrasePolicy) (models.GeneratedPassword, error) {
+	count := policy.Words
+	if count == 0 {
+		count = DefaultWords
+	}
+	if count < MinWords || count > MaxWords {
+		return models.GeneratedPassword{}, errors.New("words must be between " + strconv.Itoa(MinWords) + " and " + strconv.Itoa(MaxWords))
+	}
+	// Letters in the separator could run into the words and make the
+	// passphrase ambiguous
+	if len(policy.Separator) > 3 || strings.IndexFunc(policy.Separator, unicode.IsLetter) >= 0 {
+		return models.GeneratedPassword{}, errors.New("separator must be at most 3 characters and contain no letters")
+	}
+	if policy.Separator == "" && !policy.Capitalize {
+		return models.GeneratedPassword{}, errors.New("an empty separator requires capitalize, so the words stay apart")
+	}
+
+	list := loadWords()
+	picked := make([]string, count)
+	for i := range picked {
+		picked[i] = list[randomInt(len(list))]
+		if policy.Capitalize {
+			picked[i] = strings.ToUpper(picked[i][:1]) + picked[i][1:]
+		}
+	}
+	bits := float64(count) * math.Log2(float64(len(list)))
+
+	if policy.IncludeNumber {
+		i := randomInt(count)
+		picked[i] += strconv.Itoa(randomInt(10))
+		bits += math.Log2(float64(count * 10))
+	}
+
+	return models.GeneratedPassword{
+		Value:       strings.Join(picked, policy.Separator),
+		EntropyBits: roundBits(bits),
+	}, nil
+}
@@ -0,0 +1,109 @@
+package passgen
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestWordList(t *testing.T) {
+	list := loadWords()
+	if len(list) != 7776 {
+		t.Fatalf("word list has %d words, want 7776", len(list))
+	}
+	// The first and last entries of the EFF large word list
+	if list[0] != "abacus" || list[len(list)-1] != "zoom" {
+		t.Errorf("word list runs from %q to %q, want abacus to zoom", list[0], list[len(list)-1])
+	}
+	for _, word := range list {
+		if strings.IndexFunc(word, func(r rune) bool { return r != '-' && !unicode.IsLower(r) }) >= 0 {
+			t.Errorf("word %q is not lowercase", word)
+		}
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	inList := map[string]bool{}
+	for _, word := range loadWords() {
+		inList[word] = true
+	}
+	perWord := math.Log2(7776)
+
+	tests := []struct {
+		name   string
+		policy PassphrasePolicy
+		words  int
+		bits   float64
+	}{
+		{"default", PassphrasePolicy{Separator: " "}, DefaultWords, DefaultWords * perWord},
+		{"fewest words", PassphrasePolicy{Words: MinWords, Separator: " "}, MinWords, MinWords * perWord},
+		{"most words", PassphrasePolicy{Words: MaxWords, Separator: "."}, MaxWords, MaxWords * perWord},
+		{"capitalized", PassphrasePolicy{Words: 5, Separator: ".", Capitalize: true}, 5, 5 * perWord},
+		// The digit goes after one of 4 words and is one of 10
+		{"with a number", PassphrasePolicy{Words: 4, Separator: "_", IncludeNumber: true}, 4, 4*perWord + math.Log2(40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Passphrase(tt.policy)
+			if err != nil {
+				t.Fatalf("Passphrase: %v", err)
+			}
+			if want := roundBits(tt.bits); result.EntropyBits != want {
+				t.Errorf("entropy = %v bits, want %v", result.EntropyBits, want)
+			}
+
+			words := strings.Split(result.Value, tt.policy.Separator)
+			if len(words) != tt.words {
+				t.Fatalf("%q has %d words, want %d", result.Value, len(words), tt.words)
+			}
+			numbers := 0
+			for _, word := range words {
+				if trimmed := strings.TrimRight(word, Digits); trimmed != word {
+					numbers++
+					word = trimmed
+				}
+				if tt.policy.Capitalize {
+					if !unicode.IsUpper(rune(word[0])) {
+						t.Errorf("%q in %q is not capitalized", word, result.Value)
+					}
+					word = strings.ToLower(word[:1]) + word[1:]
+				}
+				if !inList[word] {
+					t.Errorf("%q in %q is not in the word list", word, result.Value)
+				}
+			}
+			want := 0
+			if tt.policy.IncludeNumber {
+				want = 1
+			}
+			if numbers != want {
+				t.Errorf("%q has %d numbers, want %d", result.Value, numbers, want)
+			}
+		})
+	}
+}
+
+func TestPassphraseRejectsInvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy PassphrasePolicy
+		want   string
+	}{
+		{"too few words", PassphrasePolicy{Words: MinWords - 1, Separator: "-"}, "words must be between"},
+		{"too many words", PassphrasePolicy{Words: MaxWords + 1, Separator: "-"}, "words must be between"},
+		{"letters in the separator", PassphrasePolicy{Separator: "x"}, "contain no letters"},
+		{"long separator", PassphrasePolicy{Separator: "----"}, "at most 3 characters"},
+		{"words run together", PassphrasePolicy{}, "empty separator requires capitalize"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Passphrase(tt.policy)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
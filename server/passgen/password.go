@@ -0,0 +1,246 @@
+// Package passgen generates passwords and passphrases from a cryptographically
+// secure random source and reports how much entropy each one has
+package passgen
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// Password length limits
+const (
+	DefaultLength = 20
+	MinLength     = 4
+	MaxLength     = 128
+)
+
+// Character sets
+const (
+	Lowercase = "abcdefghijklmnopqrstuvwxyz"
+	Uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits    = "0123456789"
+	// Symbols leaves out quotes, backslashes, spaces and angle brackets,
+	// which sites most often reject or mangle
+	Symbols = "!#$%&()*+,-./:;=?@[]^_{}~"
+	// LookAlikes are easily confused when a password is read or typed
+	LookAlikes = "0Oo1lI|"
+)
+
+// Policy describes the passwords to generate
+type Policy struct {
+	Length            int // 0 picks DefaultLength, within the site's limits
+	Lowercase         bool
+	Uppercase         bool
+	Digits            bool
+	Symbols           bool
+	MinLowercase      int
+	MinUppercase      int
+	MinDigits         int
+	MinSymbols        int
+	ExcludeLookAlikes bool
+	Exclude           string
+	Site              models.PasswordSiteRules
+}
+
+// charClass is a set of characters with a minimum number to use
+type charClass struct {
+	name  string
+	chars []rune
+	min   int
+}
+
+// Password generates a password satisfying the policy. Every such password
+// is equally likely, so the entropy is exactly log2 of how many there are.
+func Password(policy Policy) (models.GeneratedPassword, error) {
+	length, classes, err := policy.resolve()
+	if err != nil {
+		return models.GeneratedPassword{}, err
+	}
+	ways := countArrangements(length, classes)
+
+	// Pick how many characters come from each class in proportion to the
+	// passwords with that mix, then the characters, then their order
+	password := make([]rune, 0, length)
+	remaining := length
+	for i, class := range classes {
+		target := randomBig(ways[i][remaining])
+		n := class.min
+		for ; n < remaining; n++ {
+			weight := arrangementWeight(remaining, n, len(class.chars), ways[i+1][remaining-n])
+			if target.Cmp(weight) < 0 {
+				break
+			}
+			target.Sub(target, weight)
+		}
+		for j := 0; j < n; j++ {
+			password = append(password, class.chars[randomInt(len(class.chars))])
+		}
+		remaining -= n
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		j := randomInt(i + 1)
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return models.GeneratedPassword{
+		Value:       string(password),
+		EntropyBits: roundBits(log2Big(ways[0][length])),
+	}, nil
+}
+
+// resolve applies the site's rules and exclusions to the policy, returning
+// the length and the character classes to draw from
+func (p Policy) resolve() (int, []charClass, error) {
+	minLength, maxLength := MinLength, MaxLength
+	if p.Site.MinLength > minLength {
+		minLength = p.Site.MinLength
+	}
+	if p.Site.MaxLength > 0 && p.Site.MaxLength < maxLength {
+		maxLength = p.Site.MaxLength
+	}
+	if minLength > maxLength {
+		return 0, nil, errors.New("the site's minLength is greater than its maxLength")
+	}
+
+	length := p.Length
+	if length == 0 {
+		length = DefaultLength
+		if length > maxLength {
+			length = maxLength
+		}
+		if length < minLength {
+			length = minLength
+		}
+	}
+	if length < minLength || length > maxLength {
+		return 0, nil, fmt.Errorf("length must be between %d and %d", minLength, maxLength)
+	}
+
+	exclude := p.Exclude + p.Site.ForbiddenCharacters
+	if p.ExcludeLookAlikes {
+		exclude += LookAlikes
+	}
+	symbols := Symbols
+	if p.Site.AllowedSymbols != "" {
+		symbols = p.Site.AllowedSymbols
+	}
+
+	candidates := []struct {
+		name    string
+		enabled bool
+		chars   string
+		min     int
+	}{
+		{"lowercase", p.Lowercase, Lowercase, p.MinLowercase},
+		{"uppercase", p.Uppercase, Uppercase, p.MinUppercase},
+		{"digits", p.Digits, Digits, p.MinDigits},
+		{"symbols", p.Symbols, symbols, p.MinSymbols},
+	}
+
+	var classes []charClass
+	minTotal := 0
+	seen := map[rune]bool{}
+	for _, candidate := range candidates {
+		if candidate.min < 0 {
+			return 0, nil, fmt.Errorf("the minimum number of %s cannot be negative", candidate.name)
+		}
+		if !candidate.enabled {
+			if candidate.min > 0 {
+				return 0, nil, fmt.Errorf("a minimum number of %s requires %s to be enabled", candidate.name, candidate.name)
+			}
+			continue
+		}
+
+		var chars []rune
+		for _, r := range candidate.chars {
+			if r > 0x7e || r <= ' ' || strings.ContainsRune(exclude, r) || seen[r] {
+				continue
+			}
+			seen[r] = true
+			chars = append(chars, r)
+		}
+		if len(chars) == 0 {
+			if candidate.min > 0 {
+				return 0, nil, fmt.Errorf("every character of %s is excluded", candidate.name)
+			}
+			continue
+		}
+
+		classes = append(classes, charClass{name: candidate.name, chars: chars, min: candidate.min})
+		minTotal += candidate.min
+	}
+
+	if len(classes) == 0 {
+		return 0, nil, errors.New("at least one character class must be enabled")
+	}
+	if minTotal > length {
+		return 0, nil, fmt.Errorf("the minimum counts add up to %d, more than the length of %d", minTotal, length)
+	}
+	return length, classes, nil
+}
+
+// countArrangements returns ways, where ways[i][r] is the number of strings
+// of length r made from classes i onwards that meet their minimums
+func countArrangements(length int, classes []charClass) [][]*big.Int {
+	ways := make([][]*big.Int, len(classes)+1)
+	ways[len(classes)] = make([]*big.Int, length+1)
+	for r := range ways[len(classes)] {
+		ways[len(classes)][r] = big.NewInt(0)
+	}
+	ways[len(classes)][0].SetInt64(1)
+
+	for i := len(classes) - 1; i >= 0; i-- {
+		ways[i] = make([]*big.Int, length+1)
+		for r := 0; r <= length; r++ {
+			total := big.NewInt(0)
+			for n := classes[i].min; n <= r; n++ {
+				total.Add(total, arrangementWeight(r, n, len(classes[i].chars), ways[i+1][r-n]))
+			}
+			ways[i][r] = total
+		}
+	}
+	return ways
+}
+
+// arrangementWeight counts the strings of length r with exactly n characters
+// from a class of size chars, the rest being filled in one of rest ways
+func arrangementWeight(r, n, chars int, rest *big.Int) *big.Int {
+	weight := new(big.Int).Binomial(int64(r), int64(n))
+	weight.Mul(weight, new(big.Int).Exp(big.NewInt(int64(chars)), big.NewInt(int64(n)), nil))
+	return weight.Mul(weight, rest)
+}
+
+// randomBig returns a uniform random integer in [0, max)
+func randomBig(max *big.Int) *big.Int {
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		// The system's random source failing is not recoverable
+		panic("passgen: " + err.Error())
+	}
+	return n
+}
+
+// randomInt returns a uniform random integer in [0, max)
+func randomInt(max int) int {
+	return int(randomBig(big.NewInt(int64(max))).Int64())
+}
+
+// log2Big returns log2(x) for x > 0
+func log2Big(x *big.Int) float64 {
+	shift := x.BitLen() - 64
+	if shift <= 0 {
+		return math.Log2(float64(x.Uint64()))
+	}
+	top := new(big.Int).Rsh(x, uint(shift))
+	return math.Log2(float64(top.Uint64())) + float64(shift)
+}
+
+func roundBits(bits float64) float64 {
+	return math.Round(bits*100) / 100
+}
@@ -0,0 +1,149 @@
+package passgen
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// countIn returns how many runes of s are in chars
+func countIn(s, chars string) int {
+	n := 0
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestPasswordMeetsPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		length  int
+		allowed string
+	}{
+		{"default length", Policy{Lowercase: true, Uppercase: true, Digits: true, Symbols: true},
+			DefaultLength, Lowercase + Uppercase + Digits + Symbols},
+		{"minimum counts", Policy{Length: 8, Lowercase: true, Uppercase: true, Digits: true, Symbols: true,
+			MinLowercase: 2, MinUppercase: 2, MinDigits: 2, MinSymbols: 2},
+			8, Lowercase + Uppercase + Digits + Symbols},
+		{"minimums filling the length", Policy{Length: 4, Digits: true, Symbols: true, MinDigits: 3, MinSymbols: 1},
+			4, Digits + Symbols},
+		{"look-alikes excluded", Policy{Length: 64, Lowercase: true, Uppercase: true, Digits: true, ExcludeLookAlikes: true},
+			64, "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"},
+		{"characters excluded", Policy{Length: 32, Digits: true, Exclude: "13579"},
+			32, "02468"},
+		{"site maximum length", Policy{Lowercase: true, Site: models.PasswordSiteRules{MaxLength: 12}},
+			12, Lowercase},
+		{"site minimum length", Policy{Lowercase: true, Site: models.PasswordSiteRules{MinLength: 30}},
+			30, Lowercase},
+		{"site allowed symbols", Policy{Length: 40, Symbols: true, Site: models.PasswordSiteRules{AllowedSymbols: "!@#"}},
+			40, "!@#"},
+		{"site forbidden characters", Policy{Length: 40, Symbols: true, Digits: true, MinSymbols: 5,
+			Site: models.PasswordSiteRules{ForbiddenCharacters: "!#$%&()*+,-./:;=?[]^_{}0123456789"}},
+			40, "@~"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Several draws, since a single one can meet the policy by chance
+			for i := 0; i < 50; i++ {
+				result, err := Password(tt.policy)
+				if err != nil {
+					t.Fatalf("Password: %v", err)
+				}
+				value := result.Value
+				if n := len([]rune(value)); n != tt.length {
+					t.Fatalf("%q has length %d, want %d", value, n, tt.length)
+				}
+				if n := countIn(value, tt.allowed); n != len(value) {
+					t.Fatalf("%q has characters outside %q", value, tt.allowed)
+				}
+				for _, class := range []struct {
+					chars string
+					min   int
+				}{
+					{Lowercase, tt.policy.MinLowercase},
+					{Uppercase, tt.policy.MinUppercase},
+					{Digits, tt.policy.MinDigits},
+					{Symbols + tt.policy.Site.AllowedSymbols, tt.policy.MinSymbols},
+				} {
+					if n := countIn(value, class.chars); n < class.min {
+						t.Fatalf("%q has %d of %q, want at least %d", value, n, class.chars, class.min)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordRejectsImpossiblePolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   string
+	}{
+		{"no classes", Policy{Length: 8}, "at least one character class"},
+		{"minimums over the length", Policy{Length: 4, Lowercase: true, Digits: true, MinLowercase: 3, MinDigits: 2},
+			"add up to 5, more than the length of 4"},
+		{"minimum of a disabled class", Policy{Lowercase: true, MinDigits: 1}, "requires digits to be enabled"},
+		{"negative minimum", Policy{Lowercase: true, MinLowercase: -1}, "cannot be negative"},
+		{"length too short", Policy{Length: 3, Lowercase: true}, "length must be between 4 and 128"},
+		{"length over the site maximum", Policy{Length: 20, Lowercase: true, Site: models.PasswordSiteRules{MaxLength: 16}},
+			"length must be between 4 and 16"},
+		{"site limits crossed", Policy{Lowercase: true, Site: models.PasswordSiteRules{MinLength: 20, MaxLength: 10}},
+			"minLength is greater than its maxLength"},
+		{"every required character forbidden", Policy{Lowercase: true, Digits: true, MinDigits: 1,
+			Site: models.PasswordSiteRules{ForbiddenCharacters: Digits}}, "every character of digits is excluded"},
+		{"every character excluded", Policy{Digits: true, Exclude: Digits}, "at least one character class"},
+		{"only look-alikes allowed", Policy{Symbols: true, ExcludeLookAlikes: true, Site: models.PasswordSiteRules{AllowedSymbols: "|"}},
+			"at least one character class"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Password(tt.policy)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordEntropy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		bits   float64
+	}{
+		{"digits", Policy{Length: 4, Digits: true}, 4 * math.Log2(10)},
+		{"lowercase", Policy{Length: 20, Lowercase: true}, 20 * math.Log2(26)},
+		{"all classes", Policy{Length: 16, Lowercase: true, Uppercase: true, Digits: true, Symbols: true},
+			16 * math.Log2(26+26+10+float64(len(Symbols)))},
+		// Two letters and two digits in any of C(4,2) orders
+		{"minimums filling the length", Policy{Length: 4, Lowercase: true, Digits: true, MinLowercase: 2, MinDigits: 2},
+			math.Log2(6 * 100 * 676)},
+		// Three digits and a letter in any of 4 orders, or four digits
+		{"minimum below the length", Policy{Length: 4, Lowercase: true, Digits: true, MinDigits: 3},
+			math.Log2(4*1000*26 + 10000)},
+		{"look-alikes excluded", Policy{Length: 10, Digits: true, ExcludeLookAlikes: true}, 10 * math.Log2(8)},
+		// Large enough to need more than 64 bits
+		{"maximum length", Policy{Length: MaxLength, Lowercase: true, Uppercase: true}, MaxLength * math.Log2(52)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Password(tt.policy)
+			if err != nil {
+				t.Fatalf("Password: %v", err)
+			}
+			if want := roundBits(tt.bits); result.EntropyBits != want {
+				t.Errorf("entropy = %v bits, want %v", result.EntropyBits, want)
+			}
+		})
+	}
+}
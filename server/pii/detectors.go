@@ -0,0 +1,341 @@
+package pii
+
+import (
+	"math"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/siddhantgureja/safetrace/faker"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// Finding types
+const (
+	TypeEmail      = "email"
+	TypePhone      = "phone"
+	TypeCreditCard = "creditCard"
+	TypeIBAN       = "iban"
+	TypeSSN        = "ssn"
+	TypeNINO       = "nino"
+	TypePAN        = "pan"
+	TypeAadhaar    = "aadhaar"
+	TypeAPIKey     = "apiKey"
+	TypePrivateKey = "privateKey"
+)
+
+// Types are every type of finding a scan reports
+var Types = []string{
+	TypeEmail, TypePhone, TypeCreditCard, TypeIBAN, TypeSSN, TypeNINO, TypePAN,
+	TypeAadhaar, TypeAPIKey, TypePrivateKey,
+}
+
+// labels replace each type of finding in redacted output
+var labels = map[string]string{
+	TypeEmail:      "[EMAIL]",
+	TypePhone:      "[PHONE]",
+	TypeCreditCard: "[CARD_NUMBER]",
+	TypeIBAN:       "[IBAN]",
+	TypeSSN:        "[SSN]",
+	TypeNINO:       "[NINO]",
+	TypePAN:        "[PAN]",
+	TypeAadhaar:    "[AADHAAR]",
+	TypeAPIKey:     "[API_KEY]",
+	TypePrivateKey: "[PRIVATE_KEY]",
+}
+
+// detector finds one kind of value. Its pattern finds candidates, which check
+// validates; keywords near a candidate, or in the name of the field holding
+// it, raise the confidence by boost.
+type detector struct {
+	kind        string
+	description string
+	pattern     *regexp.Regexp
+	group       int  // submatch holding the value, 0 for the whole match
+	bounded     bool // the value must not continue a longer word or number
+	// maxDigits, when set, makes the pattern match runs of digit groups, in
+	// which each span of whole groups with minDigits to maxDigits digits is a
+	// candidate, so a value is found even when more digits follow it
+	minDigits, maxDigits int
+	// check returns the candidate's confidence before keywords and why, or
+	// 0 to reject it
+	check    func(value string, scope checkScope) (float64, string)
+	keywords []string
+	boost    float64
+}
+
+// checkScope is what a check can use besides the candidate itself
+type checkScope struct {
+	region  string // read national phone numbers in this region
+	keyword string // one of the detector's keywords found near the candidate
+}
+
+// fixed accepts every candidate with the same confidence, for patterns
+// specific enough to need no further check
+func fixed(confidence float64, reason string) func(string, checkScope) (float64, string) {
+	return func(string, checkScope) (float64, string) { return confidence, reason }
+}
+
+// identifier accepts candidates that pass the faker package's validator for
+// kind
+func identifier(kind string, confidence float64, reason string) func(string, checkScope) (float64, string) {
+	return func(value string, _ checkScope) (float64, string) {
+		result, err := faker.ValidateIdentifier(kind, value, "")
+		if err != nil || !result.Valid {
+			return 0, ""
+		}
+		return confidence, reason
+	}
+}
+
+var detectors = []detector{
+	{
+		kind:        TypePrivateKey,
+		description: "Private key",
+		pattern:     regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY(?: BLOCK)?-----(?s:.*?)-----END[A-Z ]*PRIVATE KEY(?: BLOCK)?-----`),
+		check:       fixed(0.99, "PEM private key block"),
+	},
+	{
+		kind:        TypePrivateKey,
+		description: "Private key",
+		pattern:     regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY(?: BLOCK)?-----`),
+		check:       fixed(0.9, "PEM private key header"),
+	},
+	{
+		kind:        TypeAPIKey,
+		description: "AWS access key ID",
+		pattern:     regexp.MustCompile(`(?:AKIA|ASIA)[0-9A-Z]{16}`),
+		bounded:     true,
+		check:       fixed(0.95, "AWS access key ID format"),
+	},
+	{
+		kind:        TypeAPIKey,
+		description: "GitHub token",
+		pattern:     regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255}`),
+		bounded:     true,
+		check:       fixed(0.95, "GitHub token format"),
+	},
+	{
+		kind:        TypeAPIKey,
+		description: "Stripe secret key",
+		pattern:     regexp.MustCompile(`(?:sk|rk)_(?:live|test)_[A-Za-z0-9]{16,}`),
+		bounded:     true,
+		check:       fixed(0.95, "Stripe secret key format"),
+	},
+	{
+		kind:        TypeAPIKey,
+		description: "Slack token",
+		pattern:     regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`),
+		bounded:     true,
+		check:       fixed(0.9, "Slack token format"),
+	},
+	{
+		kind:        TypeAPIKey,
+		description: "Google API key",
+		pattern:     regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),
+		bounded:     true,
+		check:       fixed(0.9, "Google API key format"),
+	},
+	{
+		kind:        TypeAPIKey,
+		description: "JSON Web Token",
+		pattern:     regexp.MustCompile(`eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+		bounded:     true,
+		check:       fixed(0.85, "JSON Web Token format"),
+	},
+	{
+		// A random-looking value assigned to a name such as api_key
+		kind:        TypeAPIKey,
+		description: "Secret assigned to a credential name",
+		pattern:     regexp.MustCompile(`(?i)(?:api[_-]?key|secret|token|passw(?:or)?d|access[_-]?key|auth)["']?\s*[:=]\s*["']?([A-Za-z0-9_\-./+=]{16,})`),
+		group:       1,
+		check:       randomToken(0.75, "random-looking value assigned to a credential name"),
+	},
+	{
+		// A random-looking field value, reported only when the field's name
+		// says it is a credential
+		kind:        TypeAPIKey,
+		description: "Secret in a credential field",
+		pattern:     regexp.MustCompile(`^[A-Za-z0-9_\-./+=]{16,}$`),
+		check:       randomToken(0.2, "random-looking value"),
+		keywords:    []string{"key", "secret", "token", "password", "credential"},
+		boost:       0.55,
+	},
+	{
+		kind:        TypeEmail,
+		description: "Email address",
+		pattern:     regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+		check: func(value string, _ checkScope) (float64, string) {
+			if _, err := mail.ParseAddress(value); err != nil {
+				return 0, ""
+			}
+			return 0.95, "valid email address"
+		},
+	},
+	{
+		kind:        TypeIBAN,
+		description: "IBAN",
+		pattern:     regexp.MustCompile(`[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?`),
+		bounded:     true,
+		check:       identifier("iban", 0.95, "valid IBAN check digits"),
+		keywords:    []string{"iban", "account", "bank"},
+		boost:       0.04,
+	},
+	{
+		kind:        TypeCreditCard,
+		description: "Payment card number",
+		pattern:     regexp.MustCompile(`\d(?:[ -]?\d){11,}`),
+		bounded:     true,
+		minDigits:   12,
+		maxDigits:   19,
+		check: func(value string, _ checkScope) (float64, string) {
+			digits := digitsOf(value)
+			if !faker.ValidCardNumber(digits) {
+				return 0, ""
+			}
+			if network := faker.IdentifyCardNetwork(digits); network != nil {
+				return 0.85, "passes the Luhn check with a " + network.Name + " prefix"
+			}
+			return 0.45, "passes the Luhn check"
+		},
+		keywords: []string{"card", "credit", "debit", "visa", "mastercard", "amex", "payment", "cc"},
+		boost:    0.1,
+	},
+	{
+		kind:        TypeSSN,
+		description: "US Social Security number",
+		pattern:     regexp.MustCompile(`\d{3}[- ]?\d{2}[- ]?\d{4}`),
+		bounded:     true,
+		check: func(value string, _ checkScope) (float64, string) {
+			if !consistentSeparators(value) {
+				return 0, ""
+			}
+			if result, err := faker.ValidateIdentifier("ssn", value, ""); err != nil || !result.Valid {
+				return 0, ""
+			}
+			if len(value) == 9 {
+				return 0.3, "valid SSN digits"
+			}
+			return 0.6, "SSN format"
+		},
+		keywords: []string{"ssn", "social security", "social sec"},
+		boost:    0.35,
+	},
+	{
+		kind:        TypeNINO,
+		description: "UK National Insurance number",
+		pattern:     regexp.MustCompile(`[A-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]`),
+		bounded:     true,
+		check:       identifier("nino", 0.75, "valid National Insurance number"),
+		keywords:    []string{"national insurance", "nino", "ni number", "ni no"},
+		boost:       0.2,
+	},
+	{
+		kind:        TypePAN,
+		description: "Indian PAN",
+		pattern:     regexp.MustCompile(`[A-Z]{5}\d{4}[A-Z]`),
+		bounded:     true,
+		check:       identifier("pan", 0.45, "valid PAN format"),
+		keywords:    []string{"pan", "permanent account", "income tax"},
+		boost:       0.45,
+	},
+	{
+		kind:        TypeAadhaar,
+		description: "Aadhaar number",
+		pattern:     regexp.MustCompile(`\d{4}[ -]?\d{4}[ -]?\d{4}`),
+		bounded:     true,
+		check: func(value string, scope checkScope) (float64, string) {
+			if !consistentSeparators(value) {
+				return 0, ""
+			}
+			return identifier("aadhaar", 0.4, "passes the Verhoeff check")(value, scope)
+		},
+		keywords: []string{"aadhaar", "aadhar", "uidai", "uid"},
+		boost:    0.5,
+	},
+	{
+		kind:        TypePhone,
+		description: "Phone number",
+		pattern:     regexp.MustCompile(`(?:\+|\()?\d[\d ().-]{5,17}\d`),
+		bounded:     true,
+		check: func(value string, scope checkScope) (float64, string) {
+			digits := digitsOf(value)
+			if len(digits) < 7 || strings.Count(value, "(") != strings.Count(value, ")") {
+				return 0, ""
+			}
+			phone, err := utils.NormalizePhone(value, scope.region)
+			if err == nil {
+				if strings.HasPrefix(value, "+") {
+					return 0.7, "valid international number for " + phone.Region
+				}
+				return 0.45, "valid number for " + phone.Region
+			}
+			// A number from another region only counts when its field or
+			// the text before it says it is a phone number
+			if scope.keyword == "" || strings.HasPrefix(value, "+") {
+				return 0, ""
+			}
+			for _, region := range utils.PhoneRegionCodes() {
+				if _, err := utils.NormalizePhone(value, region); err == nil {
+					return 0.35, "valid national number outside " + scope.region
+				}
+			}
+			return 0, ""
+		},
+		keywords: []string{"phone", "tel", "mobile", "cell", "call", "whatsapp", "fax", "contact"},
+		boost:    0.3,
+	},
+}
+
+// randomToken accepts values that look randomly generated, with letters and
+// digits and high entropy per character, rather than words or numbers
+func randomToken(confidence float64, reason string) func(string, checkScope) (float64, string) {
+	return func(value string, _ checkScope) (float64, string) {
+		if strings.IndexAny(value, "0123456789") < 0 || strings.IndexFunc(value, isLetter) < 0 || entropy(value) < 3.5 {
+			return 0, ""
+		}
+		return confidence, reason
+	}
+}
+
+// entropy returns the Shannon entropy of value in bits per character
+func entropy(value string) float64 {
+	counts := map[rune]int{}
+	for _, r := range value {
+		counts[r]++
+	}
+	bits := 0.0
+	n := float64(len([]rune(value)))
+	for _, count := range counts {
+		p := float64(count) / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// consistentSeparators reports whether a number's groups are either not
+// separated or all separated by the same character, so "123-45 6789" is not
+// taken for an SSN
+func consistentSeparators(value string) bool {
+	separators := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return -1
+		}
+		return r
+	}, value)
+	return separators == "" || (len(separators) == 2 && separators[0] == separators[1])
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func digitsOf(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
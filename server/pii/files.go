@@ -0,0 +1,175 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvSegments splits a CSV file into its fields. The first row names the
+// columns, which are used as context for the fields below them.
+func csvSegments(input string) ([]segment, error) {
+	reader := csv.NewReader(strings.NewReader(input))
+	reader.FieldsPerRecord = -1
+	lines := append([]int{0}, lineStarts(input)...)
+
+	var segments []segment
+	var header []string
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row == 0 {
+			header = append([]string(nil), record...)
+		}
+
+		for i, value := range record {
+			seg := segment{text: value}
+			if row > 0 && i < len(header) {
+				seg.context = header[i]
+				seg.location = fmt.Sprintf("row %d, %s", row, header[i])
+			} else {
+				seg.location = fmt.Sprintf("row %d, column %d", row, i+1)
+			}
+
+			line, column := reader.FieldPos(i)
+			start := lines[line-1] + column - 1
+			if start >= len(input) || input[start] != '"' {
+				seg.exact, seg.offset = true, start
+			} else if end := closingQuote(input, start); input[start+1:end-1] == value {
+				seg.exact, seg.offset = true, start+1
+			} else {
+				// Escaped quotes or normalised line endings
+				seg.start, seg.end, seg.encode = start, end, quoteCSV
+			}
+			segments = append(segments, seg)
+		}
+	}
+}
+
+// closingQuote returns the offset just after the quote closing the quoted
+// CSV field starting at start
+func closingQuote(input string, start int) int {
+	for i := start + 1; i < len(input); i++ {
+		if input[i] != '"' {
+			continue
+		}
+		if i+1 < len(input) && input[i+1] == '"' {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(input)
+}
+
+// quoteCSV writes value as a quoted CSV field
+func quoteCSV(value string) string {
+	return `"` + strings.Replace(value, `"`, `""`, -1) + `"`
+}
+
+// jsonSegments splits a JSON document, or a stream of them, into its string
+// and number values. The name of the key holding each value is its context.
+func jsonSegments(input string) ([]segment, error) {
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+
+	// frame is an object or array being read
+	type frame struct {
+		object    bool
+		key       string
+		index     int
+		expectKey bool
+	}
+	var stack []*frame
+	path := func() string {
+		var b strings.Builder
+		for _, f := range stack {
+			if !f.object {
+				b.WriteString("[" + strconv.Itoa(f.index) + "]")
+			} else if b.Len() > 0 {
+				b.WriteString("." + f.key)
+			} else {
+				b.WriteString(f.key)
+			}
+		}
+		return b.String()
+	}
+	// valueDone moves the innermost container past the value just read
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		if top := stack[len(stack)-1]; top.object {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	}
+
+	var segments []segment
+	for {
+		before := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return segments, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		end := int(decoder.InputOffset())
+
+		// Values in arrays take the name of the key holding the array
+		context := ""
+		for i := len(stack) - 1; i >= 0 && context == ""; i-- {
+			context = stack[i].key
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			if t == '{' || t == '[' {
+				stack = append(stack, &frame{object: t == '{', expectKey: t == '{'})
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			valueDone()
+		case string:
+			if top := len(stack) - 1; top >= 0 && stack[top].expectKey {
+				stack[top].key, stack[top].expectKey = t, false
+				continue
+			}
+			start := before + strings.IndexByte(input[before:end], '"')
+			seg := segment{text: t, context: context, location: path()}
+			if input[start+1:end-1] == t {
+				seg.exact, seg.offset = true, start+1
+			} else {
+				seg.start, seg.end, seg.encode = start, end, quoteJSON
+			}
+			segments = append(segments, seg)
+			valueDone()
+		case json.Number:
+			// Numbers are redacted as strings, since labels are not numbers
+			segments = append(segments, segment{text: t.String(), context: context, location: path(), start: end - len(t), end: end, encode: quoteJSON})
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
+
+// quoteJSON writes value as a JSON string
+func quoteJSON(value string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,377 @@
+// Package pii finds personal data and secrets in text and files, such as
+// email addresses, card numbers, national IDs and API keys, so they can be
+// removed before the text is shared
+package pii
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// Input formats
+const (
+	FormatText = "text"
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// DefaultMinConfidence hides matches too weak to be worth reviewing, such as
+// a 12-digit number that happens to pass the Aadhaar check
+const DefaultMinConfidence = 0.5
+
+// MaxFindings limits the findings returned by one scan. Redaction still
+// covers every finding.
+const MaxFindings = 1000
+
+// contextWindow is how many bytes before a match are searched for keywords
+const contextWindow = 40
+
+// Options limit what a scan reports
+type Options struct {
+	MinConfidence float64
+	Types         []string // empty for every type
+	// Region is where phone numbers without a country code are assumed to
+	// be from, defaulting to utils.DefaultPhoneRegion
+	Region string
+}
+
+// Validate checks the options, naming the first problem
+func (o Options) Validate() error {
+	if o.MinConfidence < 0 || o.MinConfidence > 1 {
+		return fmt.Errorf("minConfidence must be between 0 and 1")
+	}
+	for _, kind := range o.Types {
+		if labels[kind] == "" {
+			return fmt.Errorf("types must be among %s", strings.Join(Types, ", "))
+		}
+	}
+	if o.Region != "" && !containsString(utils.PhoneRegionCodes(), strings.ToUpper(o.Region)) {
+		return fmt.Errorf("region must be one of %s", strings.Join(utils.PhoneRegionCodes(), ", "))
+	}
+	return nil
+}
+
+// region returns the phone region to read national numbers in
+func (o Options) region() string {
+	if o.Region != "" {
+		return strings.ToUpper(o.Region)
+	}
+	return utils.DefaultPhoneRegion()
+}
+
+// segment is a piece of the input scanned on its own: all of a text, or one
+// field of a CSV or JSON file
+type segment struct {
+	text     string
+	context  string // name of the field holding the text, for keywords
+	location string
+	// When exact, text appears unchanged in the input at offset. Otherwise
+	// it was escaped, and findings cover the field's span [start, end),
+	// which redaction replaces with encode(redacted text).
+	exact      bool
+	offset     int
+	start, end int
+	encode     func(string) string
+}
+
+// match is a finding within a segment's text
+type match struct {
+	detector   *detector
+	start, end int
+	confidence float64
+	reasons    []string
+}
+
+// FormatFor picks the input format for a file name
+func FormatFor(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".json", ".ndjson", ".jsonl":
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// Scan finds personal data and secrets in input, read as format, and returns
+// them with a redacted copy of the input in the same format
+func Scan(input string, format string, options Options) (models.PIIScanResponse, error) {
+	var segments []segment
+	var err error
+	switch format {
+	case FormatText:
+		segments = []segment{{text: input, exact: true}}
+	case FormatCSV:
+		segments, err = csvSegments(input)
+	case FormatJSON:
+		segments, err = jsonSegments(input)
+	default:
+		err = fmt.Errorf("format must be text, csv or json")
+	}
+	if err != nil {
+		return models.PIIScanResponse{}, err
+	}
+
+	response := models.PIIScanResponse{Format: format, Findings: []models.PIIFinding{}, Counts: map[string]int{}}
+	lines := lineStarts(input)
+	var replacements []replacement
+	for _, seg := range segments {
+		matches := options.filter(findMatches(seg, options.region()))
+		if len(matches) == 0 {
+			continue
+		}
+
+		for _, m := range matches {
+			start, end := seg.start, seg.end
+			if seg.exact {
+				start, end = seg.offset+m.start, seg.offset+m.end
+				replacements = append(replacements, replacement{start, end, labels[m.detector.kind]})
+			}
+			response.Counts[m.detector.kind]++
+			if len(response.Findings) == MaxFindings {
+				response.Truncated = true
+				continue
+			}
+			response.Findings = append(response.Findings, models.PIIFinding{
+				Type:        m.detector.kind,
+				Description: m.detector.description,
+				Start:       start,
+				End:         end,
+				Line:        sort.SearchInts(lines, start+1) + 1,
+				Location:    seg.location,
+				Preview:     preview(m.detector.kind, seg.text[m.start:m.end]),
+				Confidence:  math.Round(m.confidence*100) / 100,
+				Reasons:     m.reasons,
+			})
+		}
+		if !seg.exact {
+			replacements = append(replacements, replacement{seg.start, seg.end, seg.encode(redact(seg.text, matches))})
+		}
+	}
+
+	response.Redacted = splice(input, replacements)
+	return response, nil
+}
+
+// findMatches runs every detector over a segment, keeping the most confident
+// of any overlapping matches. National phone numbers are read in region.
+func findMatches(seg segment, region string) []match {
+	var candidates []match
+	for i := range detectors {
+		d := &detectors[i]
+		d.candidates(seg.text, func(start, end int) bool {
+			keyword := d.keywordNear(seg, start)
+			confidence, reason := d.check(seg.text[start:end], checkScope{region: region, keyword: keyword})
+			if confidence == 0 {
+				return false
+			}
+			reasons := []string{reason}
+			if keyword != "" {
+				confidence = math.Min(confidence+d.boost, 0.99)
+				reasons = append(reasons, fmt.Sprintf("near the keyword %q", keyword))
+			}
+			candidates = append(candidates, match{detector: d, start: start, end: end, confidence: confidence, reasons: reasons})
+			return true
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].confidence != candidates[j].confidence {
+			return candidates[i].confidence > candidates[j].confidence
+		}
+		return candidates[i].end-candidates[i].start > candidates[j].end-candidates[j].start
+	})
+	var kept []match
+	for _, candidate := range candidates {
+		overlaps := false
+		for _, k := range kept {
+			if candidate.start < k.end && k.start < candidate.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, candidate)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+	return kept
+}
+
+// candidates passes each of the detector's candidates in text to try, which
+// reports whether it accepted the candidate. In a run of digit groups, the
+// longest accepted span from a group on is kept and the search goes on after
+// it, or from the next group when none is accepted.
+func (d *detector) candidates(text string, try func(start, end int) bool) {
+	for _, loc := range d.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2*d.group], loc[2*d.group+1]
+		if d.bounded && !atBoundary(text, start, end) {
+			continue
+		}
+		if d.maxDigits == 0 {
+			try(start, end)
+			continue
+		}
+
+		// Groups start after each separator and end before it
+		starts, ends := []int{start}, []int{}
+		for i := start; i < end; i++ {
+			if text[i] == ' ' || text[i] == '-' {
+				ends = append(ends, i)
+				starts = append(starts, i+1)
+			}
+		}
+		ends = append(ends, end)
+
+		for first := 0; first < len(starts); {
+			// Every group holds a digit, so no more than maxDigits groups fit
+			next, last := first+1, first+d.maxDigits-1
+			if last >= len(ends) {
+				last = len(ends) - 1
+			}
+			for ; last >= first; last-- {
+				digits := len(digitsOf(text[starts[first]:ends[last]]))
+				if digits < d.minDigits || digits > d.maxDigits {
+					continue
+				}
+				if try(starts[first], ends[last]) {
+					next = last + 1
+					break
+				}
+			}
+			first = next
+		}
+	}
+}
+
+// keywordNear returns the first of the detector's keywords found in the name
+// of the segment's field or just before the match, or ""
+func (d *detector) keywordNear(seg segment, start int) string {
+	if len(d.keywords) == 0 {
+		return ""
+	}
+	from := start - contextWindow
+	if from < 0 {
+		from = 0
+	}
+	nearby := strings.ToLower(seg.context + " " + seg.text[from:start])
+	for _, keyword := range d.keywords {
+		if strings.Contains(nearby, keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// filter drops matches below the minimum confidence or of unwanted types. It
+// runs after overlaps are resolved, so excluding cards does not turn a card
+// number into a reported phone number.
+func (o Options) filter(matches []match) []match {
+	var kept []match
+	for _, m := range matches {
+		if m.confidence < o.MinConfidence {
+			continue
+		}
+		if len(o.Types) > 0 && !containsString(o.Types, m.detector.kind) {
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// atBoundary reports whether text[start:end] neither starts nor ends in the
+// middle of a word or number
+func atBoundary(text string, start, end int) bool {
+	return (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end]))
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// replacement replaces input[start:end] with text
+type replacement struct {
+	start, end int
+	text       string
+}
+
+// splice applies non-overlapping replacements to input
+func splice(input string, replacements []replacement) string {
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start < replacements[j].start })
+	var b strings.Builder
+	last := 0
+	for _, r := range replacements {
+		b.WriteString(input[last:r.start])
+		b.WriteString(r.text)
+		last = r.end
+	}
+	b.WriteString(input[last:])
+	return b.String()
+}
+
+// redact replaces the matches in text with their labels
+func redact(text string, matches []match) string {
+	replacements := make([]replacement, len(matches))
+	for i, m := range matches {
+		replacements[i] = replacement{m.start, m.end, labels[m.detector.kind]}
+	}
+	return splice(text, replacements)
+}
+
+// preview hides most of a value, keeping enough to recognise it: the last
+// four characters of numbers, the first letter and domain of emails and the
+// first few characters of keys
+func preview(kind string, value string) string {
+	switch kind {
+	case TypeEmail:
+		at := strings.LastIndex(value, "@")
+		return value[:1] + "***" + value[at:]
+	case TypePrivateKey:
+		return strings.SplitN(value, "\n", 2)[0]
+	case TypeAPIKey:
+		return value[:4] + strings.Repeat("*", 8)
+	}
+
+	// Hide every letter and digit but the last four
+	runes := []rune(value)
+	shown := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] > 0x7f || !isWordByte(byte(runes[i])) {
+			continue
+		}
+		if shown < 4 {
+			shown++
+			continue
+		}
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// lineStarts returns the offset just after each newline, so a finding's line
+// is the number of newlines before it plus one
+func lineStarts(input string) []int {
+	var starts []int
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+type wantFinding struct {
+	kind  string
+	value string // the input at [start, end)
+}
+
+func TestScanFindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		options Options
+		want    []wantFinding
+	}{
+		{
+			name:   "email and card in text",
+			input:  "Reach jane.doe@example.com, card 4111 1111 1111 1111 expires soon",
+			format: FormatText,
+			want:   []wantFinding{{TypeEmail, "jane.doe@example.com"}, {TypeCreditCard, "4111 1111 1111 1111"}},
+		},
+		{
+			name:   "card followed by an expiry date",
+			input:  "card 4111 1111 1111 1111 12/27",
+			format: FormatText,
+			want:   []wantFinding{{TypeCreditCard, "4111 1111 1111 1111"}},
+		},
+		{
+			name:   "card followed by a security code",
+			input:  "4111111111111111 123",
+			format: FormatText,
+			want:   []wantFinding{{TypeCreditCard, "4111111111111111"}},
+		},
+		{
+			name:   "two cards in a row",
+			input:  "cards: 4111111111111111 5500000000000004",
+			format: FormatText,
+			want:   []wantFinding{{TypeCreditCard, "4111111111111111"}, {TypeCreditCard, "5500000000000004"}},
+		},
+		{
+			name:   "card after other digits",
+			input:  "order 12 4111-1111-1111-1111",
+			format: FormatText,
+			want:   []wantFinding{{TypeCreditCard, "4111-1111-1111-1111"}},
+		},
+		{
+			name:   "international phone in text",
+			input:  "Call +44 20 7946 0958 today",
+			format: FormatText,
+			want:   []wantFinding{{TypePhone, "+44 20 7946 0958"}},
+		},
+		{
+			name:    "national phone in the requested region",
+			input:   "Call 020 7946 0958 today",
+			format:  FormatText,
+			options: Options{Region: "GB"},
+			want:    []wantFinding{{TypePhone, "020 7946 0958"}},
+		},
+		{
+			name:    "national phone from another region without context",
+			input:   "Reference 020 7946 0958 attached",
+			format:  FormatText,
+			options: Options{Region: "US"},
+			want:    nil,
+		},
+		{
+			name:    "national phone from another region in a phone column",
+			input:   "name,phone\nJane,020 7946 0958\n",
+			format:  FormatCSV,
+			options: Options{Region: "US"},
+			want:    []wantFinding{{TypePhone, "020 7946 0958"}},
+		},
+		{
+			name:   "quoted CSV field",
+			input:  "id,contact\n1,\"jane@example.com\"\n",
+			format: FormatCSV,
+			want:   []wantFinding{{TypeEmail, "jane@example.com"}},
+		},
+		{
+			name:   "multi-byte text before a finding",
+			input:  "Ünïcödé — jane@example.com",
+			format: FormatText,
+			want:   []wantFinding{{TypeEmail, "jane@example.com"}},
+		},
+		{
+			name:   "JSON values",
+			input:  `{"ssn": "078-05-1120", "note": "nothing here"}`,
+			format: FormatJSON,
+			want:   []wantFinding{{TypeSSN, "078-05-1120"}},
+		},
+		{
+			name:   "national insurance number",
+			input:  "NI number: AB 12 34 56 C.",
+			format: FormatText,
+			want:   []wantFinding{{TypeNINO, "AB 12 34 56 C"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.MinConfidence = DefaultMinConfidence
+			if err := options.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			response, err := Scan(tt.input, tt.format, options)
+			if err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+
+			if len(response.Findings) != len(tt.want) {
+				t.Fatalf("got %d findings %+v, want %d", len(response.Findings), response.Findings, len(tt.want))
+			}
+			for i, finding := range response.Findings {
+				if finding.Type != tt.want[i].kind {
+					t.Errorf("finding %d type = %s, want %s", i, finding.Type, tt.want[i].kind)
+				}
+				if got := tt.input[finding.Start:finding.End]; got != tt.want[i].value {
+					t.Errorf("finding %d covers %q, want %q", i, got, tt.want[i].value)
+				}
+			}
+			for _, want := range tt.want {
+				if strings.Contains(response.Redacted, want.value) {
+					t.Errorf("redacted output still contains %q: %s", want.value, response.Redacted)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionsValidateRegion(t *testing.T) {
+	tests := []struct {
+		region string
+		valid  bool
+	}{
+		{"", true},
+		{"GB", true},
+		{"gb", true},
+		{"XX", false},
+	}
+	for _, tt := range tests {
+		err := Options{Region: tt.region}.Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(region %q) = %v, want valid %v", tt.region, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package risk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// Kinds of fact
+const (
+	factBoolean = "boolean"
+	factNumber  = "number"
+	factString  = "string"
+	factList    = "list"
+)
+
+// factKinds are the facts known about a user, by name
+var factKinds = map[string]string{
+	"email":               factString,
+	"emailDomain":         factString,
+	"hasStrongPasswords":  factBoolean,
+	"uses2FA":             factBoolean,
+	"usesSocialMedia":     factBoolean,
+	"publicProfiles":      factList,
+	"hasDataBreaches":     factBoolean,
+	"sharesPersonalInfo":  factBoolean,
+	"breachCount":         factNumber,
+	"vaultPasswords":      factNumber,
+	"weakPasswords":       factNumber,
+	"reusedPasswords":     factNumber,
+	"monitoredIdentities": factNumber,
+	"openAlerts":          factNumber,
+}
+
+// Sources of a risk factor
+const (
+	SourceVerified     = "verified"
+	SourceSelfReported = "self-reported"
+)
+
+// Fact is one thing known about a user
+type Fact struct {
+	Value interface{} // bool, float64, string or []string
+	// Verified facts come from the user's data, such as breach lookups, rather
+	// than their answers, and Evidence says how
+	Verified bool
+	Evidence string
+}
+
+// Facts are what is known about a user, by fact name. A missing fact fails
+// every condition on it.
+type Facts map[string]Fact
+
+// FactsFor returns the verified facts, filling the gaps with the answers
+// given in a risk analysis request
+func FactsFor(request models.RiskAnalysisRequest, verified Facts) Facts {
+	facts := Facts{
+		"email":              {Value: strings.ToLower(strings.TrimSpace(request.Email))},
+		"hasStrongPasswords": {Value: request.HasStrongPasswords},
+		"uses2FA":            {Value: request.Uses2FA},
+		"usesSocialMedia":    {Value: request.UsesSocialMedia},
+		"publicProfiles":     {Value: append([]string{}, request.PublicProfiles...)},
+		"hasDataBreaches":    {Value: request.HasDataBreaches},
+		"sharesPersonalInfo": {Value: request.SharesPersonalInfo},
+	}
+	if at := strings.LastIndex(request.Email, "@"); at >= 0 {
+		facts["emailDomain"] = Fact{Value: strings.ToLower(strings.TrimSpace(request.Email[at+1:]))}
+	}
+	for name, fact := range verified {
+		facts[name] = fact
+	}
+	return facts
+}
+
+// Snapshot returns the facts' values and the names of the verified facts, for
+// recording an assessment. The email address is left out; its domain is kept.
+func (f Facts) Snapshot() (map[string]interface{}, []string) {
+	values := map[string]interface{}{}
+	verified := []string{}
+	for name, fact := range f {
+		if name == "email" {
+			continue
+		}
+		values[name] = fact.Value
+		if fact.Verified {
+			verified = append(verified, name)
+		}
+	}
+	sort.Strings(verified)
+	return values, verified
+}
+
+// Evaluate scores facts with the policy. Factors, mitigations and advice are
+// listed in the order of the rules that produced them, and each factor that
+// can be fixed is simulated fixed on its own to rank the fixes.
+func (p *Policy) Evaluate(facts Facts) models.RiskAnalysisResponse {
+	response, total := p.evaluate(facts)
+	response.WhatIf = []models.RiskWhatIf{}
+	for _, factor := range response.Factors {
+		if !factor.Fixable {
+			continue
+		}
+		whatIf, _ := p.simulate(facts, total, []string{factor.ID})
+		response.WhatIf = append(response.WhatIf, whatIf)
+	}
+	sort.SliceStable(response.WhatIf, func(i, j int) bool {
+		return response.WhatIf[i].Improvement > response.WhatIf[j].Improvement
+	})
+	return response
+}
+
+// Simulate returns the score facts would get with the rules named by ids
+// fixed together
+func (p *Policy) Simulate(facts Facts, ids []string) (models.RiskWhatIf, error) {
+	_, total := p.evaluate(facts)
+	return p.simulate(facts, total, ids)
+}
+
+// simulate scores facts with the rules named by ids fixed, comparing with
+// their uncapped total so that fixes still rank when the score is capped. A
+// rule's fix facts replace the user's, which may bring in other rules
+// such as mitigations. Rules without a fix cannot be simulated, since
+// dropping their points would promise an improvement nobody can make.
+func (p *Policy) simulate(facts Facts, total int, ids []string) (models.RiskWhatIf, error) {
+	fixed := Facts{}
+	for name, fact := range facts {
+		fixed[name] = fact
+	}
+	for _, id := range ids {
+		rule := p.rule(id)
+		if rule == nil {
+			return models.RiskWhatIf{}, fmt.Errorf("unknown factor %q", id)
+		}
+		if len(rule.Fix) == 0 {
+			return models.RiskWhatIf{}, fmt.Errorf("factor %q cannot be fixed", id)
+		}
+		for name, value := range rule.Fix {
+			fixed[name] = Fact{Value: value}
+		}
+	}
+
+	result, fixedTotal := p.evaluate(fixed)
+	return models.RiskWhatIf{
+		FactorIDs:   ids,
+		Score:       result.Score,
+		RiskLevel:   result.RiskLevel,
+		Improvement: total - fixedTotal,
+	}, nil
+}
+
+// evaluate scores facts and also returns the score before it was capped
+func (p *Policy) evaluate(facts Facts) (models.RiskAnalysisResponse, int) {
+	response := models.RiskAnalysisResponse{BaseScore: p.BaseScore, PolicyVersion: p.Version}
+	score := p.BaseScore
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.When != nil && !rule.When.matches(facts) {
+			continue
+		}
+
+		contribution := rule.Weight
+		if rule.Per != "" {
+			contribution *= int(count(facts[rule.Per].Value))
+		}
+		score += contribution
+
+		// Advice of a rule that neither names a factor nor moves the score is general
+		if rule.Factor == "" && contribution == 0 {
+			if rule.Advice != "" {
+				response.Advice = append(response.Advice, rule.Advice)
+			}
+			continue
+		}
+		if contribution < 0 {
+			response.Mitigations = append(response.Mitigations, rule.factor(facts, contribution))
+		} else {
+			response.Factors = append(response.Factors, rule.factor(facts, contribution))
+		}
+	}
+
+	// Cap score between 0 and 100
+	response.Score = score
+	if score < 0 {
+		response.Score = 0
+	} else if score > 100 {
+		response.Score = 100
+	}
+	response.RiskLevel = p.level(response.Score)
+	return response, score
+}
+
+// rule returns the rule with the given ID, or nil
+func (p *Policy) rule(id string) *Rule {
+	for i := range p.Rules {
+		if p.Rules[i].ID == id {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// FactorLabel returns the factor the rule with the given ID reports, or the ID
+// itself when no rule has it or the rule names no factor
+func (p *Policy) FactorLabel(id string) string {
+	if rule := p.rule(id); rule != nil && rule.Factor != "" {
+		return rule.Factor
+	}
+	return id
+}
+
+// FactorID returns the ID of the rule reporting a factor, given either its ID
+// or, as assessments recorded before IDs were stored hold, its label
+func (p *Policy) FactorID(factor string) string {
+	if p.rule(factor) != nil {
+		return factor
+	}
+	for i := range p.Rules {
+		if p.Rules[i].Factor == factor {
+			return p.Rules[i].ID
+		}
+	}
+	return factor
+}
+
+// factor describes what a rule found. It is verified when every fact the rule
+// tests was verified, and unless the rule sets a severity, a risk factor's
+// follows from the points it added.
+func (r *Rule) factor(facts Facts, contribution int) models.RiskFactor {
+	factor := models.RiskFactor{
+		ID:           r.ID,
+		Factor:       r.Factor,
+		Category:     r.Category,
+		Severity:     r.Severity,
+		Contribution: contribution,
+		Source:       SourceVerified,
+		Advice:       r.Advice,
+		Remediation:  r.Remediation,
+		Fixable:      len(r.Fix) > 0,
+	}
+	if factor.Factor == "" {
+		factor.Factor = r.ID
+	}
+	if factor.Severity == "" && contribution >= 0 {
+		factor.Severity = severity(contribution)
+	}
+
+	for _, name := range r.facts() {
+		fact, ok := facts[name]
+		if !ok {
+			continue
+		}
+		if !fact.Verified {
+			factor.Source = SourceSelfReported
+		} else if fact.Evidence != "" && !containsString(factor.Evidence, fact.Evidence) {
+			factor.Evidence = append(factor.Evidence, fact.Evidence)
+		}
+	}
+	if factor.Source == SourceSelfReported {
+		factor.Evidence = nil
+	}
+	return factor
+}
+
+// severity rates a risk factor by the points it added
+func severity(contribution int) string {
+	switch {
+	case contribution >= 20:
+		return "High"
+	case contribution >= 10:
+		return "Medium"
+	default:
+		return "Low"
+	}
+}
+
+// facts returns the names of the facts a rule tests
+func (r *Rule) facts() []string {
+	var names []string
+	if r.When != nil {
+		names = r.When.facts(names)
+	}
+	if r.Per != "" && !containsString(names, r.Per) {
+		names = append(names, r.Per)
+	}
+	return names
+}
+
+// facts appends the names of the facts a condition tests to names
+func (c *Condition) facts(names []string) []string {
+	if c.Fact != "" && !containsString(names, c.Fact) {
+		names = append(names, c.Fact)
+	}
+	for i := range c.All {
+		names = c.All[i].facts(names)
+	}
+	for i := range c.Any {
+		names = c.Any[i].facts(names)
+	}
+	if c.Not != nil {
+		names = c.Not.facts(names)
+	}
+	return names
+}
+
+// level names the level a score falls in
+func (p *Policy) level(score int) string {
+	for _, level := range p.Levels {
+		if level.Below == nil || score < *level.Below {
+			return level.Name
+		}
+	}
+	return p.Levels[len(p.Levels)-1].Name
+}
+
+// matches reports whether the condition holds for facts
+func (c *Condition) matches(facts Facts) bool {
+	for i := range c.All {
+		if !c.All[i].matches(facts) {
+			return false
+		}
+	}
+	if len(c.Any) > 0 {
+		matched := false
+		for i := range c.Any {
+			if c.Any[i].matches(facts) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if c.Not != nil && c.Not.matches(facts) {
+		return false
+	}
+	if c.Fact == "" {
+		return true
+	}
+
+	fact, ok := facts[c.Fact]
+	if !ok {
+		return false
+	}
+	value := fact.Value
+	switch {
+	case c.Equals != nil:
+		return value == c.Equals
+	case c.In != nil:
+		return in(value, c.In)
+	}
+
+	n := count(value)
+	return (c.GT == nil || n > *c.GT) &&
+		(c.GTE == nil || n >= *c.GTE) &&
+		(c.LT == nil || n < *c.LT) &&
+		(c.LTE == nil || n <= *c.LTE)
+}
+
+// count returns a number fact, or the length of a list fact
+func count(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case []string:
+		return float64(len(v))
+	}
+	return 0
+}
+
+// in reports whether a string fact, or any item of a list fact, is one of
+// values, ignoring case
+func in(value interface{}, values []string) bool {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = []string{v}
+	case []string:
+		items = v
+	}
+	for _, item := range items {
+		for _, candidate := range values {
+			if strings.EqualFold(item, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package risk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+func TestEvaluateWhatIfOnlyFixableFactors(t *testing.T) {
+	facts := FactsFor(models.RiskAnalysisRequest{HasDataBreaches: true}, Facts{
+		"breachCount": {Value: float64(5), Verified: true},
+	})
+	response := DefaultPolicy().Evaluate(facts)
+
+	fixable := map[string]bool{}
+	for _, factor := range response.Factors {
+		fixable[factor.ID] = factor.Fixable
+	}
+	for _, id := range []string{"data-breaches", "many-breaches"} {
+		if fixable, found := fixable[id]; !found || fixable {
+			t.Errorf("factor %s: found %v, fixable %v; want an unfixable factor", id, found, fixable)
+		}
+	}
+	if !fixable["weak-passwords"] || !fixable["no-2fa"] {
+		t.Errorf("weak-passwords and no-2fa should be fixable: %v", fixable)
+	}
+
+	if len(response.WhatIf) == 0 {
+		t.Fatal("whatIf is empty")
+	}
+	for _, whatIf := range response.WhatIf {
+		for _, id := range whatIf.FactorIDs {
+			if !fixable[id] {
+				t.Errorf("whatIf includes unfixable factor %s", id)
+			}
+		}
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	facts := FactsFor(models.RiskAnalysisRequest{HasDataBreaches: true}, nil)
+	policy := DefaultPolicy()
+
+	tests := []struct {
+		name    string
+		ids     []string
+		wantErr string
+	}{
+		{"fixable factors", []string{"no-2fa", "weak-passwords"}, ""},
+		{"unfixable factor", []string{"no-2fa", "data-breaches"}, "cannot be fixed"},
+		{"unknown factor", []string{"no-such-rule"}, "unknown factor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			whatIf, err := policy.Simulate(facts, tt.ids)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Simulate: %v", err)
+			}
+			if whatIf.Improvement <= 0 {
+				t.Errorf("improvement = %d, want a positive improvement", whatIf.Improvement)
+			}
+		})
+	}
+}
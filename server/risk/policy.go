@@ -0,0 +1,271 @@
+// Package risk scores a user's privacy risk with a policy of rules, each
+// adding a weight to the score and naming a risk factor and advice when its
+// condition holds. Policies are YAML or JSON, so scoring can be tuned without
+// changing code.
+//
+// Each factor in a result says how many points it contributed, and what the
+// score would be if it were fixed, so the fixes can be ranked by impact.
+package risk
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+//go:embed policies/default.yaml
+var policyFiles embed.FS
+
+// Policy is a versioned set of scoring rules
+type Policy struct {
+	Version   string  `json:"version" yaml:"version"`
+	BaseScore int     `json:"baseScore" yaml:"baseScore"`
+	Levels    []Level `json:"levels" yaml:"levels"`
+	Rules     []Rule  `json:"rules" yaml:"rules"`
+}
+
+// Level names a range of scores. A level applies to scores below its bound
+// that no earlier level covers; the last level has no bound.
+type Level struct {
+	Name  string `json:"name" yaml:"name"`
+	Below *int   `json:"below,omitempty" yaml:"below,omitempty"`
+}
+
+// Rule adds its weight to the score when its condition holds, or always when
+// it has none. With per, the weight is added once for each item of a list
+// fact or each unit of a number fact.
+//
+// Fix gives the facts as they would be once the factor is fixed, for working
+// out the score if it were. A rule without one, such as past breaches, is
+// something the user cannot undo, so it is left out of what-if results.
+type Rule struct {
+	ID          string                 `json:"id" yaml:"id"`
+	When        *Condition             `json:"when,omitempty" yaml:"when,omitempty"`
+	Weight      int                    `json:"weight" yaml:"weight"`
+	Per         string                 `json:"per,omitempty" yaml:"per,omitempty"`
+	Factor      string                 `json:"factor,omitempty" yaml:"factor,omitempty"`
+	Category    string                 `json:"category,omitempty" yaml:"category,omitempty"`
+	Severity    string                 `json:"severity,omitempty" yaml:"severity,omitempty"` // Low, Medium or High; by default from the points added
+	Advice      string                 `json:"advice,omitempty" yaml:"advice,omitempty"`
+	Remediation []string               `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+	Fix         map[string]interface{} `json:"fix,omitempty" yaml:"fix,omitempty"`
+}
+
+// Condition tests a fact with one operator, and holds when that test and all
+// of its nested conditions hold. Number comparisons of a list fact compare its
+// length, and in matches a list fact when any item is in the values.
+type Condition struct {
+	All []Condition `json:"all,omitempty" yaml:"all,omitempty"`
+	Any []Condition `json:"any,omitempty" yaml:"any,omitempty"`
+	Not *Condition  `json:"not,omitempty" yaml:"not,omitempty"`
+
+	Fact   string      `json:"fact,omitempty" yaml:"fact,omitempty"`
+	Equals interface{} `json:"equals,omitempty" yaml:"equals,omitempty"`
+	In     []string    `json:"in,omitempty" yaml:"in,omitempty"`
+	GT     *float64    `json:"gt,omitempty" yaml:"gt,omitempty"`
+	GTE    *float64    `json:"gte,omitempty" yaml:"gte,omitempty"`
+	LT     *float64    `json:"lt,omitempty" yaml:"lt,omitempty"`
+	LTE    *float64    `json:"lte,omitempty" yaml:"lte,omitempty"`
+}
+
+// DefaultPolicy returns the built-in policy
+func DefaultPolicy() *Policy {
+	data, err := policyFiles.ReadFile("policies/default.yaml")
+	if err != nil {
+		panic("risk: missing default policy")
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		panic("risk: invalid default policy: " + err.Error())
+	}
+	return policy
+}
+
+// ParsePolicy reads a policy from YAML or JSON and checks it, rejecting
+// unknown fields so that a misspelt key is not silently ignored
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&policy); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&policy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// validate checks the policy, naming the first problem
+func (p *Policy) validate() error {
+	if strings.TrimSpace(p.Version) == "" {
+		return fmt.Errorf("version is required")
+	}
+
+	if len(p.Levels) == 0 {
+		return fmt.Errorf("levels: at least one level is required")
+	}
+	for i, level := range p.Levels {
+		last := i == len(p.Levels)-1
+		switch {
+		case level.Name == "":
+			return fmt.Errorf("levels[%d]: name is required", i)
+		case last && level.Below != nil:
+			return fmt.Errorf("levels[%d]: the last level covers every remaining score and cannot have below", i)
+		case !last && level.Below == nil:
+			return fmt.Errorf("levels[%d]: below is required", i)
+		case i > 0 && !last && *level.Below <= *p.Levels[i-1].Below:
+			return fmt.Errorf("levels[%d]: below must be greater than the previous level's", i)
+		}
+	}
+
+	ids := map[string]bool{}
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rules[%d]: id is required", i)
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("rules[%d]: duplicate id %q", i, rule.ID)
+		}
+		ids[rule.ID] = true
+
+		if rule.Per != "" {
+			if kind := factKinds[rule.Per]; kind != factNumber && kind != factList {
+				return fmt.Errorf("rules[%d] (%s): per must name a number or list fact", i, rule.ID)
+			}
+		}
+		if rule.When != nil {
+			if err := rule.When.validate(); err != nil {
+				return fmt.Errorf("rules[%d] (%s): when: %w", i, rule.ID, err)
+			}
+		}
+		if rule.Severity != "" && utils.SeverityRank(rule.Severity) == 0 {
+			return fmt.Errorf("rules[%d] (%s): severity must be Low, Medium or High", i, rule.ID)
+		}
+		for name, value := range rule.Fix {
+			kind, ok := factKinds[name]
+			if !ok {
+				return fmt.Errorf("rules[%d] (%s): fix: unknown fact %q, facts are %s", i, rule.ID, name, strings.Join(FactNames(), ", "))
+			}
+			normalized, ok := factValue(kind, value)
+			if !ok {
+				return fmt.Errorf("rules[%d] (%s): fix: fact %s is a %s and cannot be %v", i, rule.ID, name, kind, value)
+			}
+			p.Rules[i].Fix[name] = normalized
+		}
+	}
+	return nil
+}
+
+// factValue checks that value suits a fact of the given kind and converts it
+// to the type facts hold, since YAML decodes whole numbers as ints and lists
+// as []interface{}
+func factValue(kind string, value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, kind == factBoolean
+	case string:
+		return v, kind == factString
+	case int:
+		return float64(v), kind == factNumber
+	case float64:
+		return v, kind == factNumber
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, s)
+		}
+		return items, kind == factList
+	}
+	return nil, false
+}
+
+// validate checks a condition and normalises its equals value, since YAML
+// decodes whole numbers as ints
+func (c *Condition) validate() error {
+	for i := range c.All {
+		if err := c.All[i].validate(); err != nil {
+			return fmt.Errorf("all[%d]: %w", i, err)
+		}
+	}
+	for i := range c.Any {
+		if err := c.Any[i].validate(); err != nil {
+			return fmt.Errorf("any[%d]: %w", i, err)
+		}
+	}
+	if c.Not != nil {
+		if err := c.Not.validate(); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+	}
+
+	operators := 0
+	for _, set := range []bool{c.Equals != nil, c.In != nil, c.GT != nil, c.GTE != nil, c.LT != nil, c.LTE != nil} {
+		if set {
+			operators++
+		}
+	}
+	if c.Fact == "" {
+		if operators > 0 {
+			return fmt.Errorf("fact is required with an operator")
+		}
+		if len(c.All) == 0 && len(c.Any) == 0 && c.Not == nil {
+			return fmt.Errorf("a condition needs a fact, all, any or not")
+		}
+		return nil
+	}
+
+	kind, ok := factKinds[c.Fact]
+	if !ok {
+		return fmt.Errorf("unknown fact %q, facts are %s", c.Fact, strings.Join(FactNames(), ", "))
+	}
+	if operators != 1 {
+		return fmt.Errorf("fact %s needs exactly one of equals, in, gt, gte, lt or lte", c.Fact)
+	}
+
+	if c.Equals != nil {
+		value, ok := factValue(kind, c.Equals)
+		if !ok || kind == factList {
+			return fmt.Errorf("fact %s is a %s and cannot equal %v", c.Fact, kind, c.Equals)
+		}
+		c.Equals = value
+		return nil
+	}
+	if c.In != nil && kind != factString && kind != factList {
+		return fmt.Errorf("in only applies to string and list facts, and %s is a %s", c.Fact, kind)
+	}
+	if (c.GT != nil || c.GTE != nil || c.LT != nil || c.LTE != nil) && kind != factNumber && kind != factList {
+		return fmt.Errorf("comparisons only apply to number and list facts, and %s is a %s", c.Fact, kind)
+	}
+	return nil
+}
+
+// FactNames returns the facts conditions can test, sorted
+func FactNames() []string {
+	names := make([]string, 0, len(factKinds))
+	for name := range factKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,125 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// PolicyStore holds the policy in use. When RISK_POLICY_FILE names a file, the
+// store reloads it whenever it changes; a change that fails to parse is logged
+// and the previous policy stays in use.
+type PolicyStore struct {
+	path     string
+	interval time.Duration
+
+	mu       sync.RWMutex
+	policy   *Policy
+	loadedAt time.Time
+	modTime  time.Time
+	size     int64
+}
+
+// NewPolicyStore loads the policy named by RISK_POLICY_FILE, or the built-in
+// policy when it is unset
+func NewPolicyStore() (*PolicyStore, error) {
+	s := &PolicyStore{
+		path:     os.Getenv("RISK_POLICY_FILE"),
+		interval: utils.EnvDuration("RISK_POLICY_RELOAD_INTERVAL", 30*time.Second),
+	}
+	if s.path == "" {
+		s.policy, s.loadedAt = DefaultPolicy(), time.Now()
+		return s, nil
+	}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Policy returns the policy in use
+func (s *PolicyStore) Policy() *Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+// Source returns the policy file, or "built-in"
+func (s *PolicyStore) Source() string {
+	if s.path == "" {
+		return "built-in"
+	}
+	return s.path
+}
+
+// LoadedAt returns when the policy in use was loaded
+func (s *PolicyStore) LoadedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadedAt
+}
+
+// Reload reads the policy file again if it changed since it was last read,
+// and reports whether a new policy was loaded
+func (s *PolicyStore) Reload() (bool, error) {
+	if s.path == "" {
+		return false, nil
+	}
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return false, fmt.Errorf("reading risk policy: %w", err)
+	}
+
+	s.mu.RLock()
+	unchanged := s.policy != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return false, fmt.Errorf("reading risk policy: %w", err)
+	}
+	policy, err := ParsePolicy(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Remember a broken file too, so it is reported once rather than on every poll
+	s.modTime, s.size = info.ModTime(), info.Size()
+	if err != nil {
+		return false, fmt.Errorf("parsing risk policy %s: %w", s.path, err)
+	}
+	s.policy, s.loadedAt = policy, time.Now()
+	return true, nil
+}
+
+// Run reloads the policy file when it changes until the context is cancelled
+func (s *PolicyStore) Run(ctx context.Context) {
+	if s.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		loaded, err := s.Reload()
+		if err != nil {
+			log.Printf("Risk: %v, keeping policy version %s", err, s.Policy().Version)
+		} else if loaded {
+			log.Printf("Risk: loaded policy version %s from %s", s.Policy().Version, s.path)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// CachedBreaches is a breach lookup result stored in a cache
+type CachedBreaches struct {
+	Breaches  []string  `bson:"breaches"`
+	Source    string    `bson:"source"`
+	CheckedAt time.Time `bson:"checkedAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// BreachCache stores breach lookup results under an opaque key
+type BreachCache interface {
+	Get(ctx context.Context, key string) (*CachedBreaches, bool)
+	Set(ctx context.Context, key string, entry CachedBreaches)
+}
+
+// NewBreachCache returns the cache selected by BREACH_CACHE_BACKEND, either
+// "memory" (the default) or "mongo"
+func NewBreachCache(client *mongo.Client) BreachCache {
+	if os.Getenv("BREACH_CACHE_BACKEND") == "mongo" {
+		return NewMongoBreachCache(client)
+	}
+	return NewMemoryBreachCache(utils.EnvInt("BREACH_CACHE_SIZE", 10000))
+}
+
+// MemoryBreachCache is an in-memory LRU cache of breach lookups
+type MemoryBreachCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // most recently used at the front
+	entries  map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry CachedBreaches
+}
+
+// NewMemoryBreachCache creates an LRU cache holding at most capacity entries
+func NewMemoryBreachCache(capacity int) *MemoryBreachCache {
+	return &MemoryBreachCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry for key if it exists and has not expired
+func (c *MemoryBreachCache) Get(ctx context.Context, key string) (*CachedBreaches, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := element.Value.(*memoryCacheItem)
+	if time.Now().After(item.entry.ExpiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	entry := item.entry
+	return &entry, true
+}
+
+// Set stores an entry, evicting the least recently used one when full
+func (c *MemoryBreachCache) Set(ctx context.Context, key string, entry CachedBreaches) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*memoryCacheItem).entry = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// MongoBreachCache stores breach lookups in MongoDB so they are shared
+// between server instances. Expired documents are removed by a TTL index.
+type MongoBreachCache struct {
+	client    *mongo.Client
+	indexOnce sync.Once
+}
+
+// NewMongoBreachCache creates a new MongoDB backed cache
+func NewMongoBreachCache(client *mongo.Client) *MongoBreachCache {
+	return &MongoBreachCache{
+		client: client,
+	}
+}
+
+// Get returns the entry for key if it exists and has not expired
+func (c *MongoBreachCache) Get(ctx context.Context, key string) (*CachedBreaches, bool) {
+	collection := c.client.Database("safetrace").Collection("breach_cache")
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var entry CachedBreaches
+	filter := bson.M{"_id": key, "expiresAt": bson.M{"$gt": time.Now()}}
+	if err := collection.FindOne(dbCtx, filter).Decode(&entry); err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Breach cache: lookup failed: %v", err)
+		}
+		return nil, false
+	}
+	return &entry, true
+}
+
+// Set stores an entry
+func (c *MongoBreachCache) Set(ctx context.Context, key string, entry CachedBreaches) {
+	collection := c.client.Database("safetrace").Collection("breach_cache")
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	c.indexOnce.Do(func() {
+		_, err := collection.Indexes().CreateOne(dbCtx, mongo.IndexModel{
+			Keys:    bson.M{"expiresAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			log.Printf("Breach cache: failed to create TTL index: %v", err)
+		}
+	})
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := collection.ReplaceOne(dbCtx, bson.M{"_id": key}, entry, opts); err != nil {
+		log.Printf("Breach cache: store failed: %v", err)
+	}
+}
+
+// BreachResult is the outcome of a cached breach lookup
+type BreachResult struct {
+	Breaches  []string
+	Source    string
+	CheckedAt time.Time
+	Cached    bool
+}
+
+// BreachLookup wraps a breach provider with a TTL cache and collapses
+// concurrent lookups of the same identity into a single upstream request.
+// Identities are only ever used as cache keys in hashed form.
+type BreachLookup struct {
+	provider BreachProvider
+	cache    BreachCache
+	group    singleflight.Group
+	ttl      time.Duration
+	hashKey  []byte
+}
+
+// NewBreachLookup creates a new cached breach lookup. Cache keys are keyed
+// with BREACH_CACHE_KEY, falling back to ENCRYPTION_KEY. Without either, the
+// hashes in the cache and in check history could be reversed by guessing
+// identities, so no lookup is created.
+func NewBreachLookup(provider BreachProvider, cache BreachCache) (*BreachLookup, error) {
+	hashKey := os.Getenv("BREACH_CACHE_KEY")
+	if hashKey == "" {
+		hashKey = os.Getenv("ENCRYPTION_KEY")
+	}
+	if hashKey == "" {
+		return nil, errors.New("BREACH_CACHE_KEY or ENCRYPTION_KEY must be set to key identity hashes")
+	}
+
+	return &BreachLookup{
+		provider: provider,
+		cache:    cache,
+		ttl:      utils.EnvDuration("BREACH_CACHE_TTL", 6*time.Hour),
+		hashKey:  []byte(hashKey),
+	}, nil
+}
+
+// Lookup returns the breaches for a normalized identity. Cached results
+// older than maxAge are ignored; a maxAge of zero forces a fresh lookup.
+func (l *BreachLookup) Lookup(ctx context.Context, kind string, value string, maxAge time.Duration) (*BreachResult, error) {
+	key := l.Key(kind, value)
+
+	if maxAge > 0 {
+		if entry, ok := l.cache.Get(ctx, key); ok && time.Since(entry.CheckedAt) <= maxAge {
+			return &BreachResult{
+				Breaches:  entry.Breaches,
+				Source:    entry.Source,
+				CheckedAt: entry.CheckedAt,
+				Cached:    true,
+			}, nil
+		}
+	}
+
+	// The shared lookup must outlive any single caller's request
+	resultChan := l.group.DoChan(key, func() (interface{}, error) {
+		lookupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		breaches, err := l.provider.Lookup(lookupCtx, kind, value)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		l.cache.Set(lookupCtx, key, CachedBreaches{
+			Breaches:  breaches,
+			Source:    l.provider.Name(),
+			CheckedAt: now,
+			ExpiresAt: now.Add(l.ttl),
+		})
+		return &BreachResult{Breaches: breaches, Source: l.provider.Name(), CheckedAt: now}, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-resultChan:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		shared := *result.Val.(*BreachResult)
+		return &shared, nil
+	}
+}
+
+// TTL returns how long lookups stay cached
+func (l *BreachLookup) TTL() time.Duration {
+	return l.ttl
+}
+
+// Key returns the cache key for an identity: a keyed hash, so the cache never
+// holds the identity itself
+func (l *BreachLookup) Key(kind string, value string) string {
+	return kind + ":" + l.IdentityHash(kind, value)
+}
+
+// IdentityHash returns the keyed hash an identity is stored under
+func (l *BreachLookup) IdentityHash(kind string, value string) string {
+	mac := hmac.New(sha256.New, l.hashKey)
+	mac.Write([]byte(kind + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
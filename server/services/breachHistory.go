@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// IdentityPassword is the kind recorded for password checks
+const IdentityPassword = "password"
+
+// BreachHistory records users' breach checks and purges them once they are
+// older than the retention period
+type BreachHistory struct {
+	client        *mongo.Client
+	retention     time.Duration
+	purgeInterval time.Duration
+}
+
+// NewBreachHistory creates a new breach history configured from the
+// environment
+func NewBreachHistory(client *mongo.Client) *BreachHistory {
+	return &BreachHistory{
+		client:        client,
+		retention:     utils.EnvDuration("BREACH_HISTORY_RETENTION", 90*24*time.Hour),
+		purgeInterval: utils.EnvDuration("BREACH_HISTORY_PURGE_INTERVAL", time.Hour),
+	}
+}
+
+// Record stores a breach check. Failures are logged, since history must never
+// fail the check itself.
+func (h *BreachHistory) Record(record models.BreachCheckRecord) {
+	collection := h.client.Database("safetrace").Collection("breach_history")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if record.Breaches == nil {
+		record.Breaches = []string{}
+	}
+	if _, err := collection.InsertOne(dbCtx, record); err != nil {
+		log.Printf("Breach history: failed to record check: %v", err)
+	}
+}
+
+// Run purges expired history until the context is cancelled
+func (h *BreachHistory) Run(ctx context.Context) {
+	h.ensureIndexes(ctx)
+
+	ticker := time.NewTicker(h.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := h.Purge(ctx); err != nil {
+			log.Printf("Breach history: purge failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Breach history: purged %d checks older than %s", deleted, h.retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes checks older than the retention period
+func (h *BreachHistory) Purge(ctx context.Context) (int64, error) {
+	collection := h.client.Database("safetrace").Collection("breach_history")
+	dbCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	result, err := collection.DeleteMany(dbCtx, bson.M{"checkedAt": bson.M{"$lt": time.Now().Add(-h.retention)}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// Retention returns how long checks are kept
+func (h *BreachHistory) Retention() time.Duration {
+	return h.retention
+}
+
+func (h *BreachHistory) ensureIndexes(ctx context.Context) {
+	collection := h.client.Database("safetrace").Collection("breach_history")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(dbCtx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "checkedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "kind", Value: 1}, {Key: "identityHash", Value: 1}, {Key: "checkedAt", Value: -1}}},
+		{Keys: bson.M{"checkedAt": 1}},
+	})
+	if err != nil {
+		log.Printf("Breach history: failed to create indexes: %v", err)
+	}
+}
+
+// PasswordHashPrefix returns the first 5 hex characters of a password's SHA-1
+// hash, the only part sent to k-anonymity range APIs. Many passwords share a
+// prefix, so it does not identify the password.
+func PasswordHashPrefix(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))[:5]
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identity kinds understood by breach providers
+const (
+	IdentityEmail    = "email"
+	IdentityUsername = "username"
+	IdentityPhone    = "phone"
+)
+
+// ErrUnsupportedIdentity is returned when a provider cannot look up an identity kind
+var ErrUnsupportedIdentity = errors.New("identity kind not supported by provider")
+
+// RateLimitError is returned when the upstream API asks us to slow down
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by provider, retry after %s", e.RetryAfter)
+}
+
+// BreachProvider looks up the names of the breaches an identity appears in
+type BreachProvider interface {
+	Name() string
+	Lookup(ctx context.Context, kind string, value string) ([]string, error)
+}
+
+// DomainSearcher is implemented by providers that can list every breached
+// address on a domain
+type DomainSearcher interface {
+	SearchDomain(ctx context.Context, domain string) (map[string][]string, error)
+}
+
+// NewBreachProvider returns the fixture provider when BREACH_FIXTURES names a
+// file, the XposedOrNot provider when XPOSED_API_KEY is set, and the mock
+// provider otherwise
+func NewBreachProvider() (BreachProvider, error) {
+	if path := os.Getenv("BREACH_FIXTURES"); path != "" {
+		return NewFixtureBreachProvider(path)
+	}
+
+	apiKey := os.Getenv("XPOSED_API_KEY")
+	if apiKey == "" {
+		return &MockBreachProvider{}, nil
+	}
+	return NewXposedOrNotProvider(apiKey), nil
+}
+
+// XposedOrNotProvider looks up email breaches through the XposedOrNot API
+type XposedOrNotProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewXposedOrNotProvider creates a new XposedOrNot provider
+func NewXposedOrNotProvider(apiKey string) *XposedOrNotProvider {
+	return &XposedOrNotProvider{
+		apiKey:  apiKey,
+		baseURL: "https://api.xposedornot.com/v1",
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name returns the provider name
+func (p *XposedOrNotProvider) Name() string {
+	return "XposedOrNot"
+}
+
+// Lookup returns the breaches an email address appears in. XposedOrNot does
+// not index phone numbers or usernames.
+func (p *XposedOrNotProvider) Lookup(ctx context.Context, kind string, value string) ([]string, error) {
+	if kind != IdentityEmail {
+		return nil, ErrUnsupportedIdentity
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/check-email/"+url.PathEscape(value), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("X-Api-Key", p.apiKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return []string{}, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %d from XposedOrNot", resp.StatusCode)
+	}
+
+	var apiResponse struct {
+		Breaches [][]string `json:"breaches"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, err
+	}
+
+	breaches := []string{}
+	for _, group := range apiResponse.Breaches {
+		breaches = append(breaches, group...)
+	}
+	sort.Strings(breaches)
+	return breaches, nil
+}
+
+// SearchDomain returns the breaches of every address on a verified domain.
+// The domain must already be verified with XposedOrNot for the API key.
+func (p *XposedOrNotProvider) SearchDomain(ctx context.Context, domain string) (map[string][]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/domain-breaches/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("x-api-key", p.apiKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return map[string][]string{}, nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %d from XposedOrNot", resp.StatusCode)
+	}
+
+	var apiResponse struct {
+		SendDomains struct {
+			BreachesDetails []struct {
+				Breach string `json:"breach"`
+				Domain string `json:"domain"`
+				Email  string `json:"email"`
+			} `json:"breaches_details"`
+		} `json:"sendDomains"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, err
+	}
+
+	results := map[string][]string{}
+	for _, detail := range apiResponse.SendDomains.BreachesDetails {
+		if !strings.EqualFold(detail.Domain, domain) {
+			continue
+		}
+		email := strings.ToLower(detail.Email)
+		results[email] = append(results[email], detail.Breach)
+	}
+	for email := range results {
+		sort.Strings(results[email])
+	}
+	return results, nil
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds, defaulting to a minute
+func parseRetryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Minute
+}
+
+// mockBreachNames are well-known breaches the mock provider picks from
+var mockBreachNames = []string{
+	"Adobe", "Canva", "Dropbox", "LinkedIn", "MyFitnessPal",
+	"MySpace", "Tumblr", "Twitter", "Zynga", "Dubsmash",
+}
+
+// MockBreachProvider returns stable, made-up breaches for demonstration purposes
+type MockBreachProvider struct{}
+
+// Name returns the provider name
+func (p *MockBreachProvider) Name() string {
+	return "Mock Data"
+}
+
+// Lookup returns a deterministic set of breaches derived from the identity
+func (p *MockBreachProvider) Lookup(ctx context.Context, kind string, value string) ([]string, error) {
+	h := fnv.New32a()
+	h.Write([]byte(kind + ":" + value))
+	sum := h.Sum32()
+
+	count := int(sum % 4)
+	breaches := []string{}
+	for i := 0; i < count; i++ {
+		breaches = append(breaches, mockBreachNames[(int(sum>>8)+i*3)%len(mockBreachNames)])
+	}
+	sort.Strings(breaches)
+	return breaches, nil
+}
+
+// mockMailboxes are the local parts the mock provider reports on a domain
+var mockMailboxes = []string{"admin", "info", "sales", "support", "john.smith", "mary.jones"}
+
+// SearchDomain returns mock breaches for a handful of common addresses on the domain
+func (p *MockBreachProvider) SearchDomain(ctx context.Context, domain string) (map[string][]string, error) {
+	results := map[string][]string{}
+	for _, mailbox := range mockMailboxes {
+		email := mailbox + "@" + domain
+		breaches, _ := p.Lookup(ctx, IdentityEmail, email)
+		if len(breaches) > 0 {
+			results[email] = breaches
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// TXTResolver looks up the TXT records published for a domain
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// NewTXTResolver returns a resolver configured from the environment.
+// DNS_TXT_RECORDS ("example.com=value;other.org=value") selects a static
+// resolver for local development, and DNS_RESOLVER_ADDR ("127.0.0.1:5353")
+// sends queries to a specific DNS server instead of the system resolver.
+func NewTXTResolver() TXTResolver {
+	if records := os.Getenv("DNS_TXT_RECORDS"); records != "" {
+		return ParseStaticResolver(records)
+	}
+
+	resolver := &net.Resolver{}
+	if addr := os.Getenv("DNS_RESOLVER_ADDR"); addr != "" {
+		resolver.PreferGo = true
+		resolver.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: 5 * time.Second}
+			return dialer.DialContext(ctx, network, addr)
+		}
+	}
+	return resolver
+}
+
+// HasTXTRecord reports whether a domain publishes a TXT record
+func HasTXTRecord(ctx context.Context, resolver TXTResolver, domain string, record string) (bool, error) {
+	records, err := resolver.LookupTXT(ctx, domain)
+	if err != nil {
+		return false, err
+	}
+	for _, published := range records {
+		if strings.TrimSpace(published) == record {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StaticResolver answers TXT lookups from a fixed table
+type StaticResolver map[string][]string
+
+// ParseStaticResolver builds a static resolver from "domain=value" pairs
+// separated by semicolons
+func ParseStaticResolver(records string) StaticResolver {
+	resolver := StaticResolver{}
+	for _, pair := range strings.Split(records, ";") {
+		domain, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		resolver[domain] = append(resolver[domain], strings.TrimSpace(value))
+	}
+	return resolver
+}
+
+// LookupTXT returns the records configured for a domain
+func (r StaticResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	records, ok := r[strings.ToLower(domain)]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: domain, IsNotFound: true}
+	}
+	return records, nil
+}
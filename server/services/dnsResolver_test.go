@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestHasTXTRecordWithStaticResolver(t *testing.T) {
+	t.Setenv("DNS_TXT_RECORDS", "Example.com=v=spf1 -all; example.com = safetrace-verification=abc123 ;other.org=safetrace-verification=def456")
+	resolver := NewTXTResolver()
+
+	tests := []struct {
+		name     string
+		domain   string
+		record   string
+		want     bool
+		notFound bool
+	}{
+		{"published record", "example.com", "safetrace-verification=abc123", true, false},
+		{"domain in another case", "EXAMPLE.COM", "safetrace-verification=abc123", true, false},
+		{"another domain's record", "example.com", "safetrace-verification=def456", false, false},
+		{"unknown domain", "missing.net", "safetrace-verification=abc123", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := HasTXTRecord(context.Background(), resolver, tt.domain, tt.record)
+			var dnsErr *net.DNSError
+			if tt.notFound {
+				if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+					t.Fatalf("err = %v, want a not found DNS error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HasTXTRecord: %v", err)
+			}
+			if found != tt.want {
+				t.Errorf("found = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FixtureBreachProvider answers lookups from a local JSON file, for tests and
+// offline development. The file maps each identity kind to normalized values
+// and their breaches:
+//
+//	{
+//	  "email":    {"jane@example.com": ["Adobe", "LinkedIn"]},
+//	  "phone":    {"+14155550123": ["Facebook"]},
+//	  "username": {"janedoe": ["Twitter"]}
+//	}
+type FixtureBreachProvider struct {
+	fixtures map[string]map[string][]string
+}
+
+// NewFixtureBreachProvider loads fixtures from a JSON file
+func NewFixtureBreachProvider(path string) (*FixtureBreachProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading breach fixtures: %w", err)
+	}
+
+	var raw map[string]map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing breach fixtures %s: %w", path, err)
+	}
+
+	fixtures := map[string]map[string][]string{}
+	for kind, entries := range raw {
+		if kind != IdentityEmail && kind != IdentityPhone && kind != IdentityUsername {
+			return nil, fmt.Errorf("breach fixtures %s: unknown identity kind %q", path, kind)
+		}
+		fixtures[kind] = map[string][]string{}
+		for value, breaches := range entries {
+			sorted := append([]string{}, breaches...)
+			sort.Strings(sorted)
+			fixtures[kind][strings.ToLower(value)] = sorted
+		}
+	}
+
+	return &FixtureBreachProvider{fixtures: fixtures}, nil
+}
+
+// Name returns the provider name
+func (p *FixtureBreachProvider) Name() string {
+	return "Fixtures"
+}
+
+// Lookup returns the breaches listed for the identity, or none
+func (p *FixtureBreachProvider) Lookup(ctx context.Context, kind string, value string) ([]string, error) {
+	if breaches, ok := p.fixtures[kind][strings.ToLower(value)]; ok {
+		return breaches, nil
+	}
+	return []string{}, nil
+}
+
+// SearchDomain returns the email fixtures on the domain
+func (p *FixtureBreachProvider) SearchDomain(ctx context.Context, domain string) (map[string][]string, error) {
+	results := map[string][]string{}
+	for email, breaches := range p.fixtures[IdentityEmail] {
+		if strings.HasSuffix(email, "@"+strings.ToLower(domain)) && len(breaches) > 0 {
+			results[email] = breaches
+		}
+	}
+	return results, nil
+}
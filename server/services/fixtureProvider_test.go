@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+const testFixtures = `{
+  "email": {"Jane@Example.com": ["LinkedIn", "Adobe"], "john@example.com": ["Dropbox"], "jane@other.org": ["Canva"]},
+  "phone": {"+442079460958": ["Facebook"]},
+  "username": {"janedoe": ["Twitter"]}
+}`
+
+func writeFixtures(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("writing fixtures: %v", err)
+	}
+	return path
+}
+
+func TestFixtureBreachProviderLookup(t *testing.T) {
+	t.Setenv("BREACH_FIXTURES", writeFixtures(t, testFixtures))
+	provider, err := NewBreachProvider()
+	if err != nil {
+		t.Fatalf("NewBreachProvider: %v", err)
+	}
+	if provider.Name() != "Fixtures" {
+		t.Fatalf("provider = %s, want the fixture provider", provider.Name())
+	}
+
+	// Values are looked up as the breach check endpoints normalize them
+	phone, err := utils.NormalizePhone("020 7946 0958", "GB")
+	if err != nil {
+		t.Fatalf("NormalizePhone: %v", err)
+	}
+	username, err := utils.NormalizeUsername("@JaneDoe")
+	if err != nil {
+		t.Fatalf("NormalizeUsername: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		kind  string
+		value string
+		want  []string
+	}{
+		{"email in another case, breaches sorted", IdentityEmail, "jane@example.com", []string{"Adobe", "LinkedIn"}},
+		{"normalized phone", IdentityPhone, phone.E164, []string{"Facebook"}},
+		{"normalized username", IdentityUsername, username, []string{"Twitter"}},
+		{"unknown value", IdentityEmail, "nobody@example.com", []string{}},
+		{"value under another kind", IdentityUsername, "jane@example.com", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaches, err := provider.Lookup(context.Background(), tt.kind, tt.value)
+			if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			if !reflect.DeepEqual(breaches, tt.want) {
+				t.Errorf("breaches = %v, want %v", breaches, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixtureBreachProviderSearchDomain(t *testing.T) {
+	provider, err := NewFixtureBreachProvider(writeFixtures(t, testFixtures))
+	if err != nil {
+		t.Fatalf("NewFixtureBreachProvider: %v", err)
+	}
+
+	results, err := provider.SearchDomain(context.Background(), "EXAMPLE.com")
+	if err != nil {
+		t.Fatalf("SearchDomain: %v", err)
+	}
+	want := map[string][]string{"jane@example.com": {"Adobe", "LinkedIn"}, "john@example.com": {"Dropbox"}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
+
+func TestNewFixtureBreachProviderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"invalid JSON", `{"email": [`, "parsing breach fixtures"},
+		{"unknown kind", `{"passport": {"X123": ["Leak"]}}`, `unknown identity kind "passport"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFixtureBreachProvider(writeFixtures(t, tt.contents))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := NewFixtureBreachProvider(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loading a missing file succeeded")
+	}
+}
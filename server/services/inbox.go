@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// ErrInboxNotFound is returned for inboxes that do not exist, have expired or
+// belong to another user
+var ErrInboxNotFound = errors.New("inbox not found")
+
+// ErrNoMailDomains is returned when the mail service offers no domains
+var ErrNoMailDomains = errors.New("mail service has no active domains")
+
+// InboxService provisions disposable mailboxes for fake identities and
+// deletes them once their TTL has passed
+type InboxService struct {
+	client        *mongo.Client
+	mail          *MailTMClient
+	ttl           time.Duration
+	purgeInterval time.Duration
+}
+
+// NewInboxService creates a new inbox service configured from the environment
+func NewInboxService(client *mongo.Client, mail *MailTMClient) *InboxService {
+	return &InboxService{
+		client:        client,
+		mail:          mail,
+		ttl:           utils.EnvDuration("INBOX_TTL", time.Hour),
+		purgeInterval: utils.EnvDuration("INBOX_PURGE_INTERVAL", 5*time.Minute),
+	}
+}
+
+// Create provisions a mailbox whose address starts with localPart for the
+// signed-in user userID
+func (s *InboxService) Create(ctx context.Context, userID string, localPart string) (*models.DisposableInbox, error) {
+	domains, err := s.mail.Domains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, ErrNoMailDomains
+	}
+
+	// A random suffix keeps addresses unique when the same fake name comes up
+	// again, e.g. from a fixed seed
+	address := localPart + "." + randomHex(3) + "@" + domains[0]
+	password := randomHex(16)
+
+	account, err := s.mail.CreateAccount(ctx, address, password)
+	if err != nil {
+		return nil, err
+	}
+	// From here on, failures must delete the mailbox or it would outlive
+	// every purge, which only sees inboxes in the database
+	token, err := s.mail.Token(ctx, address, password)
+	if err != nil {
+		s.discardAccount(account, password, "")
+		return nil, err
+	}
+
+	encryptedPassword, err := utils.Encrypt(password, "")
+	if err != nil {
+		s.discardAccount(account, password, token)
+		return nil, err
+	}
+	encryptedToken, err := utils.Encrypt(token, "")
+	if err != nil {
+		s.discardAccount(account, password, token)
+		return nil, err
+	}
+
+	now := time.Now()
+	inbox := &models.DisposableInbox{
+		ID:        randomHex(16),
+		UserID:    userID,
+		Address:   account.Address,
+		AccountID: account.ID,
+		Password:  encryptedPassword,
+		Token:     encryptedToken,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	collection := s.client.Database("safetrace").Collection("disposable_inboxes")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := collection.InsertOne(dbCtx, inbox); err != nil {
+		s.discardAccount(account, password, token)
+		return nil, err
+	}
+	return inbox, nil
+}
+
+// discardAccount deletes a mailbox that Create could not finish setting up,
+// logging in first if no token was obtained. It does not use the request's
+// context, which may be what made Create fail.
+func (s *InboxService) discardAccount(account *MailTMAccount, password string, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if token == "" {
+		var err error
+		if token, err = s.mail.Token(ctx, account.Address, password); err != nil {
+			log.Printf("Inboxes: failed to log in to delete %s: %v", account.Address, err)
+			return
+		}
+	}
+	if err := s.mail.DeleteAccount(ctx, token, account.ID); err != nil && err != ErrMailNotFound {
+		log.Printf("Inboxes: failed to delete %s: %v", account.Address, err)
+	}
+}
+
+// Get returns an unexpired inbox owned by userID. Any other inbox, including
+// those created anonymously before sign-in was required, is not found.
+func (s *InboxService) Get(ctx context.Context, id string, userID string) (*models.DisposableInbox, error) {
+	collection := s.client.Database("safetrace").Collection("disposable_inboxes")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var inbox models.DisposableInbox
+	filter := bson.M{"_id": id, "userId": userID, "expiresAt": bson.M{"$gt": time.Now()}}
+	if err := collection.FindOne(dbCtx, filter).Decode(&inbox); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInboxNotFound
+		}
+		return nil, err
+	}
+	return &inbox, nil
+}
+
+// Messages lists the messages that have arrived in an inbox
+func (s *InboxService) Messages(ctx context.Context, inbox *models.DisposableInbox) ([]models.InboxMessageSummary, error) {
+	var messages []models.InboxMessageSummary
+	err := s.withToken(ctx, inbox, func(token string) error {
+		var err error
+		messages, err = s.mail.Messages(ctx, token)
+		return err
+	})
+	return messages, err
+}
+
+// Message returns one message from an inbox
+func (s *InboxService) Message(ctx context.Context, inbox *models.DisposableInbox, messageID string) (*models.InboxMessage, error) {
+	var message *models.InboxMessage
+	err := s.withToken(ctx, inbox, func(token string) error {
+		var err error
+		message, err = s.mail.Message(ctx, token, messageID)
+		return err
+	})
+	return message, err
+}
+
+// Delete removes an inbox from the mail service and from the database
+func (s *InboxService) Delete(ctx context.Context, inbox *models.DisposableInbox) error {
+	if err := s.deleteAccount(ctx, inbox); err != nil {
+		return err
+	}
+
+	collection := s.client.Database("safetrace").Collection("disposable_inboxes")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := collection.DeleteOne(dbCtx, bson.M{"_id": inbox.ID})
+	return err
+}
+
+// Run deletes expired inboxes until the context is cancelled
+func (s *InboxService) Run(ctx context.Context) {
+	s.ensureIndexes(ctx)
+
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := s.Purge(ctx); err != nil {
+			log.Printf("Inboxes: purge failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Inboxes: deleted %d expired inboxes", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes every expired inbox. Inboxes the mail service fails to
+// delete are kept and retried on the next purge.
+func (s *InboxService) Purge(ctx context.Context) (int, error) {
+	collection := s.client.Database("safetrace").Collection("disposable_inboxes")
+	dbCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	cursor, err := collection.Find(dbCtx, bson.M{"expiresAt": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	var expired []models.DisposableInbox
+	if err := cursor.All(dbCtx, &expired); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for i := range expired {
+		if err := s.Delete(dbCtx, &expired[i]); err != nil {
+			log.Printf("Inboxes: failed to delete %s: %v", expired[i].Address, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// TTL returns how long inboxes are kept
+func (s *InboxService) TTL() time.Duration {
+	return s.ttl
+}
+
+// withToken calls fn with the inbox's token, logging in again if the token
+// has expired
+func (s *InboxService) withToken(ctx context.Context, inbox *models.DisposableInbox, fn func(token string) error) error {
+	token, err := utils.Decrypt(inbox.Token, "")
+	if err != nil {
+		return err
+	}
+	if err := fn(token); err != ErrMailUnauthorized {
+		return err
+	}
+
+	password, err := utils.Decrypt(inbox.Password, "")
+	if err != nil {
+		return err
+	}
+	if token, err = s.mail.Token(ctx, inbox.Address, password); err != nil {
+		return err
+	}
+
+	if encrypted, err := utils.Encrypt(token, ""); err == nil {
+		inbox.Token = encrypted
+		collection := s.client.Database("safetrace").Collection("disposable_inboxes")
+		dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if _, err := collection.UpdateOne(dbCtx, bson.M{"_id": inbox.ID}, bson.M{"$set": bson.M{"token": encrypted}}); err != nil {
+			log.Printf("Inboxes: failed to save refreshed token: %v", err)
+		}
+	}
+	return fn(token)
+}
+
+// deleteAccount deletes the mailbox on the mail service. A mailbox that is
+// already gone, so its credentials no longer work, counts as deleted.
+func (s *InboxService) deleteAccount(ctx context.Context, inbox *models.DisposableInbox) error {
+	err := s.withToken(ctx, inbox, func(token string) error {
+		return s.mail.DeleteAccount(ctx, token, inbox.AccountID)
+	})
+	if err == ErrMailNotFound || err == ErrMailUnauthorized {
+		return nil
+	}
+	return err
+}
+
+func (s *InboxService) ensureIndexes(ctx context.Context) {
+	collection := s.client.Database("safetrace").Collection("disposable_inboxes")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := collection.Indexes().CreateOne(dbCtx, mongo.IndexModel{Keys: bson.M{"expiresAt": 1}}); err != nil {
+		log.Printf("Inboxes: failed to create indexes: %v", err)
+	}
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// InboxLocalPart turns a fake email address into a mailbox name, keeping only
+// characters every mail service accepts
+func InboxLocalPart(email string) string {
+	local := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+	var b strings.Builder
+	for _, r := range local {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	if local = strings.Trim(b.String(), "."); local == "" {
+		return "inbox"
+	}
+	return local
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeMailAPI is an in-memory Mail.tm-compatible API
+type fakeMailAPI struct {
+	mu sync.Mutex
+	// accounts maps account IDs to passwords
+	accounts map[string]string
+	// addresses maps addresses to account IDs
+	addresses map[string]string
+	// tokens maps bearer tokens to account IDs
+	tokens map[string]string
+	// tokenFailures is how many logins fail before they succeed again
+	tokenFailures int
+	// hydra wraps collections in JSON-LD, as the public service does
+	hydra bool
+}
+
+func newFakeMailAPI(t *testing.T) (*fakeMailAPI, *MailTMClient) {
+	t.Helper()
+	api := &fakeMailAPI{accounts: map[string]string{}, addresses: map[string]string{}, tokens: map[string]string{}}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, &MailTMClient{baseURL: server.URL, client: server.Client()}
+}
+
+func (f *fakeMailAPI) accountCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.accounts)
+}
+
+func (f *fakeMailAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var credentials struct {
+		Address  string `json:"address"`
+		Password string `json:"password"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&credentials)
+	}
+	account := f.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/domains":
+		f.writeCollection(w, []map[string]interface{}{
+			{"domain": "inactive.test", "isActive": false},
+			{"domain": "mail.test", "isActive": true},
+		})
+	case r.Method == "POST" && r.URL.Path == "/accounts":
+		id := randomHex(8)
+		f.accounts[id] = credentials.Password
+		f.addresses[credentials.Address] = id
+		json.NewEncoder(w).Encode(map[string]string{"id": id, "address": credentials.Address})
+	case r.Method == "POST" && r.URL.Path == "/token":
+		id, ok := f.addresses[credentials.Address]
+		if f.tokenFailures > 0 {
+			f.tokenFailures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok || f.accounts[id] != credentials.Password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := randomHex(8)
+		f.tokens[token] = id
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	case r.Method == "GET" && r.URL.Path == "/messages":
+		if account == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.writeCollection(w, []map[string]interface{}{{"id": "m1", "subject": "Welcome"}})
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/accounts/"):
+		id := strings.TrimPrefix(r.URL.Path, "/accounts/")
+		if account == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if account != id {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.accounts, id)
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/slow-down":
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeMailAPI) writeCollection(w http.ResponseWriter, members interface{}) {
+	if f.hydra {
+		json.NewEncoder(w).Encode(map[string]interface{}{"hydra:member": members})
+		return
+	}
+	json.NewEncoder(w).Encode(members)
+}
+
+func TestMailTMClient(t *testing.T) {
+	for _, hydra := range []bool{false, true} {
+		api, client := newFakeMailAPI(t)
+		api.hydra = hydra
+		ctx := context.Background()
+
+		domains, err := client.Domains(ctx)
+		if err != nil || len(domains) != 1 || domains[0] != "mail.test" {
+			t.Fatalf("hydra=%v: Domains = %v, %v", hydra, domains, err)
+		}
+
+		account, err := client.CreateAccount(ctx, "jane@mail.test", "secret")
+		if err != nil {
+			t.Fatalf("hydra=%v: CreateAccount: %v", hydra, err)
+		}
+		token, err := client.Token(ctx, "jane@mail.test", "secret")
+		if err != nil {
+			t.Fatalf("hydra=%v: Token: %v", hydra, err)
+		}
+		messages, err := client.Messages(ctx, token)
+		if err != nil || len(messages) != 1 || messages[0].Subject != "Welcome" {
+			t.Fatalf("hydra=%v: Messages = %v, %v", hydra, messages, err)
+		}
+		if err := client.DeleteAccount(ctx, token, account.ID); err != nil {
+			t.Fatalf("hydra=%v: DeleteAccount: %v", hydra, err)
+		}
+	}
+}
+
+func TestMailTMClientErrors(t *testing.T) {
+	_, client := newFakeMailAPI(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want func(error) bool
+	}{
+		{"wrong password", func() error {
+			_, err := client.Token(ctx, "nobody@mail.test", "wrong")
+			return err
+		}, func(err error) bool { return err == ErrMailUnauthorized }},
+		{"missing token", func() error {
+			_, err := client.Messages(ctx, "")
+			return err
+		}, func(err error) bool { return err == ErrMailUnauthorized }},
+		{"unknown message", func() error {
+			_, err := client.Message(ctx, "", "missing")
+			return err
+		}, func(err error) bool { return err == ErrMailNotFound }},
+		{"rate limited", func() error {
+			return client.do(ctx, "GET", "/slow-down", "", nil, nil)
+		}, func(err error) bool {
+			var limited *RateLimitError
+			return errors.As(err, &limited) && limited.RetryAfter == 7*time.Second
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !tt.want(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestInboxCreateDeletesAccountOnFailure(t *testing.T) {
+	// Nothing listens on port 1, so every database write fails quickly
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		tokenFailures int
+		wantAccounts  int
+	}{
+		{"database write fails", 0, 0},
+		{"first login fails", 1, 0},
+		{"every login fails", 100, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, mail := newFakeMailAPI(t)
+			api.tokenFailures = tt.tokenFailures
+			inboxes := &InboxService{client: client, mail: mail, ttl: time.Hour}
+
+			if _, err := inboxes.Create(context.Background(), "user-1", "jane.doe"); err == nil {
+				t.Fatal("Create succeeded")
+			}
+			if got := api.accountCount(); got != tt.wantAccounts {
+				t.Errorf("%d accounts left on the mail service, want %d", got, tt.wantAccounts)
+			}
+		})
+	}
+}
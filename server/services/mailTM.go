@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// Errors returned by the Mail.tm client
+var (
+	ErrMailUnauthorized = errors.New("mail service rejected the mailbox credentials")
+	ErrMailNotFound     = errors.New("not found on mail service")
+)
+
+// MailTMClient talks to a Mail.tm-compatible disposable mail API
+type MailTMClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// MailTMAccount is a mailbox created on the mail service
+type MailTMAccount struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// NewMailTMClient creates a client for the API at MAILTM_BASE_URL, defaulting
+// to the public Mail.tm service
+func NewMailTMClient() *MailTMClient {
+	baseURL := os.Getenv("MAILTM_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.mail.tm"
+	}
+	return &MailTMClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Domains returns the active domains mailboxes can be created on
+func (c *MailTMClient) Domains(ctx context.Context) ([]string, error) {
+	var domains []struct {
+		Domain   string `json:"domain"`
+		IsActive bool   `json:"isActive"`
+	}
+	if err := c.do(ctx, "GET", "/domains", "", nil, &domains); err != nil {
+		return nil, err
+	}
+
+	var active []string
+	for _, domain := range domains {
+		if domain.IsActive {
+			active = append(active, domain.Domain)
+		}
+	}
+	return active, nil
+}
+
+// CreateAccount creates a mailbox with the given address and password
+func (c *MailTMClient) CreateAccount(ctx context.Context, address string, password string) (*MailTMAccount, error) {
+	var account MailTMAccount
+	body := map[string]string{"address": address, "password": password}
+	if err := c.do(ctx, "POST", "/accounts", "", body, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Token logs in to a mailbox and returns its bearer token
+func (c *MailTMClient) Token(ctx context.Context, address string, password string) (string, error) {
+	var response struct {
+		Token string `json:"token"`
+	}
+	body := map[string]string{"address": address, "password": password}
+	if err := c.do(ctx, "POST", "/token", "", body, &response); err != nil {
+		return "", err
+	}
+	return response.Token, nil
+}
+
+// Messages lists the newest messages in the mailbox
+func (c *MailTMClient) Messages(ctx context.Context, token string) ([]models.InboxMessageSummary, error) {
+	messages := []models.InboxMessageSummary{}
+	if err := c.do(ctx, "GET", "/messages?page=1", token, nil, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Message returns a complete message
+func (c *MailTMClient) Message(ctx context.Context, token string, id string) (*models.InboxMessage, error) {
+	var message models.InboxMessage
+	if err := c.do(ctx, "GET", "/messages/"+url.PathEscape(id), token, nil, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// DeleteAccount deletes a mailbox and everything in it
+func (c *MailTMClient) DeleteAccount(ctx context.Context, token string, id string) error {
+	return c.do(ctx, "DELETE", "/accounts/"+url.PathEscape(id), token, nil, nil)
+}
+
+// do sends a request and decodes the JSON response into out, if given
+func (c *MailTMClient) do(ctx context.Context, method string, path string, token string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return ErrMailUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrMailNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("unexpected status %d from mail service", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return decodeMailResponse(data, out)
+}
+
+// decodeMailResponse decodes a response body. Collections come back as plain
+// arrays or, from services answering in JSON-LD, wrapped in hydra:member.
+func decodeMailResponse(data []byte, out interface{}) error {
+	if reflect.TypeOf(out).Elem().Kind() == reflect.Slice && bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		var collection struct {
+			Members json.RawMessage `json:"hydra:member"`
+		}
+		if err := json.Unmarshal(data, &collection); err != nil {
+			return err
+		}
+		data = collection.Members
+	}
+	return json.Unmarshal(data, out)
+}
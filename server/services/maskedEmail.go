@@ -0,0 +1,449 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// Masked alias statuses
+const (
+	AliasActive   = "active"   // mail is forwarded
+	AliasBlocked  = "blocked"  // mail is accepted and dropped, so senders cannot tell
+	AliasDisabled = "disabled" // mail is refused
+)
+
+// droppedForwardHeaders are removed from forwarded mail because they describe
+// the original delivery or would fail verification once From is rewritten
+var droppedForwardHeaders = map[string]bool{
+	"From":           true,
+	"Sender":         true,
+	"Return-Path":    true,
+	"Delivered-To":   true,
+	"Dkim-Signature": true,
+}
+
+// MaskedEmailService receives mail for users' permanent aliases and forwards
+// it to their real addresses through an SMTP relay
+type MaskedEmailService struct {
+	client    *mongo.Client
+	addr      string
+	domain    string
+	maxSize   int
+	relay     SMTPConfig
+	verifyURL string
+	verifyTTL time.Duration
+}
+
+// NewMaskedEmailService creates the alias service configured from the
+// environment, or returns nil when ALIAS_DOMAIN is not set. The relay
+// defaults to the SMTP_* settings used for alert emails.
+func NewMaskedEmailService(client *mongo.Client) *MaskedEmailService {
+	domain := strings.ToLower(strings.TrimSpace(os.Getenv("ALIAS_DOMAIN")))
+	if domain == "" {
+		return nil
+	}
+
+	addr := os.Getenv("ALIAS_SMTP_ADDR")
+	if addr == "" {
+		addr = ":2526"
+	}
+
+	relay := SMTPConfig{
+		Host:     os.Getenv("ALIAS_RELAY_HOST"),
+		Port:     os.Getenv("ALIAS_RELAY_PORT"),
+		Username: os.Getenv("ALIAS_RELAY_USERNAME"),
+		Password: os.Getenv("ALIAS_RELAY_PASSWORD"),
+		From:     os.Getenv("ALIAS_RELAY_FROM"),
+	}
+	if relay.Host == "" {
+		relay.Host = os.Getenv("SMTP_HOST")
+		relay.Port = os.Getenv("SMTP_PORT")
+		relay.Username = os.Getenv("SMTP_USERNAME")
+		relay.Password = os.Getenv("SMTP_PASSWORD")
+	}
+	if relay.Port == "" {
+		relay.Port = "587"
+	}
+	if relay.From == "" {
+		// Bounces from the real mailbox come back to us rather than telling
+		// the original sender anything about it
+		relay.From = "forwarder@" + domain
+	}
+
+	verifyURL := os.Getenv("ALIAS_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = "http://localhost:8080/api/alias-verification"
+	}
+
+	return &MaskedEmailService{
+		client:    client,
+		addr:      addr,
+		domain:    domain,
+		maxSize:   utils.EnvInt("ALIAS_MAX_SIZE", 25<<20),
+		relay:     relay,
+		verifyURL: verifyURL,
+		verifyTTL: utils.EnvDuration("ALIAS_VERIFY_TTL", 48*time.Hour),
+	}
+}
+
+// Domain returns the domain aliases are created on
+func (s *MaskedEmailService) Domain() string {
+	return s.domain
+}
+
+// Create stores a new alias, picking an unused address built from its site
+func (s *MaskedEmailService) Create(ctx context.Context, alias *models.MaskedAlias) error {
+	collection := s.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	prefix := aliasPrefix(alias.Site)
+	for attempt := 0; ; attempt++ {
+		alias.Address = prefix + "." + randomHex(4) + "@" + s.domain
+		result, err := collection.InsertOne(dbCtx, alias)
+		if err == nil {
+			alias.ID = result.InsertedID.(primitive.ObjectID)
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt == 4 {
+			return err
+		}
+	}
+}
+
+// SendVerification pauses forwarding for an alias until its forwarding
+// address is confirmed, and mails a verification link to that address. An
+// address the user already verified for another alias is trusted at once.
+func (s *MaskedEmailService) SendVerification(ctx context.Context, alias *models.MaskedAlias) error {
+	collection := s.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	alias.ForwardVerified, alias.VerifyTokenHash, alias.VerifySentAt = false, "", nil
+	verified, err := collection.CountDocuments(dbCtx, bson.M{
+		"_id":             bson.M{"$ne": alias.ID},
+		"userId":          alias.UserID,
+		"forwardTo":       alias.ForwardTo,
+		"forwardVerified": true,
+	})
+	if err != nil {
+		return err
+	}
+	if verified > 0 {
+		alias.ForwardVerified = true
+		update := bson.M{
+			"$set":   bson.M{"forwardVerified": true},
+			"$unset": bson.M{"verifyTokenHash": "", "verifySentAt": ""},
+		}
+		_, err := collection.UpdateOne(dbCtx, bson.M{"_id": alias.ID}, update)
+		return err
+	}
+
+	if s.relay.Host == "" {
+		return errors.New("no relay configured")
+	}
+	token := randomHex(32)
+	sentAt := time.Now()
+	update := bson.M{"$set": bson.M{"forwardVerified": false, "verifyTokenHash": hashToken(token), "verifySentAt": sentAt}}
+	if _, err := collection.UpdateOne(dbCtx, bson.M{"_id": alias.ID}, update); err != nil {
+		return err
+	}
+
+	if err := s.send(alias.ForwardTo, s.verificationMessage(alias, token)); err != nil {
+		return err
+	}
+	alias.VerifyTokenHash, alias.VerifySentAt = hashToken(token), &sentAt
+	return nil
+}
+
+// Verify confirms the forwarding address whose verification link carried
+// token and returns its alias, or nil if the token is unknown or expired
+func (s *MaskedEmailService) Verify(ctx context.Context, token string) (*models.MaskedAlias, error) {
+	collection := s.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"verifyTokenHash": hashToken(token),
+		"verifySentAt":    bson.M{"$gt": time.Now().Add(-s.verifyTTL)},
+	}
+	update := bson.M{
+		"$set":   bson.M{"forwardVerified": true, "updatedAt": time.Now()},
+		"$unset": bson.M{"verifyTokenHash": "", "verifySentAt": ""},
+	}
+	var alias models.MaskedAlias
+	err := collection.FindOneAndUpdate(dbCtx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&alias)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// verificationMessage builds the email asking the owner of a forwarding
+// address to confirm it
+func (s *MaskedEmailService) verificationMessage(alias *models.MaskedAlias, token string) []byte {
+	link := s.verifyURL + "?token=" + url.QueryEscape(token)
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", s.relay.From)
+	fmt.Fprintf(&message, "To: %s\r\n", (&mail.Address{Address: alias.ForwardTo}).String())
+	fmt.Fprintf(&message, "Subject: Confirm forwarding for %s\r\n", alias.Address)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&message, "Hello,\r\n\r\nA SafeTrace user wants mail sent to %s forwarded to this address.\r\n", alias.Address)
+	fmt.Fprintf(&message, "Open this link within %s to confirm:\r\n\r\n%s\r\n\r\n", s.verifyTTL, link)
+	message.WriteString("If you did not ask for this, ignore this email and nothing will be forwarded.\r\n\r\n- SafeTrace\r\n")
+	return message.Bytes()
+}
+
+// send delivers a message to one recipient through the relay
+func (s *MaskedEmailService) send(to string, message []byte) error {
+	var auth smtp.Auth
+	if s.relay.Username != "" {
+		auth = smtp.PlainAuth("", s.relay.Username, s.relay.Password, s.relay.Host)
+	}
+	addr := net.JoinHostPort(s.relay.Host, s.relay.Port)
+	return smtp.SendMail(addr, auth, s.relay.From, []string{to}, message)
+}
+
+// Run receives mail for aliases until the context is cancelled
+func (s *MaskedEmailService) Run(ctx context.Context) {
+	s.ensureIndexes(ctx)
+	if s.relay.Host == "" {
+		log.Printf("Aliases: no relay configured, set ALIAS_RELAY_HOST or SMTP_HOST to forward mail")
+	}
+
+	server := &smtpServer{
+		name:     "Aliases",
+		hostname: s.domain,
+		addr:     s.addr,
+		maxSize:  s.maxSize,
+		// One recipient per transaction, so a relay failure for one alias
+		// never makes the sender retry mail that was already forwarded
+		maxRecipients: 1,
+		accept:        s.accept,
+		deliver:       s.deliver,
+	}
+	server.Run(ctx)
+}
+
+// accept refuses mail for unknown and disabled aliases, and defers mail for
+// aliases whose forwarding address is not verified yet, so senders retry
+// once it is
+func (s *MaskedEmailService) accept(from string, recipient string) error {
+	alias, err := s.lookup(recipient)
+	if err != nil {
+		return err
+	}
+	if alias.Status == AliasDisabled {
+		s.record(alias, from, "stats.rejected")
+		return newSMTPReply(550, "5.1.1 Mailbox unavailable")
+	}
+	if alias.Status == AliasActive && !alias.ForwardVerified {
+		return newSMTPReply(450, "4.2.1 Mailbox not ready, try again later")
+	}
+	return nil
+}
+
+// deliver forwards a message to the alias's real address, or drops it if
+// the alias is blocked
+func (s *MaskedEmailService) deliver(session smtpSession, raw []byte) error {
+	alias, err := s.lookup(session.recipients[0])
+	if err != nil {
+		return err
+	}
+
+	switch alias.Status {
+	case AliasDisabled:
+		s.record(alias, session.from, "stats.rejected")
+		return newSMTPReply(550, "5.1.1 Mailbox unavailable")
+	case AliasBlocked:
+		s.record(alias, session.from, "stats.blocked")
+		return nil
+	}
+
+	if !alias.ForwardVerified {
+		return newSMTPReply(450, "4.2.1 Mailbox not ready, try again later")
+	}
+	if s.relay.Host == "" {
+		return errors.New("no relay configured")
+	}
+	forwarded, err := rewriteForwardedHeaders(raw, alias.Address)
+	if err != nil {
+		return newSMTPReply(554, "5.6.0 Malformed message headers")
+	}
+
+	if err := s.send(alias.ForwardTo, forwarded); err != nil {
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+			log.Printf("Aliases: relay refused mail for %s: %v", alias.Address, err)
+			return newSMTPReply(554, "5.0.0 Message could not be forwarded")
+		}
+		return err
+	}
+
+	s.record(alias, session.from, "stats.forwarded")
+	return nil
+}
+
+// lookup returns the alias for a recipient address, or an SMTP reply
+// refusing it
+func (s *MaskedEmailService) lookup(recipient string) (*models.MaskedAlias, error) {
+	local := localPart(recipient, s.domain)
+	if local == "" {
+		return nil, newSMTPReply(550, "5.7.1 Relaying denied, only @%s is accepted", s.domain)
+	}
+
+	collection := s.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var alias models.MaskedAlias
+	err := collection.FindOne(dbCtx, bson.M{"address": local + "@" + s.domain}).Decode(&alias)
+	if err == mongo.ErrNoDocuments {
+		return nil, newSMTPReply(550, "5.1.1 No such user")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// record counts a message in one of the alias's statistics
+func (s *MaskedEmailService) record(alias *models.MaskedAlias, sender string, counter string) {
+	collection := s.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$inc": bson.M{counter: 1},
+		"$set": bson.M{"stats.lastReceivedAt": time.Now(), "stats.lastSender": sender},
+	}
+	if _, err := collection.UpdateOne(dbCtx, bson.M{"_id": alias.ID}, update); err != nil {
+		log.Printf("Aliases: failed to update stats for %s: %v", alias.Address, err)
+	}
+}
+
+func (s *MaskedEmailService) ensureIndexes(ctx context.Context) {
+	collection := s.client.Database("safetrace").Collection("masked_aliases")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(dbCtx, []mongo.IndexModel{
+		{Keys: bson.M{"address": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.M{"verifyTokenHash": 1}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		log.Printf("Aliases: failed to create indexes: %v", err)
+	}
+}
+
+// rewriteForwardedHeaders makes a message come from the alias, with replies
+// going to the original sender. Everything below the headers is left
+// untouched.
+func rewriteForwardedHeaders(raw []byte, alias string) ([]byte, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	headerEnd, bodyStart := len(raw), len(raw)
+	if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		headerEnd, bodyStart = i+1, i+2
+	}
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 && i+2 < headerEnd {
+		headerEnd, bodyStart = i+2, i+4
+	}
+
+	var out bytes.Buffer
+	keep := false
+	for _, line := range strings.SplitAfter(string(raw[:headerEnd]), "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			name, _, _ := strings.Cut(line, ":")
+			keep = !droppedForwardHeaders[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))]
+		}
+		if keep {
+			out.WriteString(strings.TrimRight(line, "\r\n") + "\r\n")
+		}
+	}
+
+	from := &mail.Address{Address: alias}
+	if senders, err := message.Header.AddressList("From"); err == nil && len(senders) > 0 {
+		sender := senders[0]
+		from.Name = sender.Address
+		if sender.Name != "" {
+			from.Name = sender.Name + " (" + sender.Address + ")"
+		}
+		if message.Header.Get("Reply-To") == "" {
+			out.WriteString("Reply-To: " + sender.String() + "\r\n")
+		}
+	}
+	out.WriteString("From: " + from.String() + "\r\n")
+	out.WriteString("X-SafeTrace-Alias: " + alias + "\r\n")
+	out.WriteString("\r\n")
+	out.Write(raw[bodyStart:])
+	return out.Bytes(), nil
+}
+
+// hashToken returns the hex SHA-256 of a verification token, which is all
+// that is stored of it
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// aliasPrefix turns a site name or URL into the start of an alias address,
+// e.g. "https://www.github.com/login" becomes "github"
+func aliasPrefix(site string) string {
+	site = strings.ToLower(strings.TrimSpace(site))
+	if parsed, err := url.Parse(site); err == nil && parsed.Host != "" {
+		site = parsed.Hostname()
+	}
+	site = strings.TrimPrefix(site, "www.")
+	if dot := strings.IndexByte(site, '.'); dot > 0 {
+		site = site[:dot]
+	}
+
+	var b strings.Builder
+	for _, r := range site {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+		if b.Len() == 20 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "alias"
+	}
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// maxMIMEDepth bounds how deeply multipart bodies are followed
+const maxMIMEDepth = 10
+
+// MIMEMessage is the readable content of a parsed email
+type MIMEMessage struct {
+	From        string
+	To          []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []models.SinkAttachment
+}
+
+// headerDecoder decodes RFC 2047 encoded words in any charset we know
+var headerDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// ParseMIMEMessage parses a raw RFC 5322 message, taking the first plain text
+// and HTML bodies and collecting every other part as an attachment
+func ParseMIMEMessage(raw []byte) (*MIMEMessage, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &MIMEMessage{
+		From:    decodeHeader(msg.Header.Get("From")),
+		Subject: decodeHeader(msg.Header.Get("Subject")),
+	}
+	for _, header := range []string{"To", "Cc"} {
+		if addresses, err := msg.Header.AddressList(header); err == nil {
+			for _, address := range addresses {
+				if address.Name == "" {
+					parsed.To = append(parsed.To, address.Address)
+				} else {
+					parsed.To = append(parsed.To, address.String())
+				}
+			}
+		}
+	}
+
+	if err := parsed.addPart(msg.Header, msg.Body, 0); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// partHeader is satisfied by both message and multipart part headers
+type partHeader interface {
+	Get(key string) string
+}
+
+// addPart adds one MIME part, descending into multipart bodies
+func (m *MIMEMessage) addPart(header partHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth {
+			return fmt.Errorf("message is nested more than %d levels deep", maxMIMEDepth)
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := m.addPart(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(transferDecoder(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	isBody := disposition != "attachment" && filename == ""
+	switch {
+	case isBody && mediaType == "text/plain" && m.Text == "":
+		m.Text = decodeCharset(params["charset"], content)
+	case isBody && mediaType == "text/html" && m.HTML == "":
+		m.HTML = decodeCharset(params["charset"], content)
+	default:
+		m.Attachments = append(m.Attachments, models.SinkAttachment{
+			ID:          strconv.Itoa(len(m.Attachments) + 1),
+			Filename:    filename,
+			ContentType: mediaType,
+			ContentID:   strings.Trim(header.Get("Content-ID"), "<>"),
+			Inline:      disposition == "inline",
+			Size:        len(content),
+			Data:        content,
+		})
+	}
+	return nil
+}
+
+// transferDecoder undoes a Content-Transfer-Encoding
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// newlineStripper drops the line breaks base64 bodies are wrapped with
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// decodeCharset converts text in the given charset to UTF-8, leaving it as is
+// when the charset is unknown
+func decodeCharset(charset string, content []byte) string {
+	if charset == "" || strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "us-ascii") {
+		return string(content)
+	}
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(content)
+	}
+	decoded, err := encoding.NewDecoder().Bytes(content)
+	if err != nil {
+		return string(content)
+	}
+	return string(decoded)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
+
+// decodeHeader decodes RFC 2047 encoded words, keeping the raw value if it
+// cannot be decoded
+func decodeHeader(value string) string {
+	decoded, err := headerDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// MonitorScheduler periodically re-checks monitored identities against a
+// breach provider and records an alert when a new breach appears. Work is
+// claimed through leases stored on each identity so that several server
+// instances can share the same collection.
+type MonitorScheduler struct {
+	client     *mongo.Client
+	provider   BreachProvider
+	notifier   *Notifier
+	instanceID string
+
+	interval     time.Duration // time between checks of the same identity
+	jitter       time.Duration // random spread added to interval
+	leaseTTL     time.Duration // how long a claimed identity stays reserved
+	pollInterval time.Duration // how often to look for due identities
+	minGap       time.Duration // minimum spacing between provider calls
+
+	mu           sync.Mutex
+	rng          *rand.Rand
+	blockedUntil time.Time
+}
+
+// NewMonitorScheduler creates a new monitor scheduler configured from the
+// environment. New alerts are passed to notifier when it is not nil.
+func NewMonitorScheduler(client *mongo.Client, provider BreachProvider, notifier *Notifier) *MonitorScheduler {
+	hostname, _ := os.Hostname()
+	ratePerMinute := utils.EnvInt("MONITOR_RATE_PER_MINUTE", 10)
+
+	return &MonitorScheduler{
+		client:       client,
+		provider:     provider,
+		notifier:     notifier,
+		instanceID:   fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		interval:     utils.EnvDuration("MONITOR_INTERVAL", 24*time.Hour),
+		jitter:       utils.EnvDuration("MONITOR_JITTER", time.Hour),
+		leaseTTL:     utils.EnvDuration("MONITOR_LEASE_TTL", 5*time.Minute),
+		pollInterval: utils.EnvDuration("MONITOR_POLL_INTERVAL", time.Minute),
+		minGap:       time.Minute / time.Duration(ratePerMinute),
+		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Run processes due identities until the context is cancelled
+func (s *MonitorScheduler) Run(ctx context.Context) {
+	s.ensureIndexes(ctx)
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NextCheck returns when an identity checked now should be checked again
+func (s *MonitorScheduler) NextCheck(now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := now.Add(s.interval)
+	if s.jitter > 0 {
+		next = next.Add(time.Duration(s.rng.Int63n(int64(s.jitter))))
+	}
+	return next
+}
+
+// drain checks due identities one at a time, respecting the provider rate
+func (s *MonitorScheduler) drain(ctx context.Context) {
+	for {
+		if wait := time.Until(s.blockedUntil); wait > 0 {
+			log.Printf("Monitor: provider rate limited, pausing for %s", wait.Round(time.Second))
+			return
+		}
+
+		identity, err := s.claim(ctx)
+		if err == mongo.ErrNoDocuments {
+			return
+		}
+		if err != nil {
+			log.Printf("Monitor: failed to claim identity: %v", err)
+			return
+		}
+
+		s.check(ctx, identity)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.minGap):
+		}
+	}
+}
+
+// claim leases the most overdue identity that no other instance holds
+func (s *MonitorScheduler) claim(ctx context.Context) (*models.MonitoredIdentity, error) {
+	collection := s.client.Database("safetrace").Collection("monitored_identities")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"nextCheckAt":    bson.M{"$lte": now},
+		"leaseExpiresAt": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"leaseOwner":     s.instanceID,
+			"leaseExpiresAt": now.Add(s.leaseTTL),
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextCheckAt": 1}).
+		SetReturnDocument(options.After)
+
+	var identity models.MonitoredIdentity
+	if err := collection.FindOneAndUpdate(dbCtx, filter, update, opts).Decode(&identity); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// check looks up a claimed identity, stores the result and releases the lease
+func (s *MonitorScheduler) check(ctx context.Context, identity *models.MonitoredIdentity) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	breaches, err := s.provider.Lookup(lookupCtx, identity.Kind, identity.Value)
+	cancel()
+
+	now := time.Now()
+	if err != nil {
+		s.recordFailure(ctx, identity, now, err)
+		return
+	}
+
+	newBreaches := diffBreaches(identity.KnownBreaches, breaches)
+	known := mergeBreaches(identity.KnownBreaches, breaches)
+
+	// The first successful check only records a baseline
+	if !identity.LastCheckedAt.IsZero() && len(newBreaches) > 0 {
+		alert := models.BreachAlert{
+			UserID:      identity.UserID,
+			IdentityID:  identity.ID,
+			Kind:        identity.Kind,
+			Value:       identity.Value,
+			NewBreaches: newBreaches,
+			TotalCount:  len(known),
+			Severity:    utils.SeverityLevel(len(known)),
+			Source:      s.provider.Name(),
+			CreatedAt:   now,
+		}
+		if err := s.createAlert(ctx, alert); err != nil {
+			// Leave the known set untouched so the alert is raised on the next run
+			s.recordFailure(ctx, identity, now, err)
+			return
+		}
+	}
+
+	s.release(ctx, identity, bson.M{
+		"knownBreaches": known,
+		"lastCheckedAt": now,
+		"nextCheckAt":   s.NextCheck(now),
+		"failureCount":  0,
+		"lastError":     "",
+	})
+}
+
+// recordFailure backs off an identity after a failed check
+func (s *MonitorScheduler) recordFailure(ctx context.Context, identity *models.MonitoredIdentity, now time.Time, err error) {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		s.blockedUntil = now.Add(rateLimited.RetryAfter)
+	}
+	if errors.Is(err, ErrUnsupportedIdentity) {
+		// Nothing will change until the provider changes, so wait a full interval
+		s.release(ctx, identity, bson.M{
+			"lastError":   err.Error(),
+			"nextCheckAt": s.NextCheck(now),
+		})
+		return
+	}
+
+	failures := identity.FailureCount + 1
+	backoff := time.Minute << uint(minInt(failures, 10))
+	if backoff > s.interval {
+		backoff = s.interval
+	}
+
+	log.Printf("Monitor: check of identity %s failed: %v", identity.ID.Hex(), err)
+	s.release(ctx, identity, bson.M{
+		"failureCount": failures,
+		"lastError":    err.Error(),
+		"nextCheckAt":  now.Add(backoff),
+	})
+}
+
+// release stores the given fields and gives up the lease, provided this
+// instance still holds it
+func (s *MonitorScheduler) release(ctx context.Context, identity *models.MonitoredIdentity, fields bson.M) {
+	collection := s.client.Database("safetrace").Collection("monitored_identities")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	fields["leaseOwner"] = ""
+	fields["leaseExpiresAt"] = time.Time{}
+	fields["updatedAt"] = time.Now()
+
+	filter := bson.M{"_id": identity.ID, "leaseOwner": s.instanceID}
+	if _, err := collection.UpdateOne(dbCtx, filter, bson.M{"$set": fields}); err != nil {
+		log.Printf("Monitor: failed to update identity %s: %v", identity.ID.Hex(), err)
+	}
+}
+
+// createAlert stores a breach alert and hands it to the notifier
+func (s *MonitorScheduler) createAlert(ctx context.Context, alert models.BreachAlert) error {
+	collection := s.client.Database("safetrace").Collection("breach_alerts")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(dbCtx, alert)
+	if err != nil {
+		return err
+	}
+	alert.ID = result.InsertedID.(primitive.ObjectID)
+
+	// Deliveries retry with backoff, so keep them off the scheduling loop
+	if s.notifier != nil {
+		go s.notifier.Notify(ctx, alert)
+	}
+	return nil
+}
+
+// ensureIndexes creates the indexes the scheduler and monitor endpoints rely on
+func (s *MonitorScheduler) ensureIndexes(ctx context.Context) {
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	db := s.client.Database("safetrace")
+	_, err := db.Collection("monitored_identities").Indexes().CreateMany(dbCtx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "nextCheckAt", Value: 1}, {Key: "leaseExpiresAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "kind", Value: 1}, {Key: "value", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Printf("Monitor: failed to create identity indexes: %v", err)
+	}
+
+	_, err = db.Collection("breach_alerts").Indexes().CreateOne(dbCtx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("Monitor: failed to create alert indexes: %v", err)
+	}
+}
+
+// diffBreaches returns the breaches in current that are not in known
+func diffBreaches(known []string, current []string) []string {
+	seen := make(map[string]bool, len(known))
+	for _, name := range known {
+		seen[name] = true
+	}
+
+	added := []string{}
+	for _, name := range current {
+		if !seen[name] {
+			added = append(added, name)
+			seen[name] = true
+		}
+	}
+	return added
+}
+
+// mergeBreaches returns the sorted union of two breach lists
+func mergeBreaches(known []string, current []string) []string {
+	merged := append([]string{}, known...)
+	merged = append(merged, diffBreaches(known, current)...)
+	sort.Strings(merged)
+	return merged
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// Notification channel types
+const (
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+// Headers sent with every webhook delivery. The signature is the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the channel secret.
+const (
+	SignatureHeader = "X-SafeTrace-Signature"
+	TimestampHeader = "X-SafeTrace-Timestamp"
+	EventHeader     = "X-SafeTrace-Event"
+)
+
+// errPermanent marks a delivery failure that retrying will not fix
+var errPermanent = errors.New("permanent delivery failure")
+
+// ErrChannelUnverified is returned when delivering to an email channel whose
+// address has not been verified
+var ErrChannelUnverified = fmt.Errorf("%w: email address is not verified", errPermanent)
+
+var emailSubjectTemplate = template.Must(template.New("subject").Parse(
+	`SafeTrace alert: {{len .NewBreaches}} new breach{{if ne (len .NewBreaches) 1}}es{{end}} for {{.Value}}`))
+
+var emailBodyTemplate = template.Must(template.New("body").Parse(`Hello,
+
+SafeTrace found new breaches for the {{.Kind}} {{.Value}} that you are monitoring.
+
+New breaches:
+{{range .NewBreaches}}  - {{.}}
+{{end}}
+Known breaches in total: {{.TotalCount}}
+Severity: {{.Severity}}
+Source: {{.Source}}
+Detected at: {{.CreatedAt.Format "2006-01-02 15:04 MST"}}
+
+Change the passwords of the affected accounts and enable two-factor
+authentication wherever it is available.
+
+- SafeTrace
+`))
+
+var digestSubjectTemplate = template.Must(template.New("digestSubject").Parse(
+	`SafeTrace weekly risk digest: score {{.Score}}{{if .PreviousScore}} ({{if ge .Delta 0}}+{{end}}{{.Delta}}){{end}}`))
+
+var digestBodyTemplate = template.Must(template.New("digestBody").Parse(`Hello,
+
+Here is how your privacy risk changed in the week of {{.PeriodStart.Format "2 January 2006"}}.
+
+Risk score: {{.Score}} ({{.RiskLevel}}){{if .PreviousScore}}, {{if gt .Delta 0}}up from{{else if lt .Delta 0}}down from{{else}}unchanged from{{end}} {{.PreviousScore}}{{end}}
+{{if .LevelChange}}Risk level: {{.LevelChange}}
+{{end}}Analyses this week: {{.Assessments}}
+{{if .Resolved}}
+Resolved:
+{{range .Resolved}}  - {{.}}
+{{end}}{{end}}{{if .Appeared}}
+New risk factors:
+{{range .Appeared}}  - {{.}}
+{{end}}{{end}}{{if .Factors}}
+Current risk factors:
+{{range .Factors}}  - {{.}}
+{{end}}{{end}}
+- SafeTrace
+`))
+
+var verificationSubjectTemplate = template.Must(template.New("verificationSubject").Parse(
+	`Confirm SafeTrace alerts for {{.Email}}`))
+
+var verificationBodyTemplate = template.Must(template.New("verificationBody").Parse(`Hello,
+
+A SafeTrace user wants breach alerts and risk digests sent to {{.Email}}.
+Open this link within {{.TTL}} to confirm:
+
+{{.Link}}
+
+If you did not ask for this, ignore this email and nothing will be sent.
+
+- SafeTrace
+`))
+
+// SMTPConfig holds the settings used to send alert emails
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Notifier delivers breach alerts to the notification channels users have configured
+type Notifier struct {
+	client      *mongo.Client
+	httpClient  *http.Client
+	smtp        SMTPConfig
+	maxAttempts int
+	baseBackoff time.Duration
+	// allowPrivate lets webhooks reach loopback and private addresses, for
+	// local development only
+	allowPrivate bool
+	verifyURL    string        // page email channel verification links open
+	verifyTTL    time.Duration // how long a verification link stays valid
+}
+
+// NewNotifier creates a new notifier configured from the environment
+func NewNotifier(client *mongo.Client) *Notifier {
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "alerts@safetrace.local"
+	}
+
+	allowPrivate := utils.EnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+	httpClient := newPublicHTTPClient(10 * time.Second)
+	if allowPrivate {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	verifyURL := os.Getenv("NOTIFY_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = "http://localhost:8080/api/notification-verification"
+	}
+
+	return &Notifier{
+		client:     client,
+		httpClient: httpClient,
+		smtp: SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		},
+		maxAttempts:  utils.EnvInt("NOTIFY_MAX_ATTEMPTS", 5),
+		baseBackoff:  utils.EnvDuration("NOTIFY_BASE_BACKOFF", 2*time.Second),
+		allowPrivate: allowPrivate,
+		verifyURL:    verifyURL,
+		verifyTTL:    utils.EnvDuration("NOTIFY_VERIFY_TTL", 48*time.Hour),
+	}
+}
+
+// CheckWebhookURL fails if a webhook URL's host resolves to an address that
+// webhooks may not reach
+func (n *Notifier) CheckWebhookURL(ctx context.Context, rawURL string) error {
+	if n.allowPrivate {
+		return nil
+	}
+	return CheckPublicURL(ctx, rawURL)
+}
+
+// Notify delivers an alert to every enabled channel of its user whose
+// minimum severity the alert meets. Deliveries that keep failing are stored
+// as dead letters.
+func (n *Notifier) Notify(ctx context.Context, alert models.BreachAlert) {
+	channels, ok := n.enabledChannels(ctx, alert.UserID)
+	if !ok {
+		return
+	}
+
+	for _, channel := range channels {
+		if utils.SeverityRank(alert.Severity) < utils.SeverityRank(channel.MinSeverity) {
+			continue
+		}
+
+		attempts, err := n.Deliver(ctx, channel, alert)
+		if err != nil {
+			log.Printf("Notifier: delivery to channel %s failed: %v", channel.ID.Hex(), err)
+			n.storeDeadLetter(ctx, channel, alert, attempts, err)
+		}
+	}
+}
+
+// NotifyDigest delivers a weekly risk digest to every enabled channel of its
+// user. Digests are informational, so failed deliveries are only logged.
+func (n *Notifier) NotifyDigest(ctx context.Context, digest models.RiskDigest) {
+	channels, ok := n.enabledChannels(ctx, digest.UserID)
+	if !ok {
+		return
+	}
+
+	for _, channel := range channels {
+		channel := channel
+		_, err := n.retry(ctx, func() error {
+			switch channel.Type {
+			case ChannelWebhook:
+				return n.sendWebhook(ctx, channel, "risk.digest", "digest", digest)
+			case ChannelEmail:
+				return n.sendTemplates(channel, digestSubjectTemplate, digestBodyTemplate, digest)
+			}
+			return fmt.Errorf("%w: unknown channel type %q", errPermanent, channel.Type)
+		})
+		if err != nil {
+			log.Printf("Notifier: digest delivery to channel %s failed: %v", channel.ID.Hex(), err)
+		}
+	}
+}
+
+// enabledChannels returns a user's enabled channels, leaving out email
+// channels that are not verified. It logs failures and returns false.
+func (n *Notifier) enabledChannels(ctx context.Context, userID string) ([]models.NotificationChannel, bool) {
+	collection := n.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"userId":  userID,
+		"enabled": true,
+		"$or":     bson.A{bson.M{"type": bson.M{"$ne": ChannelEmail}}, bson.M{"emailVerified": true}},
+	}
+	cursor, err := collection.Find(dbCtx, filter)
+	if err != nil {
+		log.Printf("Notifier: failed to fetch channels for user %s: %v", userID, err)
+		return nil, false
+	}
+
+	var channels []models.NotificationChannel
+	if err := cursor.All(dbCtx, &channels); err != nil {
+		log.Printf("Notifier: failed to decode channels for user %s: %v", userID, err)
+		return nil, false
+	}
+	return channels, true
+}
+
+// Deliver sends an alert to a single channel, retrying transient failures
+// with exponential backoff. It returns the number of attempts made, and
+// ErrChannelUnverified without trying for an unverified email channel.
+func (n *Notifier) Deliver(ctx context.Context, channel models.NotificationChannel, alert models.BreachAlert) (int, error) {
+	if channel.Type == ChannelEmail && !channel.EmailVerified {
+		return 0, ErrChannelUnverified
+	}
+	return n.retry(ctx, func() error {
+		switch channel.Type {
+		case ChannelWebhook:
+			return n.sendWebhook(ctx, channel, "breach.detected", "alert", alert)
+		case ChannelEmail:
+			return n.sendTemplates(channel, emailSubjectTemplate, emailBodyTemplate, alert)
+		}
+		return fmt.Errorf("%w: unknown channel type %q", errPermanent, channel.Type)
+	})
+}
+
+// retry calls send until it succeeds, fails permanently or runs out of
+// attempts, backing off exponentially. It returns the number of attempts made.
+func (n *Notifier) retry(ctx context.Context, send func() error) (int, error) {
+	backoff := n.baseBackoff
+	var err error
+
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		err = send()
+
+		if err == nil || errors.Is(err, errPermanent) || attempt == n.maxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return n.maxAttempts, err
+}
+
+// RetryDeadLetter attempts a dead letter again and removes it on success
+func (n *Notifier) RetryDeadLetter(ctx context.Context, deadLetter models.DeadLetter) error {
+	db := n.client.Database("safetrace")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var channel models.NotificationChannel
+	filter := bson.M{"_id": deadLetter.ChannelID, "userId": deadLetter.UserID}
+	if err := db.Collection("notification_channels").FindOne(dbCtx, filter).Decode(&channel); err != nil {
+		return err
+	}
+
+	attempts, err := n.Deliver(ctx, channel, deadLetter.Alert)
+	if err != nil {
+		update := bson.M{
+			"$inc": bson.M{"attempts": attempts},
+			"$set": bson.M{"lastError": err.Error()},
+		}
+		db.Collection("dead_letters").UpdateOne(dbCtx, bson.M{"_id": deadLetter.ID}, update)
+		return err
+	}
+
+	_, err = db.Collection("dead_letters").DeleteOne(dbCtx, bson.M{"_id": deadLetter.ID})
+	return err
+}
+
+// sendWebhook posts a signed JSON payload for an event to the channel URL,
+// with the data under key
+func (n *Notifier) sendWebhook(ctx context.Context, channel models.NotificationChannel, event string, key string, data interface{}) error {
+	secret, err := utils.Decrypt(channel.Secret, "")
+	if err != nil {
+		return fmt.Errorf("%w: unable to decrypt webhook secret", errPermanent)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":        primitive.NewObjectID().Hex(),
+		"event":     event,
+		"createdAt": time.Now().UTC(),
+		key:         data,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, "POST", channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SafeTrace-Webhook/1.0")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+SignPayload(secret, timestamp, body))
+
+	resp, err := n.httpClient.Do(req)
+	if errors.Is(err, ErrNonPublicAddress) {
+		// The error names the resolved address, which users must not see
+		return fmt.Errorf("%w: webhook host does not resolve to a public address", errPermanent)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: webhook returned status %d", errPermanent, resp.StatusCode)
+	}
+}
+
+// sendTemplates renders the subject and body templates with data and sends
+// them over SMTP
+func (n *Notifier) sendTemplates(channel models.NotificationChannel, subjectTemplate *template.Template, bodyTemplate *template.Template, data interface{}) error {
+	if n.smtp.Host == "" {
+		return fmt.Errorf("%w: SMTP_HOST is not configured", errPermanent)
+	}
+	// Channels saved before addresses were stored bare may still have a
+	// display name, which must not reach the RCPT command
+	to, err := mail.ParseAddress(channel.Email)
+	if err != nil {
+		return fmt.Errorf("%w: invalid email address", errPermanent)
+	}
+
+	var subject, body bytes.Buffer
+	if err := subjectTemplate.Execute(&subject, data); err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	if err := bodyTemplate.Execute(&body, data); err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", n.smtp.From)
+	fmt.Fprintf(&message, "To: %s\r\n", (&mail.Address{Address: to.Address}).String())
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject.String())
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if n.smtp.Username != "" {
+		auth = smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host)
+	}
+
+	addr := net.JoinHostPort(n.smtp.Host, n.smtp.Port)
+	return smtp.SendMail(addr, auth, n.smtp.From, []string{to.Address}, message.Bytes())
+}
+
+// SendVerification pauses an email channel until its address is confirmed,
+// and mails a verification link to that address. An address the user already
+// verified for another channel is trusted at once.
+func (n *Notifier) SendVerification(ctx context.Context, channel *models.NotificationChannel) error {
+	collection := n.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	channel.EmailVerified, channel.VerifyTokenHash, channel.VerifySentAt = false, "", nil
+	verified, err := collection.CountDocuments(dbCtx, bson.M{
+		"_id":           bson.M{"$ne": channel.ID},
+		"userId":        channel.UserID,
+		"type":          ChannelEmail,
+		"email":         channel.Email,
+		"emailVerified": true,
+	})
+	if err != nil {
+		return err
+	}
+	if verified > 0 {
+		channel.EmailVerified = true
+		update := bson.M{
+			"$set":   bson.M{"emailVerified": true},
+			"$unset": bson.M{"verifyTokenHash": "", "verifySentAt": ""},
+		}
+		_, err := collection.UpdateOne(dbCtx, bson.M{"_id": channel.ID}, update)
+		return err
+	}
+
+	if n.smtp.Host == "" {
+		return errors.New("SMTP_HOST is not configured")
+	}
+	token := randomHex(32)
+	sentAt := time.Now()
+	update := bson.M{"$set": bson.M{"emailVerified": false, "verifyTokenHash": hashToken(token), "verifySentAt": sentAt}}
+	if _, err := collection.UpdateOne(dbCtx, bson.M{"_id": channel.ID}, update); err != nil {
+		return err
+	}
+
+	data := struct {
+		Email string
+		Link  string
+		TTL   time.Duration
+	}{channel.Email, n.verifyURL + "?token=" + url.QueryEscape(token), n.verifyTTL}
+	if err := n.sendTemplates(*channel, verificationSubjectTemplate, verificationBodyTemplate, data); err != nil {
+		return err
+	}
+	channel.VerifyTokenHash, channel.VerifySentAt = hashToken(token), &sentAt
+	return nil
+}
+
+// VerifyChannel confirms the email channel whose verification link carried
+// token and returns it, or nil if the token is unknown or expired
+func (n *Notifier) VerifyChannel(ctx context.Context, token string) (*models.NotificationChannel, error) {
+	collection := n.client.Database("safetrace").Collection("notification_channels")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"verifyTokenHash": hashToken(token),
+		"verifySentAt":    bson.M{"$gt": time.Now().Add(-n.verifyTTL)},
+	}
+	update := bson.M{
+		"$set":   bson.M{"emailVerified": true, "updatedAt": time.Now()},
+		"$unset": bson.M{"verifyTokenHash": "", "verifySentAt": ""},
+	}
+	var channel models.NotificationChannel
+	err := collection.FindOneAndUpdate(dbCtx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&channel)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// storeDeadLetter records an alert that could not be delivered
+func (n *Notifier) storeDeadLetter(ctx context.Context, channel models.NotificationChannel, alert models.BreachAlert, attempts int, err error) {
+	collection := n.client.Database("safetrace").Collection("dead_letters")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	deadLetter := models.DeadLetter{
+		UserID:    channel.UserID,
+		ChannelID: channel.ID,
+		Alert:     alert,
+		Attempts:  attempts,
+		LastError: err.Error(),
+		CreatedAt: time.Now(),
+	}
+	if _, err := collection.InsertOne(dbCtx, deadLetter); err != nil {
+		log.Printf("Notifier: failed to store dead letter: %v", err)
+	}
+}
+
+// SignPayload returns the hex encoded webhook signature for a timestamp and body
+func SignPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
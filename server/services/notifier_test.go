@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+func TestNotifierEmailUsesBareAddress(t *testing.T) {
+	addr, next := startSMTPSink(t)
+	host, port, _ := net.SplitHostPort(addr)
+	notifier := &Notifier{
+		smtp:        SMTPConfig{Host: host, Port: port, From: "alerts@safetrace.local"},
+		maxAttempts: 1,
+	}
+
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{"bare address", "jane@example.com", "jane@example.com"},
+		{"display name", "Jane Doe <jane@example.com>", "jane@example.com"},
+		{"quoted display name", `"Doe, Jane" <jane.doe@example.com>`, "jane.doe@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := models.NotificationChannel{Type: ChannelEmail, Email: tt.email, EmailVerified: true}
+			alert := models.BreachAlert{
+				Kind:        "email",
+				Value:       "jane@example.com",
+				NewBreaches: []string{"Adobe"},
+				TotalCount:  1,
+				Severity:    "high",
+			}
+			if _, err := notifier.Deliver(context.Background(), channel, alert); err != nil {
+				t.Fatalf("Deliver: %v", err)
+			}
+
+			message := next()
+			if len(message.recipients) != 1 || message.recipients[0] != tt.want {
+				t.Errorf("recipients = %q, want [%q]", message.recipients, tt.want)
+			}
+			if message.from != "alerts@safetrace.local" {
+				t.Errorf("from = %q", message.from)
+			}
+
+			parsed, err := mail.ReadMessage(strings.NewReader(string(message.raw)))
+			if err != nil {
+				t.Fatalf("read message: %v", err)
+			}
+			if got := parsed.Header.Get("To"); got != "<"+tt.want+">" {
+				t.Errorf("To header = %q, want %q", got, "<"+tt.want+">")
+			}
+			if parsed.Header.Get("Subject") == "" {
+				t.Error("Subject header is empty")
+			}
+		})
+	}
+}
+
+func TestNotifierEmailRejectsInvalidAddress(t *testing.T) {
+	notifier := &Notifier{smtp: SMTPConfig{Host: "127.0.0.1", Port: "25"}, maxAttempts: 1}
+	channel := models.NotificationChannel{Type: ChannelEmail, Email: "not an address", EmailVerified: true}
+	if _, err := notifier.Deliver(context.Background(), channel, models.BreachAlert{}); err == nil {
+		t.Fatal("Deliver succeeded for an invalid address")
+	}
+}
+
+func TestNotifierSkipsUnverifiedEmail(t *testing.T) {
+	// Nothing listens here, so any attempt to send would fail differently
+	notifier := &Notifier{smtp: SMTPConfig{Host: "127.0.0.1", Port: "1"}, maxAttempts: 3}
+	channel := models.NotificationChannel{Type: ChannelEmail, Email: "stranger@example.com"}
+
+	attempts, err := notifier.Deliver(context.Background(), channel, models.BreachAlert{})
+	if !errors.Is(err, ErrChannelUnverified) || attempts != 0 {
+		t.Fatalf("Deliver = %d, %v; want no attempts and ErrChannelUnverified", attempts, err)
+	}
+}
+
+func TestNotifierVerificationEmail(t *testing.T) {
+	addr, next := startSMTPSink(t)
+	host, port, _ := net.SplitHostPort(addr)
+	notifier := &Notifier{smtp: SMTPConfig{Host: host, Port: port, From: "alerts@safetrace.local"}}
+
+	data := struct {
+		Email string
+		Link  string
+		TTL   time.Duration
+	}{"jane@example.com", "https://safetrace.test/api/notification-verification?token=abc", 48 * time.Hour}
+	channel := models.NotificationChannel{Type: ChannelEmail, Email: "jane@example.com"}
+	if err := notifier.sendTemplates(channel, verificationSubjectTemplate, verificationBodyTemplate, data); err != nil {
+		t.Fatalf("sendTemplates: %v", err)
+	}
+
+	message := next()
+	if len(message.recipients) != 1 || message.recipients[0] != "jane@example.com" {
+		t.Errorf("recipients = %q", message.recipients)
+	}
+	if body := string(message.raw); !strings.Contains(body, data.Link) || !strings.Contains(body, "48h0m0s") {
+		t.Errorf("message lacks the link or its lifetime:\n%s", body)
+	}
+}
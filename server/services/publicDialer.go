@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned for webhook hosts that resolve to loopback,
+// private, link-local or other reserved addresses
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// reservedBlocks are ranges that are not reachable on the internet but that
+// net.IP's predicates do not cover
+var reservedBlocks = mustParseCIDRs(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"198.18.0.0/15",   // benchmarking
+	"240.0.0.0/4",     // reserved, including broadcast
+	"64:ff9b::/96",    // NAT64, which can reach private IPv4 addresses
+	"64:ff9b:1::/48",  // local-use NAT64
+	"2001:db8::/32",   // documentation
+	"fec0::/10",       // deprecated site-local
+	"100::/64",        // discard-only
+	"2001::/23",       // IETF protocol assignments
+	"::ffff:0:0:0/96", // IPv4-translated
+)
+
+// IsPublicIP reports whether ip is a unicast address reachable on the
+// internet
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, block := range reservedBlocks {
+		if block.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPublicURL resolves the host of a URL and fails unless every address
+// it has is public
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(lookupCtx, parsed.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("host %s could not be resolved", parsed.Hostname())
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("host %s: %w", parsed.Hostname(), ErrNonPublicAddress)
+		}
+	}
+	return nil
+}
+
+// newPublicHTTPClient returns a client that can only connect to public
+// addresses. The check is made on the address actually dialled, so it also
+// holds for redirects and for hosts whose DNS changes after they were saved.
+// Proxies are not used, since they would dial on the client's behalf.
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return ErrNonPublicAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	blocks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, block, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		blocks[i] = block
+	}
+	return blocks
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimitPolicy is a token bucket: Burst requests can be made at once, and
+// tokens refill at Rate per second
+type RateLimitPolicy struct {
+	Rate  float64
+	Burst int
+}
+
+// Window returns how long an empty bucket takes to refill completely
+func (p RateLimitPolicy) Window() time.Duration {
+	return time.Duration(float64(p.Burst) / p.Rate * float64(time.Second))
+}
+
+// ParseRateLimitPolicy parses a policy such as "30/m" or "30/m:10", where the
+// optional number after the colon is the burst size (default: the count).
+// Units are s, m, h and d.
+func ParseRateLimitPolicy(value string) (RateLimitPolicy, error) {
+	rate, burst, hasBurst := strings.Cut(strings.TrimSpace(value), ":")
+	countText, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf("rate limit %q must look like 30/m", value)
+	}
+
+	count, err := strconv.Atoi(countText)
+	if err != nil || count <= 0 {
+		return RateLimitPolicy{}, fmt.Errorf("rate limit %q has an invalid count", value)
+	}
+
+	periods := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+	period, ok := periods[unit]
+	if !ok {
+		return RateLimitPolicy{}, fmt.Errorf("rate limit %q has an unknown unit %q", value, unit)
+	}
+
+	policy := RateLimitPolicy{Rate: float64(count) / period.Seconds(), Burst: count}
+	if hasBurst {
+		if policy.Burst, err = strconv.Atoi(burst); err != nil || policy.Burst <= 0 {
+			return RateLimitPolicy{}, fmt.Errorf("rate limit %q has an invalid burst", value)
+		}
+	}
+	return policy, nil
+}
+
+// RouteLimits are the policies applied to one group of routes. Every request
+// takes a token from its client IP's bucket, and authenticated requests also
+// take one from their user's bucket, so IP caps everything from one address.
+type RouteLimits struct {
+	User RateLimitPolicy
+	IP   RateLimitPolicy
+}
+
+// RouteLimitsFromEnv returns def overridden by RATE_LIMIT_<NAME>, for example
+// RATE_LIMIT_BREACH_CHECK_EMAIL="user=60/m:10;ip=10/m" for the
+// breach_check_email route
+func RouteLimitsFromEnv(name string, def RouteLimits) (RouteLimits, error) {
+	variable := "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	config := strings.TrimSpace(os.Getenv(variable))
+	if config == "" {
+		return def, nil
+	}
+
+	limits := def
+	for _, entry := range strings.Split(config, ";") {
+		scope, value, _ := strings.Cut(entry, "=")
+		policy, err := ParseRateLimitPolicy(value)
+		if err != nil {
+			return def, fmt.Errorf("%s: %w", variable, err)
+		}
+		switch strings.TrimSpace(scope) {
+		case "user":
+			limits.User = policy
+		case "ip":
+			limits.IP = policy
+		default:
+			return def, fmt.Errorf("%s: unknown scope %q, expected user or ip", variable, scope)
+		}
+	}
+	return limits, nil
+}
+
+// RateLimitDecision is the outcome of taking a token from a bucket
+type RateLimitDecision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // until a token is available, when not allowed
+	Reset      time.Duration // until the bucket is full again
+}
+
+// RateLimitStore holds token buckets by key
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitDecision, error)
+}
+
+// NewRateLimitStore returns the store selected by RATE_LIMIT_BACKEND, either
+// "memory" (the default) or "mongo" to share limits between instances
+func NewRateLimitStore(client *mongo.Client) RateLimitStore {
+	if os.Getenv("RATE_LIMIT_BACKEND") == "mongo" {
+		return NewMongoRateLimitStore(client)
+	}
+	return NewMemoryRateLimitStore()
+}
+
+// decide builds the decision for a bucket left with tokens after a take
+func decide(allowed bool, tokens float64, policy RateLimitPolicy) RateLimitDecision {
+	decision := RateLimitDecision{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(policy.Burst) - tokens) / policy.Rate * float64(time.Second)),
+	}
+	if !allowed {
+		decision.RetryAfter = time.Duration((1 - tokens) / policy.Rate * float64(time.Second))
+	}
+	return decision
+}
+
+// MemoryRateLimitStore keeps token buckets in process memory
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// NewMemoryRateLimitStore creates a new in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take removes a token from the bucket for key if one is available
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(policy.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens = math.Min(float64(policy.Burst), bucket.tokens+elapsed*policy.Rate)
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	decision := decide(allowed, bucket.tokens, policy)
+	bucket.fullAt = now.Add(decision.Reset)
+	return decision, nil
+}
+
+// sweep drops buckets that have refilled, since they hold no state, at most
+// once a minute
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.After(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// MongoRateLimitStore keeps token buckets in MongoDB so every server instance
+// enforces the same limits. Each take is a single atomic update, and buckets
+// are removed by a TTL index once they have refilled.
+type MongoRateLimitStore struct {
+	client    *mongo.Client
+	indexOnce sync.Once
+}
+
+// NewMongoRateLimitStore creates a new MongoDB backed store
+func NewMongoRateLimitStore(client *mongo.Client) *MongoRateLimitStore {
+	return &MongoRateLimitStore{
+		client: client,
+	}
+}
+
+// Take removes a token from the bucket for key if one is available
+func (s *MongoRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitDecision, error) {
+	collection := s.client.Database("safetrace").Collection("rate_limits")
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s.indexOnce.Do(func() {
+		_, err := collection.Indexes().CreateOne(dbCtx, mongo.IndexModel{
+			Keys:    bson.M{"expiresAt": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		})
+		if err != nil {
+			log.Printf("Rate limit: failed to create TTL index: %v", err)
+		}
+	})
+
+	// Refill by the time elapsed since the last update, then take a token if
+	// a whole one is available, all on the server and by the server's clock so
+	// that instances with skewed clocks agree
+	burst := float64(policy.Burst)
+	elapsedSeconds := bson.M{"$divide": bson.A{
+		bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updatedAt", "$$NOW"}}}}, 1000,
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+			bson.M{"$ifNull": bson.A{"$tokens", burst}},
+			bson.M{"$multiply": bson.A{elapsedSeconds, policy.Rate}},
+		}}}}}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":    bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updatedAt": "$$NOW",
+			"expiresAt": bson.M{"$add": bson.A{"$$NOW", policy.Window().Milliseconds()}},
+		}}},
+	}
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := collection.FindOneAndUpdate(dbCtx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket); err != nil {
+		return RateLimitDecision{}, err
+	}
+
+	return decide(bucket.Allowed, bucket.Tokens, policy), nil
+}
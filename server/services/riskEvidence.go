@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/risk"
+	"github.com/siddhantgureja/safetrace/strength"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// minStrongPasswordScore is the lowest strength score that counts as strong
+const minStrongPasswordScore = 3
+
+// RiskEvidence gathers verified facts for risk analysis from breach lookups,
+// the user's vault and their monitored identities, so the score need not rest
+// on the user's answers
+type RiskEvidence struct {
+	client     *mongo.Client
+	lookup     *BreachLookup
+	emailRules map[string]utils.EmailProviderRule
+}
+
+// NewRiskEvidence creates a new risk evidence gatherer
+func NewRiskEvidence(client *mongo.Client, lookup *BreachLookup) *RiskEvidence {
+	return &RiskEvidence{
+		client:     client,
+		lookup:     lookup,
+		emailRules: utils.EmailProviderRules(),
+	}
+}
+
+// passwordHealth summarizes the passwords in a vault
+type passwordHealth struct {
+	total, weak, reused int
+}
+
+// monitorStatus summarizes a user's monitored identities
+type monitorStatus struct {
+	identities int
+	checked    int // identities checked at least once
+	breaches   []string
+	openAlerts int64
+}
+
+// Gather returns what can be verified about a user. Breaches are looked up for
+// email when it is given, and the vault and monitored identities are read when
+// userID is. A source that fails or has nothing to say is left out, so the
+// user's answers fill in for it.
+func (e *RiskEvidence) Gather(ctx context.Context, userID string, email string) risk.Facts {
+	var (
+		wg          sync.WaitGroup
+		emailResult *BreachResult
+		health      *passwordHealth
+		status      *monitorStatus
+	)
+
+	normalized, err := utils.NormalizeEmail(email, e.emailRules)
+	if email != "" && err == nil {
+		email = normalized.Canonical
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			emailResult = e.lookupEmail(ctx, email)
+		}()
+	}
+	if userID != "" {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			health = e.passwordHealth(userID)
+		}()
+		go func() {
+			defer wg.Done()
+			status = e.monitorStatus(userID)
+		}()
+	}
+	wg.Wait()
+
+	facts := risk.Facts{}
+
+	// Breaches found for the email and for monitored identities count together
+	var breaches, notes []string
+	if emailResult != nil {
+		breaches = append(breaches, emailResult.Breaches...)
+		notes = append(notes, breachNote(email, emailResult.Breaches, emailResult.Source))
+	}
+	if status != nil && status.checked > 0 {
+		breaches = append(breaches, status.breaches...)
+		notes = append(notes, breachNote(plural(status.checked, "monitored identity", "monitored identities"), status.breaches, ""))
+	}
+	if len(notes) > 0 {
+		breaches = uniqueSorted(breaches)
+		evidence := strings.Join(notes, "; ")
+		facts["hasDataBreaches"] = risk.Fact{Value: len(breaches) > 0, Verified: true, Evidence: evidence}
+		facts["breachCount"] = risk.Fact{Value: float64(len(breaches)), Verified: true, Evidence: evidence}
+	}
+
+	if health != nil && health.total > 0 {
+		var evidence string
+		if health.weak == 0 && health.reused == 0 {
+			evidence = fmt.Sprintf("all %s in your vault are strong and unique", plural(health.total, "password", "passwords"))
+		} else {
+			evidence = fmt.Sprintf("%d weak and %d reused of %s in your vault", health.weak, health.reused, plural(health.total, "password", "passwords"))
+		}
+		facts["hasStrongPasswords"] = risk.Fact{Value: health.weak == 0 && health.reused == 0, Verified: true, Evidence: evidence}
+		facts["vaultPasswords"] = risk.Fact{Value: float64(health.total), Verified: true, Evidence: evidence}
+		facts["weakPasswords"] = risk.Fact{Value: float64(health.weak), Verified: true, Evidence: evidence}
+		facts["reusedPasswords"] = risk.Fact{Value: float64(health.reused), Verified: true, Evidence: evidence}
+	}
+
+	if status != nil {
+		facts["monitoredIdentities"] = risk.Fact{
+			Value:    float64(status.identities),
+			Verified: true,
+			Evidence: plural(status.identities, "identity", "identities") + " monitored for new breaches",
+		}
+		facts["openAlerts"] = risk.Fact{
+			Value:    float64(status.openAlerts),
+			Verified: true,
+			Evidence: plural(int(status.openAlerts), "unacknowledged breach alert", "unacknowledged breach alerts"),
+		}
+	}
+
+	return facts
+}
+
+// lookupEmail returns the breaches for a normalized email, or nil if the
+// lookup fails
+func (e *RiskEvidence) lookupEmail(ctx context.Context, email string) *BreachResult {
+	result, err := e.lookup.Lookup(ctx, IdentityEmail, email, e.lookup.TTL())
+	if err != nil {
+		if !errors.Is(err, ErrUnsupportedIdentity) {
+			log.Printf("Risk: breach lookup failed, using the answer given: %v", err)
+		}
+		return nil
+	}
+	return result
+}
+
+// passwordHealth rates the passwords in the user's vault, or returns nil if
+// the vault cannot be read
+func (e *RiskEvidence) passwordHealth(userID string) *passwordHealth {
+	collection := e.client.Database("safetrace").Collection("vault")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(dbCtx, bson.M{"userId": userID, "type": "password"})
+	if err != nil {
+		log.Printf("Risk: failed to read vault: %v", err)
+		return nil
+	}
+	var items []models.VaultItem
+	if err := cursor.All(dbCtx, &items); err != nil {
+		log.Printf("Risk: failed to decode vault items: %v", err)
+		return nil
+	}
+
+	health := &passwordHealth{}
+	uses := map[string]int{}
+	var passwords []string
+	for _, item := range items {
+		password := item.Data["password"]
+		if item.Encrypted && password != "" {
+			if password, err = utils.Decrypt(password, ""); err != nil {
+				continue
+			}
+		}
+		if password == "" {
+			continue
+		}
+
+		health.total++
+		if strength.Estimate(password, []string{item.Data["username"], item.Title}).Score < minStrongPasswordScore {
+			health.weak++
+		}
+		uses[password]++
+		passwords = append(passwords, password)
+	}
+	for _, password := range passwords {
+		if uses[password] > 1 {
+			health.reused++
+		}
+	}
+	return health
+}
+
+// monitorStatus reads the user's monitored identities and open alerts, or
+// returns nil if they cannot be read
+func (e *RiskEvidence) monitorStatus(userID string) *monitorStatus {
+	database := e.client.Database("safetrace")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := database.Collection("monitored_identities").Find(dbCtx, bson.M{"userId": userID})
+	if err != nil {
+		log.Printf("Risk: failed to read monitored identities: %v", err)
+		return nil
+	}
+	var identities []models.MonitoredIdentity
+	if err := cursor.All(dbCtx, &identities); err != nil {
+		log.Printf("Risk: failed to decode monitored identities: %v", err)
+		return nil
+	}
+
+	status := &monitorStatus{identities: len(identities)}
+	for _, identity := range identities {
+		if !identity.LastCheckedAt.IsZero() {
+			status.checked++
+			status.breaches = append(status.breaches, identity.KnownBreaches...)
+		}
+	}
+
+	status.openAlerts, err = database.Collection("breach_alerts").CountDocuments(dbCtx, bson.M{"userId": userID, "acknowledged": false})
+	if err != nil {
+		log.Printf("Risk: failed to count breach alerts: %v", err)
+		return nil
+	}
+	return status
+}
+
+// breachNote describes the breaches found for an identity
+func breachNote(identity string, breaches []string, source string) string {
+	breaches = uniqueSorted(breaches)
+	note := "no breaches found for " + identity
+	if len(breaches) > 0 {
+		note = fmt.Sprintf("%s found in %s: %s", identity, plural(len(breaches), "breach", "breaches"), strings.Join(breaches, ", "))
+	}
+	if source != "" {
+		note += " (" + source + ")"
+	}
+	return note
+}
+
+func uniqueSorted(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func plural(n int, one string, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/risk"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// RiskHistory records signed-in users' risk assessments, summarizes each
+// week's changes in a digest and purges assessments past the retention period.
+// Factors are stored as rule IDs and shown with the current policy's labels.
+type RiskHistory struct {
+	client       *mongo.Client
+	policies     *risk.PolicyStore
+	notifier     *Notifier
+	deliveries   chan models.RiskDigest // digests waiting for the delivery worker
+	retention    time.Duration
+	pollInterval time.Duration // how often to purge and look for digests due
+}
+
+// NewRiskHistory creates a new risk history configured from the environment.
+// Factor labels come from the policy held by policies, and digests are
+// delivered through notifier when it is not nil.
+func NewRiskHistory(client *mongo.Client, policies *risk.PolicyStore, notifier *Notifier) *RiskHistory {
+	return &RiskHistory{
+		client:       client,
+		policies:     policies,
+		notifier:     notifier,
+		deliveries:   make(chan models.RiskDigest, digestQueueSize),
+		retention:    utils.EnvDuration("RISK_HISTORY_RETENTION", 365*24*time.Hour),
+		pollInterval: utils.EnvDuration("RISK_DIGEST_POLL_INTERVAL", time.Hour),
+	}
+}
+
+// digestQueueSize is how many digests may wait for delivery before creating
+// more waits for the worker
+const digestQueueSize = 100
+
+// Record stores an assessment. Failures are logged, since history must never
+// fail the analysis itself.
+func (h *RiskHistory) Record(assessment models.RiskAssessment) {
+	collection := h.client.Database("safetrace").Collection("risk_assessments")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if assessment.Factors == nil {
+		assessment.Factors = []string{}
+	}
+	if _, err := collection.InsertOne(dbCtx, assessment); err != nil {
+		log.Printf("Risk history: failed to record assessment: %v", err)
+	}
+}
+
+// Trend returns a user's latest assessments since a time, at most limit, with
+// how each changed from the one before
+func (h *RiskHistory) Trend(ctx context.Context, userID string, since time.Time, limit int) (models.RiskHistoryResponse, error) {
+	collection := h.client.Database("safetrace").Collection("risk_assessments")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"userId": userID}
+	if !since.IsZero() {
+		filter["createdAt"] = bson.M{"$gte": since}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(dbCtx, filter, opts)
+	if err != nil {
+		return models.RiskHistoryResponse{}, err
+	}
+	var assessments []models.RiskAssessment
+	if err := cursor.All(dbCtx, &assessments); err != nil {
+		return models.RiskHistoryResponse{}, err
+	}
+
+	response := models.RiskHistoryResponse{Points: []models.RiskHistoryPoint{}, Appeared: []string{}, Resolved: []string{}}
+	if len(assessments) == 0 {
+		return response, nil
+	}
+
+	// Oldest first, compared with the assessment before the window if any
+	for i, j := 0, len(assessments)-1; i < j; i, j = i+1, j-1 {
+		assessments[i], assessments[j] = assessments[j], assessments[i]
+	}
+	previous, err := h.latestBefore(dbCtx, userID, assessments[0].CreatedAt)
+	if err != nil {
+		return models.RiskHistoryResponse{}, err
+	}
+
+	policy := h.policies.Policy()
+	for i := range assessments {
+		current := &assessments[i]
+		point := models.RiskHistoryPoint{
+			ID:            current.ID,
+			Score:         current.Score,
+			RiskLevel:     current.RiskLevel,
+			Factors:       factorLabels(policy, current.Factors),
+			Appeared:      []string{},
+			Resolved:      []string{},
+			PolicyVersion: current.PolicyVersion,
+			CreatedAt:     current.CreatedAt,
+		}
+		if previous != nil {
+			point.Delta = current.Score - previous.Score
+			appeared, resolved := compareFactors(policy, previous.Factors, current.Factors)
+			point.Appeared, point.Resolved = factorLabels(policy, appeared), factorLabels(policy, resolved)
+		}
+		response.Points = append(response.Points, point)
+		previous = current
+	}
+
+	latest := response.Points[len(response.Points)-1]
+	response.Score = &latest.Score
+	response.Delta, response.Appeared, response.Resolved = latest.Delta, latest.Appeared, latest.Resolved
+	return response, nil
+}
+
+// Assessment returns one of a user's assessments, or nil if there is none
+func (h *RiskHistory) Assessment(ctx context.Context, userID string, id primitive.ObjectID) (*models.RiskAssessment, error) {
+	collection := h.client.Database("safetrace").Collection("risk_assessments")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var assessment models.RiskAssessment
+	err := collection.FindOne(dbCtx, bson.M{"_id": id, "userId": userID}).Decode(&assessment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	assessment.Factors = factorLabels(h.policies.Policy(), assessment.Factors)
+	return &assessment, nil
+}
+
+// Digests returns a user's latest weekly digests, newest first
+func (h *RiskHistory) Digests(ctx context.Context, userID string, limit int) ([]models.RiskDigest, error) {
+	collection := h.client.Database("safetrace").Collection("risk_digests")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "periodStart", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(dbCtx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	digests := []models.RiskDigest{}
+	if err := cursor.All(dbCtx, &digests); err != nil {
+		return nil, err
+	}
+	policy := h.policies.Policy()
+	for i := range digests {
+		digests[i] = labelDigest(policy, digests[i])
+	}
+	return digests, nil
+}
+
+// Run purges expired history and sends each week's digests until the context
+// is cancelled
+func (h *RiskHistory) Run(ctx context.Context) {
+	h.ensureIndexes(ctx)
+	if h.notifier != nil {
+		go h.deliverDigests(ctx)
+	}
+
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := h.Purge(ctx); err != nil {
+			log.Printf("Risk history: purge failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Risk history: purged %d assessments older than %s", deleted, h.retention)
+		}
+
+		if sent, err := h.SendDigests(ctx, time.Now()); err != nil {
+			log.Printf("Risk history: digests failed: %v", err)
+		} else if sent > 0 {
+			log.Printf("Risk history: created %d weekly digests", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDigests creates a digest for every user assessed in the last complete
+// week, Monday to Monday UTC, that does not have one yet, and queues it for
+// the delivery worker Run starts. It returns the number created. A unique
+// index lets several server instances run it at once without sending a
+// digest twice.
+func (h *RiskHistory) SendDigests(ctx context.Context, now time.Time) (int, error) {
+	end := weekStart(now)
+	start := end.AddDate(0, 0, -7)
+
+	db := h.client.Database("safetrace")
+	dbCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	userIDs, err := db.Collection("risk_assessments").Distinct(dbCtx, "userId", bson.M{"createdAt": bson.M{"$gte": start, "$lt": end}})
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, value := range userIDs {
+		userID, ok := value.(string)
+		if !ok {
+			continue
+		}
+		exists, err := db.Collection("risk_digests").CountDocuments(dbCtx, bson.M{"userId": userID, "periodStart": start})
+		if err != nil {
+			return sent, err
+		}
+		if exists > 0 {
+			continue
+		}
+
+		digest, err := h.buildDigest(dbCtx, userID, start, end)
+		if err != nil {
+			return sent, err
+		}
+		result, err := db.Collection("risk_digests").InsertOne(dbCtx, digest)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+		digest.ID = result.InsertedID.(primitive.ObjectID)
+		sent++
+
+		if h.notifier != nil {
+			select {
+			case h.deliveries <- digest:
+			case <-ctx.Done():
+				return sent, ctx.Err()
+			}
+		}
+	}
+	return sent, nil
+}
+
+// deliverDigests sends queued digests one at a time until the context is
+// cancelled. Deliveries retry with backoff, so they run apart from the digest
+// loop, but in a single worker however many users are due.
+func (h *RiskHistory) deliverDigests(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case digest := <-h.deliveries:
+			h.notifier.NotifyDigest(ctx, labelDigest(h.policies.Policy(), digest))
+		}
+	}
+}
+
+// buildDigest summarizes a user's assessments in [start, end), compared with
+// their latest assessment before start or else the first one in the period
+func (h *RiskHistory) buildDigest(ctx context.Context, userID string, start time.Time, end time.Time) (models.RiskDigest, error) {
+	collection := h.client.Database("safetrace").Collection("risk_assessments")
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"userId": userID, "createdAt": bson.M{"$gte": start, "$lt": end}}, opts)
+	if err != nil {
+		return models.RiskDigest{}, err
+	}
+	var assessments []models.RiskAssessment
+	if err := cursor.All(ctx, &assessments); err != nil {
+		return models.RiskDigest{}, err
+	}
+	if len(assessments) == 0 {
+		return models.RiskDigest{}, errors.New("no assessments in the period")
+	}
+
+	latest := assessments[len(assessments)-1]
+	digest := models.RiskDigest{
+		UserID:      userID,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Assessments: len(assessments),
+		Score:       latest.Score,
+		RiskLevel:   latest.RiskLevel,
+		Factors:     factorIDs(h.policies.Policy(), latest.Factors),
+		CreatedAt:   time.Now(),
+	}
+
+	from, err := h.latestBefore(ctx, userID, start)
+	if err != nil {
+		return models.RiskDigest{}, err
+	}
+	if from != nil {
+		digest.PreviousScore = &from.Score
+	} else {
+		from = &assessments[0]
+	}
+	digest.Delta = latest.Score - from.Score
+	digest.Appeared, digest.Resolved = compareFactors(h.policies.Policy(), from.Factors, latest.Factors)
+	if from.RiskLevel != latest.RiskLevel {
+		digest.LevelChange = from.RiskLevel + " -> " + latest.RiskLevel
+	}
+	return digest, nil
+}
+
+// latestBefore returns a user's latest assessment before a time, or nil
+func (h *RiskHistory) latestBefore(ctx context.Context, userID string, before time.Time) (*models.RiskAssessment, error) {
+	collection := h.client.Database("safetrace").Collection("risk_assessments")
+	opts := options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	var assessment models.RiskAssessment
+	err := collection.FindOne(ctx, bson.M{"userId": userID, "createdAt": bson.M{"$lt": before}}, opts).Decode(&assessment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &assessment, nil
+}
+
+// Purge deletes assessments and digests older than the retention period
+func (h *RiskHistory) Purge(ctx context.Context) (int64, error) {
+	db := h.client.Database("safetrace")
+	dbCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	cutoff := time.Now().Add(-h.retention)
+	result, err := db.Collection("risk_assessments").DeleteMany(dbCtx, bson.M{"createdAt": bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := db.Collection("risk_digests").DeleteMany(dbCtx, bson.M{"periodEnd": bson.M{"$lt": cutoff}}); err != nil {
+		return result.DeletedCount, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (h *RiskHistory) ensureIndexes(ctx context.Context) {
+	db := h.client.Database("safetrace")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("risk_assessments").Indexes().CreateMany(dbCtx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.M{"createdAt": 1}},
+	})
+	if err != nil {
+		log.Printf("Risk history: failed to create assessment indexes: %v", err)
+	}
+
+	_, err = db.Collection("risk_digests").Indexes().CreateOne(dbCtx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "periodStart", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Risk history: failed to create digest indexes: %v", err)
+	}
+}
+
+// weekStart returns the start of the week holding t: Monday at midnight UTC
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.UTC().Year(), t.UTC().Month(), t.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// compareFactors returns the rule IDs of factors in current but not previous,
+// and those in previous but no longer in current
+func compareFactors(policy *risk.Policy, previous []string, current []string) ([]string, []string) {
+	previous, current = factorIDs(policy, previous), factorIDs(policy, current)
+	appeared, resolved := []string{}, []string{}
+	for _, factor := range current {
+		if !containsFactor(previous, factor) {
+			appeared = append(appeared, factor)
+		}
+	}
+	for _, factor := range previous {
+		if !containsFactor(current, factor) {
+			resolved = append(resolved, factor)
+		}
+	}
+	return appeared, resolved
+}
+
+func containsFactor(factors []string, factor string) bool {
+	for _, f := range factors {
+		if f == factor {
+			return true
+		}
+	}
+	return false
+}
+
+// factorIDs returns the rule IDs of stored factors, some of which may be
+// labels recorded before IDs were
+func factorIDs(policy *risk.Policy, factors []string) []string {
+	ids := make([]string, 0, len(factors))
+	for _, factor := range factors {
+		ids = append(ids, policy.FactorID(factor))
+	}
+	return ids
+}
+
+// factorLabels returns the labels of the factors with the given rule IDs
+func factorLabels(policy *risk.Policy, ids []string) []string {
+	labels := make([]string, 0, len(ids))
+	for _, id := range ids {
+		labels = append(labels, policy.FactorLabel(id))
+	}
+	return labels
+}
+
+// labelDigest returns a stored digest with its factors' labels in place of
+// their rule IDs
+func labelDigest(policy *risk.Policy, digest models.RiskDigest) models.RiskDigest {
+	digest.Appeared = factorLabels(policy, digest.Appeared)
+	digest.Resolved = factorLabels(policy, digest.Resolved)
+	digest.Factors = factorLabels(policy, digest.Factors)
+	return digest
+}
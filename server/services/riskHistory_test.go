@@ -0,0 +1,55 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/risk"
+)
+
+func TestCompareFactors(t *testing.T) {
+	policy := risk.DefaultPolicy()
+
+	tests := []struct {
+		name         string
+		previous     []string
+		current      []string
+		wantAppeared []string
+		wantResolved []string
+	}{
+		{"rule IDs", []string{"no-2fa", "weak-passwords"}, []string{"weak-passwords", "data-breaches"},
+			[]string{"data-breaches"}, []string{"no-2fa"}},
+		{"labels recorded before IDs", []string{"No two-factor authentication", "Weak password usage"}, []string{"weak-passwords"},
+			[]string{}, []string{"no-2fa"}},
+		{"rule no longer in the policy", []string{"retired-rule"}, []string{},
+			[]string{}, []string{"retired-rule"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appeared, resolved := compareFactors(policy, tt.previous, tt.current)
+			if !reflect.DeepEqual(appeared, tt.wantAppeared) || !reflect.DeepEqual(resolved, tt.wantResolved) {
+				t.Errorf("appeared %v, resolved %v; want %v, %v", appeared, resolved, tt.wantAppeared, tt.wantResolved)
+			}
+		})
+	}
+}
+
+func TestLabelDigest(t *testing.T) {
+	digest := labelDigest(risk.DefaultPolicy(), models.RiskDigest{
+		Appeared: []string{"data-breaches"},
+		Resolved: []string{"retired-rule"},
+		Factors:  []string{"data-breaches", "weak-passwords"},
+	})
+
+	if want := []string{"Involved in previous data breaches"}; !reflect.DeepEqual(digest.Appeared, want) {
+		t.Errorf("appeared = %v, want %v", digest.Appeared, want)
+	}
+	if want := []string{"retired-rule"}; !reflect.DeepEqual(digest.Resolved, want) {
+		t.Errorf("resolved = %v, want %v", digest.Resolved, want)
+	}
+	if want := []string{"Involved in previous data breaches", "Weak password usage"}; !reflect.DeepEqual(digest.Factors, want) {
+		t.Errorf("factors = %v, want %v", digest.Factors, want)
+	}
+}
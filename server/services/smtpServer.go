@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// smtpCommandTimeout is how long a client may stay idle between commands
+	smtpCommandTimeout = 5 * time.Minute
+	// smtpMaxRecipients bounds the recipients of a single message
+	smtpMaxRecipients = 100
+	// smtpMaxLineLength bounds a command line, without its CRLF. RFC 5321
+	// allows 512 bytes including CRLF; the rest leaves room for ESMTP
+	// parameters from lenient clients.
+	smtpMaxLineLength = 1000
+)
+
+// errSMTPLineTooLong is returned for command lines over smtpMaxLineLength
+var errSMTPLineTooLong = errors.New("line too long")
+
+// smtpReply is an error that is sent to the SMTP client as-is, e.g. to reject
+// a recipient or a message
+type smtpReply struct {
+	code    int
+	message string
+}
+
+func (r *smtpReply) Error() string {
+	return strconv.Itoa(r.code) + " " + r.message
+}
+
+func newSMTPReply(code int, format string, args ...interface{}) *smtpReply {
+	return &smtpReply{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// smtpSession is the state of one SMTP transaction
+type smtpSession struct {
+	from       string
+	hasFrom    bool
+	recipients []string
+}
+
+// smtpServer speaks just enough SMTP to receive mail: HELO/EHLO, MAIL, RCPT,
+// DATA, RSET, NOOP, VRFY and QUIT. What it accepts and what happens to
+// received mail is left to its callbacks.
+type smtpServer struct {
+	name     string // used in greetings and log messages
+	hostname string
+	addr     string
+	maxSize  int
+	// maxRecipients bounds the recipients of a single message, defaulting
+	// to smtpMaxRecipients
+	maxRecipients int
+	// accept returns an error, ideally an *smtpReply, to refuse a recipient
+	accept func(from string, recipient string) error
+	// deliver handles a received message. Errors other than *smtpReply are
+	// logged and reported to the client as temporary failures.
+	deliver func(session smtpSession, raw []byte) error
+}
+
+// Run accepts SMTP connections until the context is cancelled
+func (s *smtpServer) Run(ctx context.Context) {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		log.Printf("%s: failed to listen on %s: %v", s.name, s.addr, err)
+		return
+	}
+	log.Printf("%s: accepting mail for @%s on %s", s.name, s.hostname, s.addr)
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("%s: accept failed: %v", s.name, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		go s.serve(conn)
+	}
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) {
+		text.PrintfLine(format, args...)
+	}
+	replyError := func(err error, fallback *smtpReply) {
+		var r *smtpReply
+		if !errors.As(err, &r) {
+			r = fallback
+		}
+		reply("%d %s", r.code, r.message)
+	}
+
+	reply("220 %s SafeTrace %s ready", s.hostname, s.name)
+	var session smtpSession
+	for {
+		conn.SetDeadline(time.Now().Add(smtpCommandTimeout))
+		line, err := readSMTPLine(text.R)
+		if errors.Is(err, errSMTPLineTooLong) {
+			reply("500 5.5.2 Line too long")
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			session = smtpSession{}
+			reply("250 %s", s.hostname)
+		case "EHLO":
+			session = smtpSession{}
+			reply("250-%s", s.hostname)
+			reply("250-SIZE %d", s.maxSize)
+			reply("250-8BITMIME")
+			reply("250 SMTPUTF8")
+		case "MAIL":
+			from, params, ok := smtpPath(arg, "FROM:")
+			if !ok {
+				reply("501 5.5.4 Syntax: MAIL FROM:<address>")
+				continue
+			}
+			if size, err := strconv.Atoi(params["SIZE"]); err == nil && size > s.maxSize {
+				reply("552 5.3.4 Message size exceeds fixed limit")
+				continue
+			}
+			session = smtpSession{from: from, hasFrom: true}
+			reply("250 2.1.0 OK")
+		case "RCPT":
+			if !session.hasFrom {
+				reply("503 5.5.1 MAIL first")
+				continue
+			}
+			recipient, _, ok := smtpPath(arg, "TO:")
+			if !ok {
+				reply("501 5.5.4 Syntax: RCPT TO:<address>")
+				continue
+			}
+			if err := s.accept(session.from, recipient); err != nil {
+				replyError(err, newSMTPReply(451, "4.3.0 Temporary failure, try again later"))
+				continue
+			}
+			if len(session.recipients) >= s.recipientLimit() {
+				reply("452 4.5.3 Too many recipients")
+				continue
+			}
+			session.recipients = append(session.recipients, recipient)
+			reply("250 2.1.5 OK")
+		case "DATA":
+			if len(session.recipients) == 0 {
+				reply("503 5.5.1 RCPT first")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			conn.SetDeadline(time.Now().Add(smtpCommandTimeout))
+			dot := text.DotReader()
+			raw, err := io.ReadAll(io.LimitReader(dot, int64(s.maxSize)+1))
+			if err != nil {
+				return
+			}
+			if len(raw) > s.maxSize {
+				io.Copy(io.Discard, dot)
+				reply("552 5.3.4 Message size exceeds fixed limit")
+			} else if err := s.deliver(session, raw); err != nil {
+				var r *smtpReply
+				if !errors.As(err, &r) {
+					log.Printf("%s: failed to deliver message: %v", s.name, err)
+				}
+				replyError(err, newSMTPReply(451, "4.3.0 Failed to deliver message"))
+			} else {
+				reply("250 2.0.0 OK")
+			}
+			session = smtpSession{}
+		case "RSET":
+			session = smtpSession{}
+			reply("250 2.0.0 OK")
+		case "NOOP":
+			reply("250 2.0.0 OK")
+		case "VRFY":
+			reply("252 2.5.0 Cannot verify, but will accept")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+// readSMTPLine reads a command line without buffering more than
+// smtpMaxLineLength bytes of it
+func readSMTPLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if len(line)+len(chunk) > smtpMaxLineLength {
+			return "", errSMTPLineTooLong
+		}
+		line = append(line, chunk...)
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+func (s *smtpServer) recipientLimit() int {
+	if s.maxRecipients > 0 {
+		return s.maxRecipients
+	}
+	return smtpMaxRecipients
+}
+
+// smtpPath parses the argument of MAIL FROM:<path> or RCPT TO:<path>, with
+// any ESMTP parameters after it
+func smtpPath(arg string, prefix string) (string, map[string]string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+
+	params := map[string]string{}
+	for _, param := range strings.Fields(rest[end+1:]) {
+		key, value, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = value
+	}
+	return rest[1:end], params, true
+}
+
+// localPart returns the lowercased local part of an address on domain,
+// without any +tag, or "" for other domains
+func localPart(address string, domain string) string {
+	at := strings.LastIndexByte(address, '@')
+	if at <= 0 || !strings.EqualFold(address[at+1:], domain) {
+		return ""
+	}
+	local := strings.ToLower(address[:at])
+	if plus := strings.IndexByte(local, '+'); plus > 0 {
+		local = local[:plus]
+	}
+	return local
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sinkMessage struct {
+	from       string
+	recipients []string
+	raw        []byte
+}
+
+// startSMTPSink runs an smtpServer on a local port and returns the address
+// it listens on and a function that waits for the next delivered message
+func startSMTPSink(t *testing.T) (string, func() sinkMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan sinkMessage, 8)
+	server := &smtpServer{
+		name:     "Test sink",
+		hostname: "sink.test",
+		maxSize:  1 << 20,
+		accept:   func(string, string) error { return nil },
+		deliver: func(session smtpSession, raw []byte) error {
+			messages <- sinkMessage{from: session.from, recipients: session.recipients, raw: raw}
+			return nil
+		},
+	}
+
+	var wg sync.WaitGroup
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				server.serve(conn)
+			}()
+		}
+	}()
+	t.Cleanup(wg.Wait)
+
+	next := func() sinkMessage {
+		select {
+		case message := <-messages:
+			return message
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message reached the SMTP sink")
+			return sinkMessage{}
+		}
+	}
+	return listener.Addr().String(), next
+}
+
+func TestSMTPServerLineLength(t *testing.T) {
+	addr, _ := startSMTPSink(t)
+
+	tests := []struct {
+		name string
+		line string
+		want int
+	}{
+		{"short command", "NOOP", 250},
+		{"longest command", "NOOP " + strings.Repeat("x", smtpMaxLineLength-5), 250},
+		{"over the limit", "NOOP " + strings.Repeat("x", smtpMaxLineLength-4), 500},
+		{"far over the limit", "NOOP " + strings.Repeat("x", 1<<16), 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := textproto.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			if _, _, err := conn.ReadResponse(220); err != nil {
+				t.Fatalf("greeting: %v", err)
+			}
+
+			if err := conn.PrintfLine("%s", tt.line); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			code, _, _ := conn.ReadResponse(0)
+			if code != tt.want {
+				t.Errorf("reply code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
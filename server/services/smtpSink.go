@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/siddhantgureja/safetrace/models"
+	"github.com/siddhantgureja/safetrace/utils"
+)
+
+// ErrSinkMessageNotFound is returned for messages that do not exist or have
+// expired
+var ErrSinkMessageNotFound = errors.New("message not found")
+
+// ErrSinkAliasNotFound is returned for aliases that do not exist, have
+// expired or belong to another user
+var ErrSinkAliasNotFound = errors.New("alias not found")
+
+// SMTPSink is an SMTP server that accepts mail for the aliases it has handed
+// out on one domain and stores it per alias (the address's local part) until
+// its TTL runs out
+type SMTPSink struct {
+	client      *mongo.Client
+	addr        string
+	domain      string
+	ttl         time.Duration
+	aliasTTL    time.Duration
+	maxSize     int
+	maxMessages int
+	subscribers *sinkSubscribers
+}
+
+// NewSMTPSink creates the SMTP sink configured from the environment, or
+// returns nil when SMTP_SINK_DOMAIN is not set
+func NewSMTPSink(client *mongo.Client) *SMTPSink {
+	domain := strings.ToLower(strings.TrimSpace(os.Getenv("SMTP_SINK_DOMAIN")))
+	if domain == "" {
+		return nil
+	}
+
+	addr := os.Getenv("SMTP_SINK_ADDR")
+	if addr == "" {
+		addr = ":2525"
+	}
+	return &SMTPSink{
+		client:      client,
+		addr:        addr,
+		domain:      domain,
+		ttl:         utils.EnvDuration("SMTP_SINK_TTL", time.Hour),
+		aliasTTL:    utils.EnvDuration("SMTP_SINK_ALIAS_TTL", 24*time.Hour),
+		maxSize:     utils.EnvInt("SMTP_SINK_MAX_SIZE", 10<<20),
+		maxMessages: utils.EnvInt("SMTP_SINK_MAX_MESSAGES", 100),
+		subscribers: &sinkSubscribers{channels: map[string]map[chan models.SinkMessage]bool{}},
+	}
+}
+
+// Domain returns the catch-all domain the sink accepts mail for
+func (s *SMTPSink) Domain() string {
+	return s.domain
+}
+
+// Run accepts SMTP connections until the context is cancelled
+func (s *SMTPSink) Run(ctx context.Context) {
+	s.ensureIndexes(ctx)
+
+	server := &smtpServer{
+		name:     "SMTP sink",
+		hostname: s.domain,
+		addr:     s.addr,
+		maxSize:  s.maxSize,
+		accept:   s.accept,
+		deliver:  s.deliver,
+	}
+	server.Run(ctx)
+}
+
+// accept refuses mail for other domains and for aliases the sink has not
+// handed out
+func (s *SMTPSink) accept(from string, recipient string) error {
+	alias := s.alias(recipient)
+	if alias == "" {
+		return newSMTPReply(550, "5.7.1 Relaying denied, only @%s is accepted", s.domain)
+	}
+
+	collection := s.client.Database("safetrace").Collection("sink_aliases")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := collection.FindOne(dbCtx, bson.M{"_id": alias, "expiresAt": bson.M{"$gt": time.Now()}}).Err()
+	if err == mongo.ErrNoDocuments {
+		return newSMTPReply(550, "5.1.1 Mailbox unavailable")
+	}
+	return err
+}
+
+// alias returns the mailbox name for an address on the sink's domain, without
+// any +tag, or "" for other domains
+func (s *SMTPSink) alias(address string) string {
+	return localPart(address, s.domain)
+}
+
+// deliver parses a received message and stores a copy for each alias it was
+// sent to
+func (s *SMTPSink) deliver(session smtpSession, raw []byte) error {
+	parsed, err := ParseMIMEMessage(raw)
+	if err != nil {
+		// Keep unparseable mail readable rather than bouncing it
+		parsed = &MIMEMessage{Text: string(raw)}
+	}
+
+	collection := s.client.Database("safetrace").Collection("sink_messages")
+	dbCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	delivered := map[string]bool{}
+	for _, recipient := range session.recipients {
+		alias := s.alias(recipient)
+		if delivered[alias] {
+			continue
+		}
+		delivered[alias] = true
+
+		message := models.SinkMessage{
+			ID:           primitive.NewObjectID(),
+			Alias:        alias,
+			EnvelopeFrom: session.from,
+			From:         parsed.From,
+			To:           parsed.To,
+			Subject:      parsed.Subject,
+			Text:         parsed.Text,
+			HTML:         parsed.HTML,
+			Attachments:  parsed.Attachments,
+			Size:         len(raw),
+			ReceivedAt:   now,
+			ExpiresAt:    now.Add(s.ttl),
+		}
+		if message.Attachments == nil {
+			message.Attachments = []models.SinkAttachment{}
+		}
+		if _, err := collection.InsertOne(dbCtx, message); err != nil {
+			return err
+		}
+		s.trim(dbCtx, alias)
+		s.subscribers.publish(message)
+	}
+	return nil
+}
+
+// trim deletes an alias's oldest messages beyond SMTP_SINK_MAX_MESSAGES
+func (s *SMTPSink) trim(ctx context.Context, alias string) {
+	collection := s.client.Database("safetrace").Collection("sink_messages")
+	opts := options.Find().
+		SetSort(bson.D{{Key: "receivedAt", Value: -1}}).
+		SetSkip(int64(s.maxMessages)).
+		SetProjection(bson.M{"_id": 1})
+	cursor, err := collection.Find(ctx, bson.M{"alias": alias}, opts)
+	if err != nil {
+		log.Printf("SMTP sink: failed to trim %s: %v", alias, err)
+		return
+	}
+
+	var old []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &old); err != nil || len(old) == 0 {
+		return
+	}
+	ids := make([]primitive.ObjectID, len(old))
+	for i, message := range old {
+		ids[i] = message.ID
+	}
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		log.Printf("SMTP sink: failed to trim %s: %v", alias, err)
+	}
+}
+
+// CheckOwner returns ErrSinkAliasNotFound unless the alias exists and was
+// created by the user
+func (s *SMTPSink) CheckOwner(ctx context.Context, alias string, userID string) error {
+	collection := s.client.Database("safetrace").Collection("sink_aliases")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": strings.ToLower(alias), "userId": userID, "expiresAt": bson.M{"$gt": time.Now()}}
+	err := collection.FindOne(dbCtx, filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return ErrSinkAliasNotFound
+	}
+	return err
+}
+
+// Messages lists an alias's messages, newest first, without their bodies
+func (s *SMTPSink) Messages(ctx context.Context, alias string, limit int64) ([]models.SinkMessage, error) {
+	collection := s.client.Database("safetrace").Collection("sink_messages")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "receivedAt", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"text": 0, "html": 0, "attachments.data": 0})
+	cursor, err := collection.Find(dbCtx, bson.M{"alias": strings.ToLower(alias), "expiresAt": bson.M{"$gt": time.Now()}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []models.SinkMessage{}
+	if err := cursor.All(dbCtx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Message returns one of an alias's messages, including attachment data
+func (s *SMTPSink) Message(ctx context.Context, alias string, id primitive.ObjectID) (*models.SinkMessage, error) {
+	collection := s.client.Database("safetrace").Collection("sink_messages")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var message models.SinkMessage
+	filter := bson.M{"_id": id, "alias": strings.ToLower(alias), "expiresAt": bson.M{"$gt": time.Now()}}
+	if err := collection.FindOne(dbCtx, filter).Decode(&message); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSinkMessageNotFound
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// DeleteMessage deletes one of an alias's messages
+func (s *SMTPSink) DeleteMessage(ctx context.Context, alias string, id primitive.ObjectID) error {
+	collection := s.client.Database("safetrace").Collection("sink_messages")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(dbCtx, bson.M{"_id": id, "alias": strings.ToLower(alias)})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSinkMessageNotFound
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the alias's new messages, without
+// bodies or attachment data, and a function to stop the subscription. Only
+// mail received by this instance is delivered.
+func (s *SMTPSink) Subscribe(alias string) (<-chan models.SinkMessage, func()) {
+	return s.subscribers.subscribe(strings.ToLower(alias))
+}
+
+// NewAlias creates an alias for the user, built from a fake email. The 128-bit
+// random suffix keeps other users from guessing it.
+func (s *SMTPSink) NewAlias(ctx context.Context, userID string, email string) (*models.SinkAlias, error) {
+	now := time.Now()
+	alias := &models.SinkAlias{
+		Alias:     InboxLocalPart(email) + "." + randomHex(16),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.aliasTTL),
+	}
+
+	collection := s.client.Database("safetrace").Collection("sink_aliases")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := collection.InsertOne(dbCtx, alias); err != nil {
+		return nil, err
+	}
+	return alias, nil
+}
+
+// Address returns the email address of an alias
+func (s *SMTPSink) Address(alias *models.SinkAlias) string {
+	return alias.Alias + "@" + s.domain
+}
+
+func (s *SMTPSink) ensureIndexes(ctx context.Context) {
+	collection := s.client.Database("safetrace").Collection("sink_messages")
+	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(dbCtx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "alias", Value: 1}, {Key: "receivedAt", Value: -1}}},
+		{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Printf("SMTP sink: failed to create indexes: %v", err)
+	}
+
+	aliases := s.client.Database("safetrace").Collection("sink_aliases")
+	_, err = aliases.Indexes().CreateOne(dbCtx, mongo.IndexModel{
+		Keys:    bson.M{"expiresAt": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		log.Printf("SMTP sink: failed to create alias indexes: %v", err)
+	}
+}
+
+// sinkSubscribers fans new messages out to live streams
+type sinkSubscribers struct {
+	mu       sync.Mutex
+	channels map[string]map[chan models.SinkMessage]bool
+}
+
+func (b *sinkSubscribers) subscribe(alias string) (<-chan models.SinkMessage, func()) {
+	ch := make(chan models.SinkMessage, 16)
+
+	b.mu.Lock()
+	if b.channels[alias] == nil {
+		b.channels[alias] = map[chan models.SinkMessage]bool{}
+	}
+	b.channels[alias][ch] = true
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.channels[alias], ch)
+		if len(b.channels[alias]) == 0 {
+			delete(b.channels, alias)
+		}
+	}
+}
+
+// publish sends a summary of the message to the alias's subscribers,
+// dropping it for any that are not keeping up
+func (b *sinkSubscribers) publish(message models.SinkMessage) {
+	message.Text, message.HTML = "", ""
+	attachments := make([]models.SinkAttachment, len(message.Attachments))
+	for i, attachment := range message.Attachments {
+		attachment.Data = nil
+		attachments[i] = attachment
+	}
+	message.Attachments = attachments
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.channels[message.Alias] {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+}
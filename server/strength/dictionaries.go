@@ -0,0 +1,77 @@
+package strength
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+	"sync"
+)
+
+// Word lists ordered from most to least common, one lowercase word per line
+//
+//go:embed dictionaries/*.txt
+var dictionaryFiles embed.FS
+
+// rankedDictionary maps a word to its 1-based frequency rank
+type rankedDictionary struct {
+	name     string
+	ranks    map[string]int
+	maxRunes int // length of the longest word
+}
+
+var (
+	loadOnce     sync.Once
+	dictionaries []rankedDictionary
+)
+
+// builtinDictionaries are the embedded word lists, in matching order
+var builtinDictionaries = []string{"passwords", "english", "female_names", "male_names", "surnames"}
+
+// loadDictionaries parses the embedded word lists on first use
+func loadDictionaries() []rankedDictionary {
+	loadOnce.Do(func() {
+		for _, name := range builtinDictionaries {
+			file, err := dictionaryFiles.Open("dictionaries/" + name + ".txt")
+			if err != nil {
+				panic("strength: missing dictionary " + name)
+			}
+
+			dict := rankedDictionary{name: name, ranks: make(map[string]int)}
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				word := strings.TrimSpace(scanner.Text())
+				if word == "" {
+					continue
+				}
+				dict.add(word)
+			}
+			file.Close()
+
+			dictionaries = append(dictionaries, dict)
+		}
+	})
+	return dictionaries
+}
+
+// userInputDictionary ranks the words a user is likely to put in their own
+// password, such as their name or parts of their email address
+func userInputDictionary(inputs []string) rankedDictionary {
+	dict := rankedDictionary{name: "user_inputs", ranks: make(map[string]int)}
+	for _, input := range inputs {
+		if input = strings.ToLower(strings.TrimSpace(input)); input != "" {
+			dict.add(input)
+		}
+	}
+	return dict
+}
+
+// add appends a word with the next rank unless it is already present
+func (d *rankedDictionary) add(word string) {
+	if _, ok := d.ranks[word]; ok {
+		return
+	}
+	d.ranks[word] = len(d.ranks) + 1
+	if length := len([]rune(word)); length > d.maxRunes {
+		d.maxRunes = length
+	}
+}
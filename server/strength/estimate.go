@@ -0,0 +1,123 @@
+// Package strength estimates how many guesses an attacker needs to crack a
+// password by finding the common patterns it is made of, in the style of zxcvbn
+package strength
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+// maxPasswordRunes caps analysis, since longer passwords are strong anyway
+// and matching grows quadratically with length
+const maxPasswordRunes = 100
+
+// scoreThresholds are the guesses needed to reach scores 1 through 4
+var scoreThresholds = []float64{1e3, 1e6, 1e8, 1e10}
+
+// attackScenarios are guessing rates for common attacker models
+var attackScenarios = map[string]float64{
+	"online_throttling":    100.0 / 3600,
+	"online_no_throttling": 10,
+	"offline_slow_hashing": 1e4,
+	"offline_fast_hashing": 1e10,
+}
+
+// Estimate rates a password, penalizing words from userInputs such as the
+// user's name or email address
+func Estimate(password string, userInputs []string) models.PasswordStrengthResponse {
+	runes := []rune(password)
+	if len(runes) > maxPasswordRunes {
+		runes = runes[:maxPasswordRunes]
+	}
+
+	dicts := loadDictionaries()
+	if len(userInputs) > 0 {
+		dicts = append(append([]rankedDictionary{}, dicts...), userInputDictionary(userInputs))
+	}
+
+	result := mostGuessableSequence(runes, omnimatch(runes, dicts))
+	guesses := result.guesses()
+
+	score := 0
+	for _, threshold := range scoreThresholds {
+		// A small margin keeps a password right at a threshold from rounding up
+		if guesses < threshold+5 {
+			break
+		}
+		score++
+	}
+
+	crackTimes := make(map[string]models.CrackTimeEstimate, len(attackScenarios))
+	for name, rate := range attackScenarios {
+		seconds := guesses / rate
+		crackTimes[name] = models.CrackTimeEstimate{
+			GuessesPerSecond: rate,
+			Seconds:          seconds,
+			Display:          displayTime(seconds),
+		}
+	}
+
+	matches := make([]models.PasswordMatch, len(result.matches))
+	for i, m := range result.matches {
+		matches[i] = models.PasswordMatch{
+			Pattern:    m.pattern,
+			Token:      m.token,
+			Start:      m.i,
+			End:        m.j,
+			Guesses:    m.guesses,
+			Dictionary: m.dictionary,
+			Word:       m.word,
+			Rank:       m.rank,
+			L33t:       m.l33t,
+			Reversed:   m.reversed,
+		}
+	}
+
+	return models.PasswordStrengthResponse{
+		Score:        score,
+		Guesses:      math.Round(guesses),
+		GuessesLog10: math.Round(result.guessesLog10*100) / 100,
+		EntropyBits:  math.Round(result.guessesLog10*math.Log2(10)*100) / 100,
+		CrackTimes:   crackTimes,
+		Feedback:     feedback(score, result.matches),
+		Matches:      matches,
+	}
+}
+
+// displayTime formats a duration in seconds for people to read
+func displayTime(seconds float64) string {
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"century", 100 * 365.25 * 24 * 3600},
+		{"year", 365.25 * 24 * 3600},
+		{"month", 30.44 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+
+	if seconds < 1 {
+		return "less than a second"
+	}
+	if seconds >= 100*units[0].seconds {
+		return "centuries"
+	}
+	for _, unit := range units {
+		if seconds >= unit.seconds {
+			value := int(math.Round(seconds / unit.seconds))
+			if value == 1 {
+				return fmt.Sprintf("1 %s", unit.name)
+			}
+			if unit.name == "century" {
+				return fmt.Sprintf("%d centuries", value)
+			}
+			return fmt.Sprintf("%d %ss", value, unit.name)
+		}
+	}
+	return "less than a second"
+}
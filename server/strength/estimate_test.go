@@ -0,0 +1,115 @@
+package strength
+
+import (
+	"strings"
+	"testing"
+)
+
+// pinnedMatch is the part of a matched pattern the tests pin down
+type pinnedMatch struct {
+	pattern    string
+	token      string
+	dictionary string
+	word       string
+	l33t       bool
+	reversed   bool
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		score      int
+		matches    []pinnedMatch
+		warning    string
+	}{
+		{"common password", "password1", nil, 0,
+			[]pinnedMatch{{pattern: "dictionary", token: "password1", dictionary: "passwords", word: "password1"}},
+			"This is a very common password"},
+		{"l33t substitutions", "P@ssw0rd", nil, 0,
+			[]pinnedMatch{{pattern: "dictionary", token: "P@ssw0rd", dictionary: "passwords", word: "password", l33t: true}},
+			"This is similar to a commonly used password"},
+		{"reversed word", "drowssap", nil, 0,
+			[]pinnedMatch{{pattern: "dictionary", token: "drowssap", dictionary: "passwords", word: "password", reversed: true}}, ""},
+		{"keyboard row", "qwertyuiop", nil, 1,
+			[]pinnedMatch{{pattern: "dictionary", token: "qwertyuiop", dictionary: "passwords", word: "qwertyuiop"}}, ""},
+		{"date", "19871231", nil, 1,
+			[]pinnedMatch{{pattern: "date", token: "19871231"}},
+			"Dates are often easy to guess"},
+		{"repeated character", "aaaaaaaaaa", nil, 0,
+			[]pinnedMatch{{pattern: "repeat", token: "aaaaaaaaaa"}}, ""},
+		{"sequence", "abcdefgh", nil, 0,
+			[]pinnedMatch{{pattern: "sequence", token: "abcdefgh"}}, ""},
+		{"passphrase", "correcthorsebatterystaple", nil, 4,
+			[]pinnedMatch{
+				{pattern: "dictionary", token: "correct", dictionary: "english", word: "correct"},
+				{pattern: "dictionary", token: "horse", dictionary: "passwords", word: "horse"},
+				{pattern: "dictionary", token: "battery", dictionary: "english", word: "battery"},
+				{pattern: "dictionary", token: "staple", dictionary: "english", word: "staple"},
+			}, ""},
+		{"name without user inputs", "janedoe1987", nil, 3,
+			[]pinnedMatch{
+				{pattern: "dictionary", token: "jane", dictionary: "female_names", word: "jane"},
+				{pattern: "bruteforce", token: "doe"},
+				{pattern: "year", token: "1987"},
+			}, ""},
+		{"name from user inputs", "janedoe1987", []string{" JaneDoe ", "jane@example.com"}, 1,
+			[]pinnedMatch{
+				{pattern: "dictionary", token: "janedoe", dictionary: "user_inputs", word: "janedoe"},
+				{pattern: "year", token: "1987"},
+			}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Estimate(tt.password, tt.userInputs)
+			if result.Score != tt.score {
+				t.Errorf("score = %d, want %d (%.0f guesses)", result.Score, tt.score, result.Guesses)
+			}
+
+			got := make([]pinnedMatch, len(result.Matches))
+			for i, m := range result.Matches {
+				got[i] = pinnedMatch{m.Pattern, m.Token, m.Dictionary, m.Word, m.L33t, m.Reversed}
+			}
+			if len(got) != len(tt.matches) {
+				t.Fatalf("matches = %+v, want %+v", got, tt.matches)
+			}
+			for i := range got {
+				if got[i] != tt.matches[i] {
+					t.Errorf("match %d = %+v, want %+v", i, got[i], tt.matches[i])
+				}
+			}
+
+			if tt.warning != "" && result.Feedback.Warning != tt.warning {
+				t.Errorf("warning = %q, want %q", result.Feedback.Warning, tt.warning)
+			}
+		})
+	}
+}
+
+func TestEstimateCoversPassword(t *testing.T) {
+	// Matches must tile the password in order, with no gaps or overlaps
+	for _, password := range []string{"password1", "Tr0ub4dor&3", "zxcvbnm!2024", "é€ünicode"} {
+		result := Estimate(password, nil)
+		runes := []rune(password)
+		next := 0
+		var tokens []string
+		for _, m := range result.Matches {
+			if m.Start != next {
+				t.Errorf("%q: match %q starts at %d, want %d", password, m.Token, m.Start, next)
+			}
+			if m.Token != string(runes[m.Start:m.End+1]) {
+				t.Errorf("%q: token %q does not span %d-%d", password, m.Token, m.Start, m.End)
+			}
+			next = m.End + 1
+			tokens = append(tokens, m.Token)
+		}
+		if joined := strings.Join(tokens, ""); joined != password {
+			t.Errorf("%q: matches cover %q", password, joined)
+		}
+		if result.Guesses < 1 || result.Score < 0 || result.Score > 4 {
+			t.Errorf("%q: %v guesses, score %d", password, result.Guesses, result.Score)
+		}
+	}
+}
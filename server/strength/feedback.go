@@ -0,0 +1,121 @@
+package strength
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/siddhantgureja/safetrace/models"
+)
+
+const defaultSuggestion = "Use a few words, avoid common phrases"
+
+// feedback explains the weakest part of a password and how to improve it
+func feedback(score int, matches []*match) models.PasswordFeedback {
+	if len(matches) == 0 {
+		return models.PasswordFeedback{
+			Suggestions: []string{defaultSuggestion, "No need for symbols, digits, or uppercase letters"},
+		}
+	}
+	if score > 2 {
+		return models.PasswordFeedback{Suggestions: []string{}}
+	}
+
+	// The longest match dominates how the password will be guessed
+	longest := matches[0]
+	for _, m := range matches[1:] {
+		if len(m.token) > len(longest.token) {
+			longest = m
+		}
+	}
+
+	result := matchFeedback(longest, len(matches) == 1)
+	result.Suggestions = append([]string{"Add another word or two. Uncommon words are better."}, result.Suggestions...)
+	return result
+}
+
+func matchFeedback(m *match, soleMatch bool) models.PasswordFeedback {
+	switch m.pattern {
+	case "dictionary":
+		return dictionaryFeedback(m, soleMatch)
+	case "spatial":
+		warning := "Short keyboard patterns are easy to guess"
+		if m.turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return models.PasswordFeedback{
+			Warning:     warning,
+			Suggestions: []string{"Use a longer keyboard pattern with more turns"},
+		}
+	case "repeat":
+		warning := "Repeats like \"abcabcabc\" are only slightly harder to guess than \"abc\""
+		if len([]rune(m.baseToken)) == 1 {
+			warning = "Repeats like \"aaa\" are easy to guess"
+		}
+		return models.PasswordFeedback{
+			Warning:     warning,
+			Suggestions: []string{"Avoid repeated words and characters"},
+		}
+	case "sequence":
+		return models.PasswordFeedback{
+			Warning:     "Sequences like abc or 6543 are easy to guess",
+			Suggestions: []string{"Avoid sequences"},
+		}
+	case "year":
+		return models.PasswordFeedback{
+			Warning:     "Recent years are easy to guess",
+			Suggestions: []string{"Avoid recent years", "Avoid years that are associated with you"},
+		}
+	case "date":
+		return models.PasswordFeedback{
+			Warning:     "Dates are often easy to guess",
+			Suggestions: []string{"Avoid dates and years that are associated with you"},
+		}
+	default:
+		return models.PasswordFeedback{Suggestions: []string{}}
+	}
+}
+
+func dictionaryFeedback(m *match, soleMatch bool) models.PasswordFeedback {
+	var warning string
+	switch m.dictionary {
+	case "passwords":
+		switch {
+		case soleMatch && !m.l33t && !m.reversed && m.rank <= 10:
+			warning = "This is a top-10 common password"
+		case soleMatch && !m.l33t && !m.reversed && m.rank <= 100:
+			warning = "This is a top-100 common password"
+		case soleMatch && !m.l33t && !m.reversed:
+			warning = "This is a very common password"
+		default:
+			warning = "This is similar to a commonly used password"
+		}
+	case "english":
+		if soleMatch {
+			warning = "A word by itself is easy to guess"
+		}
+	case "surnames", "male_names", "female_names":
+		if soleMatch {
+			warning = "Names and surnames by themselves are easy to guess"
+		} else {
+			warning = "Common names and surnames are easy to guess"
+		}
+	case "user_inputs":
+		warning = "Avoid words from your own name or email address"
+	}
+
+	suggestions := []string{}
+	runes := []rune(m.token)
+	if strings.ToUpper(m.token) == m.token && strings.ToLower(m.token) != m.token {
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	} else if unicode.IsUpper(runes[0]) {
+		suggestions = append(suggestions, "Capitalization doesn't help very much")
+	}
+	if m.reversed && len(runes) >= 4 {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.l33t {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+
+	return models.PasswordFeedback{Warning: warning, Suggestions: suggestions}
+}
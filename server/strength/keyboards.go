@@ -0,0 +1,94 @@
+package strength
+
+import "strings"
+
+// keyboardGraph describes which keys neighbor each other on a keyboard.
+// neighbors[key][direction] holds the two characters (unshifted and shifted)
+// of the adjacent key in that direction, or "" where there is none.
+type keyboardGraph struct {
+	name          string
+	neighbors     map[rune][]string
+	startingKeys  float64
+	averageDegree float64
+}
+
+// qwertyRows lists each key as its unshifted and shifted character
+var qwertyRows = []string{
+	"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+	"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+	"aA sS dD fF gG hH jJ kK lL ;: '\"",
+	"zZ xX cC vV bB nN mM ,< .> /?",
+}
+
+// keypadRows lists the keys of a numeric keypad, with "  " marking gaps
+var keypadRows = []string{
+	"   /  *  -",
+	"7  8  9  +",
+	"4  5  6",
+	"1  2  3",
+	"   0  .",
+}
+
+// slantedDirections are the neighbor offsets on a staggered keyboard where
+// each row is shifted half a key to the right of the row above
+var slantedDirections = [][2]int{{-1, 0}, {0, -1}, {1, -1}, {1, 0}, {0, 1}, {-1, 1}}
+
+// alignedDirections are the neighbor offsets on a grid such as a keypad
+var alignedDirections = [][2]int{{-1, 0}, {-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}}
+
+var keyboardGraphs = []*keyboardGraph{
+	buildQwertyGraph(),
+	buildKeypadGraph(),
+}
+
+// buildQwertyGraph lays out the QWERTY rows so that the first key of every
+// letter row sits between the first two keys of the row above
+func buildQwertyGraph() *keyboardGraph {
+	positions := map[[2]int]string{}
+	for y, row := range qwertyRows {
+		offset := 0
+		if y > 0 {
+			offset = 1
+		}
+		for x, key := range strings.Fields(row) {
+			positions[[2]int{x + offset, y}] = key
+		}
+	}
+	return buildGraph("qwerty", positions, slantedDirections)
+}
+
+// buildKeypadGraph lays out the numeric keypad on a three character grid
+func buildKeypadGraph() *keyboardGraph {
+	positions := map[[2]int]string{}
+	for y, row := range keypadRows {
+		for i, char := range row {
+			if char != ' ' && i%3 == 0 {
+				positions[[2]int{i / 3, y}] = string(char)
+			}
+		}
+	}
+	return buildGraph("keypad", positions, alignedDirections)
+}
+
+func buildGraph(name string, positions map[[2]int]string, directions [][2]int) *keyboardGraph {
+	graph := &keyboardGraph{name: name, neighbors: map[rune][]string{}}
+
+	totalDegree := 0
+	for position, key := range positions {
+		adjacent := make([]string, len(directions))
+		for i, direction := range directions {
+			neighbor := positions[[2]int{position[0] + direction[0], position[1] + direction[1]}]
+			adjacent[i] = neighbor
+			if neighbor != "" {
+				totalDegree++
+			}
+		}
+		for _, char := range key {
+			graph.neighbors[char] = adjacent
+		}
+	}
+
+	graph.startingKeys = float64(len(graph.neighbors))
+	graph.averageDegree = float64(totalDegree) / float64(len(positions))
+	return graph
+}
@@ -0,0 +1,525 @@
+package strength
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// match is a guessable pattern covering password[i..j] (inclusive, in runes)
+type match struct {
+	pattern string
+	i, j    int
+	token   string
+	guesses float64
+
+	// dictionary
+	dictionary string
+	word       string
+	rank       int
+	l33t       bool
+	subs       map[rune]rune // substituted character -> letter it stands for
+	reversed   bool
+
+	// spatial
+	graph   *keyboardGraph
+	turns   int
+	shifted int
+
+	// repeat
+	baseToken   string
+	baseGuesses float64
+	repeatCount int
+
+	// sequence
+	ascending bool
+
+	// date and year
+	year      int
+	separator string
+}
+
+// l33tTable maps substituted characters to the letters they commonly replace
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'7': {'l', 't'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'+': {'t'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// maxL33tTokenRunes bounds the tokens checked for substitutions, since the
+// number of candidate words grows exponentially with the substitutions
+const maxL33tTokenRunes = 16
+
+// omnimatch returns every pattern found in the password
+func omnimatch(password []rune, dicts []rankedDictionary) []*match {
+	var matches []*match
+	matches = append(matches, dictionaryMatches(password, dicts)...)
+	matches = append(matches, reverseDictionaryMatches(password, dicts)...)
+	matches = append(matches, l33tMatches(password, dicts)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, repeatMatches(password, dicts)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+	matches = append(matches, yearMatches(password)...)
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].i != matches[b].i {
+			return matches[a].i < matches[b].i
+		}
+		return matches[a].j < matches[b].j
+	})
+	return matches
+}
+
+func lowerRunes(password []rune) []rune {
+	lower := make([]rune, len(password))
+	for i, char := range password {
+		lower[i] = unicode.ToLower(char)
+	}
+	return lower
+}
+
+// dictionaryMatches finds every substring that is a dictionary word
+func dictionaryMatches(password []rune, dicts []rankedDictionary) []*match {
+	lower := lowerRunes(password)
+	limit := 0
+	for _, dict := range dicts {
+		if dict.maxRunes > limit {
+			limit = dict.maxRunes
+		}
+	}
+
+	var matches []*match
+	for i := range lower {
+		for j := i; j < len(lower) && j-i < limit; j++ {
+			word := string(lower[i : j+1])
+			for _, dict := range dicts {
+				if rank, ok := dict.ranks[word]; ok {
+					matches = append(matches, &match{
+						pattern:    "dictionary",
+						i:          i,
+						j:          j,
+						token:      string(password[i : j+1]),
+						dictionary: dict.name,
+						word:       word,
+						rank:       rank,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// reverseDictionaryMatches finds dictionary words written backwards
+func reverseDictionaryMatches(password []rune, dicts []rankedDictionary) []*match {
+	n := len(password)
+	reversed := make([]rune, n)
+	for i, char := range password {
+		reversed[n-1-i] = char
+	}
+
+	var matches []*match
+	for _, m := range dictionaryMatches(reversed, dicts) {
+		// Palindromes are already found the right way round
+		if len(m.word) < 2 || m.word == reverseString(m.word) {
+			continue
+		}
+		m.i, m.j = n-1-m.j, n-1-m.i
+		m.token = string(password[m.i : m.j+1])
+		m.reversed = true
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// l33tMatches finds dictionary words with characters substituted, as in "p4ssw0rd"
+func l33tMatches(password []rune, dicts []rankedDictionary) []*match {
+	lower := lowerRunes(password)
+
+	var matches []*match
+	for i := range lower {
+		for j := i + 1; j < len(lower) && j-i < maxL33tTokenRunes; j++ {
+			token := lower[i : j+1]
+
+			var subbed []rune
+			seen := map[rune]bool{}
+			for _, char := range token {
+				if _, ok := l33tTable[char]; ok && !seen[char] {
+					seen[char] = true
+					subbed = append(subbed, char)
+				}
+			}
+			if len(subbed) == 0 {
+				continue
+			}
+
+			for _, subs := range l33tSubstitutions(subbed) {
+				candidate := make([]rune, len(token))
+				for k, char := range token {
+					if letter, ok := subs[char]; ok {
+						candidate[k] = letter
+					} else {
+						candidate[k] = char
+					}
+				}
+
+				word := string(candidate)
+				for _, dict := range dicts {
+					rank, ok := dict.ranks[word]
+					if !ok {
+						continue
+					}
+					matches = append(matches, &match{
+						pattern:    "dictionary",
+						i:          i,
+						j:          j,
+						token:      string(password[i : j+1]),
+						dictionary: dict.name,
+						word:       word,
+						rank:       rank,
+						l33t:       true,
+						subs:       subs,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// l33tSubstitutions enumerates every way of mapping the substituted
+// characters back to letters
+func l33tSubstitutions(subbed []rune) []map[rune]rune {
+	results := []map[rune]rune{{}}
+	for _, char := range subbed {
+		var next []map[rune]rune
+		for _, partial := range results {
+			for _, letter := range l33tTable[char] {
+				extended := make(map[rune]rune, len(partial)+1)
+				for k, v := range partial {
+					extended[k] = v
+				}
+				extended[char] = letter
+				next = append(next, extended)
+			}
+		}
+		results = next
+	}
+	return results
+}
+
+// spatialMatches finds runs of three or more adjacent keys
+func spatialMatches(password []rune) []*match {
+	var matches []*match
+	for _, graph := range keyboardGraphs {
+		i := 0
+		for i < len(password)-2 {
+			j := i + 1
+			lastDirection := -1
+			turns := 0
+			shifted := 0
+			if isShifted(graph, password[i]) {
+				shifted++
+			}
+
+			for j < len(password) {
+				direction, isShift := adjacentDirection(graph, password[j-1], password[j])
+				if direction < 0 {
+					break
+				}
+				if isShift {
+					shifted++
+				}
+				if direction != lastDirection {
+					turns++
+					lastDirection = direction
+				}
+				j++
+			}
+
+			if j-i > 2 {
+				matches = append(matches, &match{
+					pattern: "spatial",
+					i:       i,
+					j:       j - 1,
+					token:   string(password[i:j]),
+					graph:   graph,
+					turns:   turns,
+					shifted: shifted,
+				})
+			}
+			i = j
+		}
+	}
+	return matches
+}
+
+// adjacentDirection returns the direction from prev to next on the keyboard,
+// or -1 if they are not neighbors, and whether next needs shift
+func adjacentDirection(graph *keyboardGraph, prev rune, next rune) (int, bool) {
+	for direction, key := range graph.neighbors[prev] {
+		if key == "" {
+			continue
+		}
+		if index := strings.IndexRune(key, next); index >= 0 {
+			return direction, index == 1
+		}
+	}
+	return -1, false
+}
+
+// isShifted reports whether a character is typed with shift on a keyboard
+func isShifted(graph *keyboardGraph, char rune) bool {
+	if graph.name != "qwerty" {
+		return false
+	}
+	return strings.ContainsRune(`~!@#$%^&*()_+QWERTYUIOP{}|ASDFGHJKL:"ZXCVBNM<>?`, char)
+}
+
+// repeatMatches finds a unit repeated back to back, as in "aaa" or "abcabc"
+func repeatMatches(password []rune, dicts []rankedDictionary) []*match {
+	var matches []*match
+	i := 0
+	for i < len(password) {
+		bestUnit, bestCount := 0, 0
+		for unit := 1; unit <= (len(password)-i)/2; unit++ {
+			count := 1
+			for start := i + unit; start+unit <= len(password); start += unit {
+				if string(password[start:start+unit]) != string(password[i:i+unit]) {
+					break
+				}
+				count++
+			}
+			if count >= 2 && unit*count > bestUnit*bestCount {
+				bestUnit, bestCount = unit, count
+			}
+		}
+
+		if bestCount == 0 {
+			i++
+			continue
+		}
+
+		base := password[i : i+bestUnit]
+		span := bestUnit * bestCount
+		matches = append(matches, &match{
+			pattern:     "repeat",
+			i:           i,
+			j:           i + span - 1,
+			token:       string(password[i : i+span]),
+			baseToken:   string(base),
+			baseGuesses: mostGuessableSequence(base, omnimatch(base, dicts)).guesses(),
+			repeatCount: bestCount,
+		})
+		i += span
+	}
+	return matches
+}
+
+// maxSequenceDelta is the largest step between characters treated as a sequence
+const maxSequenceDelta = 5
+
+// sequenceMatches finds runs of characters with a constant step, as in "abc" or "9753"
+func sequenceMatches(password []rune) []*match {
+	var matches []*match
+	if len(password) < 3 {
+		return matches
+	}
+
+	flush := func(i, j int, delta int) {
+		if j-i+1 < 3 || delta == 0 || delta > maxSequenceDelta || delta < -maxSequenceDelta {
+			return
+		}
+		matches = append(matches, &match{
+			pattern:   "sequence",
+			i:         i,
+			j:         j,
+			token:     string(password[i : j+1]),
+			ascending: delta > 0,
+		})
+	}
+
+	i := 0
+	lastDelta := int(password[1]) - int(password[0])
+	for k := 2; k < len(password); k++ {
+		delta := int(password[k]) - int(password[k-1])
+		if delta != lastDelta {
+			flush(i, k-1, lastDelta)
+			i = k - 1
+			lastDelta = delta
+		}
+	}
+	flush(i, len(password)-1, lastDelta)
+	return matches
+}
+
+// dateSplits lists, for each digit count, where a run of digits can be cut
+// into day, month and year
+var dateSplits = map[int][][2]int{
+	4: {{1, 2}, {2, 3}},
+	5: {{1, 3}, {2, 3}},
+	6: {{1, 2}, {2, 4}, {4, 5}},
+	7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+	8: {{2, 4}, {4, 6}},
+}
+
+var separatedDate = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+
+// referenceYear is the year dates are assumed to be close to
+var referenceYear = time.Now().Year()
+
+// dateMatches finds dates written with or without separators
+func dateMatches(password []rune) []*match {
+	var matches []*match
+
+	for i := range password {
+		for j := i + 3; j < len(password) && j-i < 10; j++ {
+			token := string(password[i : j+1])
+
+			if splits, ok := dateSplits[j-i+1]; ok && isDigits(token) {
+				var best *match
+				for _, split := range splits {
+					ints := []int{atoi(token[:split[0]]), atoi(token[split[0]:split[1]]), atoi(token[split[1]:])}
+					if year, ok := dateYear(ints); ok {
+						if best == nil || absInt(year-referenceYear) < absInt(best.year-referenceYear) {
+							best = &match{pattern: "date", i: i, j: j, token: token, year: year}
+						}
+					}
+				}
+				if best != nil {
+					matches = append(matches, best)
+				}
+				continue
+			}
+
+			parts := separatedDate.FindStringSubmatch(token)
+			if parts == nil || parts[2] != parts[4] {
+				continue
+			}
+			if year, ok := dateYear([]int{atoi(parts[1]), atoi(parts[3]), atoi(parts[5])}); ok {
+				matches = append(matches, &match{pattern: "date", i: i, j: j, token: token, year: year, separator: parts[2]})
+			}
+		}
+	}
+
+	// Drop dates contained within longer dates
+	var filtered []*match
+	for _, m := range matches {
+		contained := false
+		for _, other := range matches {
+			if other != m && other.i <= m.i && other.j >= m.j && other.j-other.i > m.j-m.i {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// dateYear checks whether three integers form a day, month and year in some
+// order, returning the year
+func dateYear(ints []int) (int, bool) {
+	if ints[1] > 31 || ints[1] <= 0 {
+		return 0, false
+	}
+
+	over12, over31, under1 := 0, 0, 0
+	for _, value := range ints {
+		if (value > 99 && value < 1000) || value > 2050 {
+			return 0, false
+		}
+		if value > 31 {
+			over31++
+		}
+		if value > 12 {
+			over12++
+		}
+		if value <= 0 {
+			under1++
+		}
+	}
+	if over31 >= 2 || over12 == 3 || under1 >= 2 {
+		return 0, false
+	}
+
+	candidates := [][3]int{{ints[2], ints[0], ints[1]}, {ints[0], ints[1], ints[2]}}
+	for _, candidate := range candidates {
+		year, a, b := candidate[0], candidate[1], candidate[2]
+		if year >= 1000 && year <= 2050 && isDayMonth(a, b) {
+			return year, true
+		}
+	}
+	for _, candidate := range candidates {
+		year, a, b := candidate[0], candidate[1], candidate[2]
+		if year < 100 && isDayMonth(a, b) {
+			if year > 50 {
+				return 1900 + year, true
+			}
+			return 2000 + year, true
+		}
+	}
+	return 0, false
+}
+
+func isDayMonth(a, b int) bool {
+	return (a >= 1 && a <= 31 && b >= 1 && b <= 12) || (b >= 1 && b <= 31 && a >= 1 && a <= 12)
+}
+
+var recentYear = regexp.MustCompile(`19\d\d|20\d\d`)
+
+// yearMatches finds recent years
+func yearMatches(password []rune) []*match {
+	var matches []*match
+	for i := 0; i+4 <= len(password); i++ {
+		token := string(password[i : i+4])
+		if recentYear.MatchString(token) {
+			matches = append(matches, &match{pattern: "year", i: i, j: i + 3, token: token, year: atoi(token)})
+		}
+	}
+	return matches
+}
+
+func isDigits(token string) bool {
+	for _, char := range token {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return token != ""
+}
+
+func atoi(token string) int {
+	value, _ := strconv.Atoi(token)
+	return value
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func reverseString(value string) string {
+	runes := []rune(value)
+	for a, b := 0, len(runes)-1; a < b; a, b = a+1, b-1 {
+		runes[a], runes[b] = runes[b], runes[a]
+	}
+	return string(runes)
+}
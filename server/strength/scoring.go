@@ -0,0 +1,302 @@
+package strength
+
+import (
+	"math"
+	"unicode"
+)
+
+const (
+	bruteforceCardinality = 10
+	minGuessesSingleChar  = 10
+	minGuessesMultiChar   = 50
+	// minGuessesBeforeGrowing penalizes every extra match in a sequence, so
+	// that "password" scores as one word rather than "pass" + "word"
+	minGuessesBeforeGrowing = 10000
+	minYearSpace            = 20
+)
+
+// sequence is the cheapest way an attacker could guess a password, as a
+// series of non-overlapping matches covering it
+type sequence struct {
+	matches      []*match
+	guessesLog10 float64
+}
+
+func (s sequence) guesses() float64 {
+	return math.Pow(10, s.guessesLog10)
+}
+
+// dpState is the best sequence of length l ending at a position, stored as a
+// back pointer to the state it extends
+type dpState struct {
+	logProduct float64
+	match      *match
+}
+
+// mostGuessableSequence finds the match sequence that needs the fewest
+// guesses, filling gaps between matches with bruteforce
+func mostGuessableSequence(password []rune, matches []*match) sequence {
+	n := len(password)
+	if n == 0 {
+		return sequence{guessesLog10: 0}
+	}
+
+	byEnd := make([][]*match, n)
+	for _, m := range matches {
+		m.guesses = estimateGuesses(m, n)
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	// optimal[k][l] is the cheapest sequence of l matches covering password[0..k]
+	optimal := make([]map[int]dpState, n)
+	for k := range optimal {
+		optimal[k] = map[int]dpState{}
+	}
+
+	update := func(m *match, l int) {
+		logProduct := math.Log10(m.guesses)
+		if m.i > 0 {
+			logProduct += optimal[m.i-1][l-1].logProduct
+		}
+		total := sequenceGuessesLog10(logProduct, l)
+
+		// Skip if a shorter sequence ending here is already at least as good
+		for otherL, other := range optimal[m.j] {
+			if otherL <= l && sequenceGuessesLog10(other.logProduct, otherL) <= total {
+				return
+			}
+		}
+		optimal[m.j][l] = dpState{logProduct: logProduct, match: m}
+	}
+
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.i == 0 {
+				update(m, 1)
+				continue
+			}
+			for l := range optimal[m.i-1] {
+				update(m, l+1)
+			}
+		}
+
+		// Bruteforce from any point, but never straight after another bruteforce
+		for i := 0; i <= k; i++ {
+			bruteforce := &match{pattern: "bruteforce", i: i, j: k, token: string(password[i : k+1])}
+			bruteforce.guesses = estimateGuesses(bruteforce, n)
+			if i == 0 {
+				update(bruteforce, 1)
+				continue
+			}
+			for l, state := range optimal[i-1] {
+				if state.match.pattern != "bruteforce" {
+					update(bruteforce, l+1)
+				}
+			}
+		}
+	}
+
+	// Pick the best sequence length covering the whole password and unwind it
+	bestL, bestTotal := 0, math.Inf(1)
+	for l, state := range optimal[n-1] {
+		if total := sequenceGuessesLog10(state.logProduct, l); total < bestTotal || (total == bestTotal && l < bestL) {
+			bestL, bestTotal = l, total
+		}
+	}
+
+	result := make([]*match, bestL)
+	k, l := n-1, bestL
+	for l > 0 {
+		m := optimal[k][l].match
+		result[l-1] = m
+		k, l = m.i-1, l-1
+	}
+
+	return sequence{matches: result, guessesLog10: bestTotal}
+}
+
+// sequenceGuessesLog10 returns log10(l! * product + minGuessesBeforeGrowing^(l-1))
+func sequenceGuessesLog10(logProduct float64, l int) float64 {
+	lgamma, _ := math.Lgamma(float64(l + 1))
+	a := lgamma/math.Ln10 + logProduct
+	b := float64(l-1) * math.Log10(minGuessesBeforeGrowing)
+	high, low := math.Max(a, b), math.Min(a, b)
+	return high + math.Log10(1+math.Pow(10, low-high))
+}
+
+// estimateGuesses returns how many guesses an attacker needs for a match
+func estimateGuesses(m *match, passwordLength int) float64 {
+	tokenLength := m.j - m.i + 1
+
+	var guesses float64
+	switch m.pattern {
+	case "dictionary":
+		guesses = float64(m.rank) * uppercaseVariations(m.token) * l33tVariations(m)
+		if m.reversed {
+			guesses *= 2
+		}
+	case "spatial":
+		guesses = spatialGuesses(m)
+	case "repeat":
+		guesses = m.baseGuesses * float64(m.repeatCount)
+	case "sequence":
+		guesses = sequenceGuesses(m)
+	case "date":
+		guesses = math.Max(float64(absInt(m.year-referenceYear)), minYearSpace) * 365
+		if m.separator != "" {
+			guesses *= 4
+		}
+	case "year":
+		guesses = math.Max(float64(absInt(m.year-referenceYear)), minYearSpace)
+	default:
+		guesses = math.Pow(bruteforceCardinality, float64(tokenLength))
+		if math.IsInf(guesses, 1) {
+			guesses = math.MaxFloat64
+		}
+	}
+
+	minimum := 1.0
+	if tokenLength < passwordLength {
+		if tokenLength == 1 {
+			minimum = minGuessesSingleChar
+		} else {
+			minimum = minGuessesMultiChar
+		}
+	}
+	if m.pattern == "bruteforce" {
+		minimum += 1
+	}
+	return math.Max(guesses, minimum)
+}
+
+// uppercaseVariations counts the ways the capitals in a word could be placed
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	runes := []rune(token)
+	for _, char := range runes {
+		if unicode.IsUpper(char) {
+			upper++
+		} else if unicode.IsLower(char) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+
+	// Capitalizing the first or last letter, or all of them, is the common case
+	first, last := runes[0], runes[len(runes)-1]
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(first) || unicode.IsUpper(last))) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= minInt(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// l33tVariations counts the ways the substitutions in a word could be placed
+func l33tVariations(m *match) float64 {
+	if !m.l33t {
+		return 1
+	}
+
+	variations := 1.0
+	for subbed, letter := range m.subs {
+		subbedCount, unsubbedCount := 0, 0
+		for _, char := range []rune(m.token) {
+			switch unicode.ToLower(char) {
+			case subbed:
+				subbedCount++
+			case letter:
+				unsubbedCount++
+			}
+		}
+		if subbedCount == 0 {
+			continue
+		}
+		if unsubbedCount == 0 {
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= minInt(subbedCount, unsubbedCount); i++ {
+			possibilities += binomial(subbedCount+unsubbedCount, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+// spatialGuesses counts keyboard patterns of the same length with at most
+// as many turns, multiplied by the ways shifted keys could be placed
+func spatialGuesses(m *match) float64 {
+	length := m.j - m.i + 1
+	startingKeys := m.graph.startingKeys
+	degree := m.graph.averageDegree
+
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= minInt(m.turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * startingKeys * math.Pow(degree, float64(j))
+		}
+	}
+
+	if m.shifted > 0 {
+		unshifted := length - m.shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= minInt(m.shifted, unshifted); i++ {
+				variations += binomial(m.shifted+unshifted, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+// sequenceGuesses rates sequences starting at obvious characters as easiest
+func sequenceGuesses(m *match) float64 {
+	first := []rune(m.token)[0]
+
+	var base float64
+	switch {
+	case first == 'a' || first == 'A' || first == 'z' || first == 'Z' || first == '0' || first == '1' || first == '9':
+		base = 4
+	case first >= '0' && first <= '9':
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.ascending {
+		base *= 2
+	}
+	return base * float64(m.j-m.i+1)
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	if k == 0 {
+		return 1
+	}
+	result := 1.0
+	for d := 1; d <= k; d++ {
+		result *= float64(n)
+		result /= float64(d)
+		n--
+	}
+	return result
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
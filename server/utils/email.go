@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// EmailError describes why an email address was rejected
+type EmailError struct {
+	Reason string
+}
+
+func (e *EmailError) Error() string {
+	return "invalid email address: " + e.Reason
+}
+
+// NormalizedEmail holds the forms of a parsed email address
+type NormalizedEmail struct {
+	Input         string `json:"input"`
+	Address       string `json:"address"`       // lowercased, ASCII domain
+	Canonical     string `json:"canonical"`     // after provider-specific rules
+	Domain        string `json:"domain"`        // ASCII (punycode) domain
+	UnicodeDomain string `json:"unicodeDomain"` // domain as a person would type it
+}
+
+// EmailProviderRule describes how a mail provider treats local parts
+type EmailProviderRule struct {
+	StripDots     bool   // dots in the local part are ignored
+	StripPlusTags bool   // everything after '+' is ignored
+	CanonicalHost string // domain the provider's aliases map to
+}
+
+// defaultEmailProviderRules are the canonicalization rules of well-known providers
+var defaultEmailProviderRules = map[string]EmailProviderRule{
+	"gmail.com":      {StripDots: true, StripPlusTags: true},
+	"googlemail.com": {StripDots: true, StripPlusTags: true, CanonicalHost: "gmail.com"},
+	"outlook.com":    {StripPlusTags: true},
+	"hotmail.com":    {StripPlusTags: true},
+	"live.com":       {StripPlusTags: true},
+	"icloud.com":     {StripPlusTags: true},
+	"me.com":         {StripPlusTags: true, CanonicalHost: "icloud.com"},
+	"fastmail.com":   {StripPlusTags: true},
+	"protonmail.com": {StripPlusTags: true},
+	"proton.me":      {StripPlusTags: true},
+	"yahoo.com":      {},
+}
+
+// EmailProviderRules returns the canonicalization rules in effect. They can
+// be replaced with EMAIL_PROVIDER_RULES, e.g. "gmail.com=dots,plus;example.org=plus",
+// or turned off entirely with EMAIL_PROVIDER_RULES=none.
+func EmailProviderRules() map[string]EmailProviderRule {
+	config := strings.TrimSpace(os.Getenv("EMAIL_PROVIDER_RULES"))
+	if config == "" {
+		return defaultEmailProviderRules
+	}
+	if config == "none" {
+		return map[string]EmailProviderRule{}
+	}
+
+	rules := map[string]EmailProviderRule{}
+	for _, entry := range strings.Split(config, ";") {
+		domain, options, _ := strings.Cut(entry, "=")
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+
+		var rule EmailProviderRule
+		for _, option := range strings.Split(options, ",") {
+			option = strings.TrimSpace(option)
+			switch {
+			case option == "dots":
+				rule.StripDots = true
+			case option == "plus":
+				rule.StripPlusTags = true
+			case strings.HasPrefix(option, "alias:"):
+				rule.CanonicalHost = strings.TrimPrefix(option, "alias:")
+			}
+		}
+		rules[domain] = rule
+	}
+	return rules
+}
+
+// NormalizeEmail parses an RFC 5322 address, converts an internationalized
+// domain to punycode and applies provider canonicalization rules
+func NormalizeEmail(input string, rules map[string]EmailProviderRule) (*NormalizedEmail, error) {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		return nil, &EmailError{Reason: "address is empty"}
+	}
+
+	parsed, err := mail.ParseAddress(trimmed)
+	if err != nil {
+		return nil, &EmailError{Reason: strings.TrimPrefix(err.Error(), "mail: ")}
+	}
+	if parsed.Name != "" || strings.ContainsAny(trimmed, "<>") {
+		return nil, &EmailError{Reason: "only a bare address is accepted, without a display name or comment"}
+	}
+
+	at := strings.LastIndex(parsed.Address, "@")
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+
+	if len(local) > 64 {
+		return nil, &EmailError{Reason: "local part is longer than 64 characters"}
+	}
+
+	asciiDomain, err := NormalizeDomain(domain)
+	if err != nil {
+		return nil, &EmailError{Reason: err.Error()}
+	}
+	unicodeDomain, err := idna.Lookup.ToUnicode(asciiDomain)
+	if err != nil {
+		unicodeDomain = asciiDomain
+	}
+
+	local = strings.ToLower(local)
+	address := local + "@" + asciiDomain
+	if len(address) > 254 {
+		return nil, &EmailError{Reason: "address is longer than 254 characters"}
+	}
+
+	canonicalLocal, canonicalDomain := local, asciiDomain
+	if rule, ok := rules[asciiDomain]; ok {
+		if rule.StripPlusTags {
+			if plus := strings.Index(canonicalLocal, "+"); plus > 0 {
+				canonicalLocal = canonicalLocal[:plus]
+			}
+		}
+		if rule.StripDots {
+			canonicalLocal = strings.ReplaceAll(canonicalLocal, ".", "")
+		}
+		if rule.CanonicalHost != "" {
+			canonicalDomain = rule.CanonicalHost
+		}
+	}
+
+	return &NormalizedEmail{
+		Input:         input,
+		Address:       address,
+		Canonical:     canonicalLocal + "@" + canonicalDomain,
+		Domain:        asciiDomain,
+		UnicodeDomain: unicodeDomain,
+	}, nil
+}
+
+// NormalizeDomain lowercases a domain name and converts it to its ASCII
+// (punycode) form
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if domain == "" {
+		return "", fmt.Errorf("domain is empty")
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("domain %q is not a valid internationalized domain name", domain)
+	}
+	if !strings.Contains(ascii, ".") {
+		return "", fmt.Errorf("domain %q must contain at least one dot", domain)
+	}
+	if len(ascii) > 253 {
+		return "", fmt.Errorf("domain is longer than 253 characters")
+	}
+	return strings.ToLower(ascii), nil
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvDuration reads a duration such as "30m" from the environment, falling
+// back to def when the variable is unset or invalid
+func EnvDuration(name string, def time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return def
+}
+
+// EnvInt reads a positive integer from the environment, falling back to def
+// when the variable is unset or invalid
+func EnvInt(name string, def int) int {
+	if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+		return value
+	}
+	return def
+}
+
+// EnvBool reads a boolean such as "true" or "1" from the environment, falling
+// back to def when the variable is unset or invalid
+func EnvBool(name string, def bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(name)); err == nil {
+		return value
+	}
+	return def
+}
@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// PhoneError describes why a phone number was rejected
+type PhoneError struct {
+	Reason string
+}
+
+func (e *PhoneError) Error() string {
+	return "invalid phone number: " + e.Reason
+}
+
+// NormalizedPhone holds the forms of a parsed phone number
+type NormalizedPhone struct {
+	Input          string `json:"input"`
+	E164           string `json:"e164"` // e.g. +14155550123
+	CountryCode    string `json:"countryCode"`
+	NationalNumber string `json:"nationalNumber"` // without trunk prefix
+	Region         string `json:"region"`         // ISO 3166-1 alpha-2
+}
+
+// phoneRegion describes the numbering plan of a region
+type phoneRegion struct {
+	CountryCode string
+	TrunkPrefix string // dialed before national numbers within the region
+	MinLength   int    // national significant number length
+	MaxLength   int
+}
+
+// phoneRegions are the numbering plans of common regions
+var phoneRegions = map[string]phoneRegion{
+	"US": {"1", "1", 10, 10},
+	"CA": {"1", "1", 10, 10},
+	"RU": {"7", "8", 10, 10},
+	"KZ": {"7", "8", 10, 10},
+	"EG": {"20", "0", 8, 10},
+	"ZA": {"27", "0", 9, 9},
+	"GR": {"30", "", 10, 10},
+	"NL": {"31", "0", 9, 9},
+	"BE": {"32", "0", 8, 9},
+	"FR": {"33", "0", 9, 9},
+	"ES": {"34", "", 9, 9},
+	"HU": {"36", "06", 8, 9},
+	"IT": {"39", "", 6, 11},
+	"RO": {"40", "0", 9, 9},
+	"CH": {"41", "0", 9, 9},
+	"AT": {"43", "0", 4, 13},
+	"GB": {"44", "0", 9, 10},
+	"DK": {"45", "", 8, 8},
+	"SE": {"46", "0", 7, 9},
+	"NO": {"47", "", 8, 8},
+	"PL": {"48", "", 9, 9},
+	"DE": {"49", "0", 6, 13},
+	"PE": {"51", "0", 8, 9},
+	"MX": {"52", "", 10, 10},
+	"AR": {"54", "0", 10, 11},
+	"BR": {"55", "0", 10, 11},
+	"CL": {"56", "", 9, 9},
+	"CO": {"57", "0", 10, 10},
+	"MY": {"60", "0", 8, 10},
+	"AU": {"61", "0", 9, 9},
+	"ID": {"62", "0", 8, 12},
+	"PH": {"63", "0", 8, 10},
+	"NZ": {"64", "0", 8, 10},
+	"SG": {"65", "", 8, 8},
+	"TH": {"66", "0", 8, 9},
+	"JP": {"81", "0", 9, 10},
+	"KR": {"82", "0", 8, 10},
+	"VN": {"84", "0", 9, 10},
+	"CN": {"86", "0", 10, 11},
+	"TR": {"90", "0", 10, 10},
+	"IN": {"91", "0", 10, 10},
+	"PK": {"92", "0", 9, 10},
+	"LK": {"94", "0", 9, 9},
+	"IR": {"98", "0", 10, 10},
+	"MA": {"212", "0", 9, 9},
+	"NG": {"234", "0", 8, 10},
+	"KE": {"254", "0", 9, 9},
+	"PT": {"351", "", 9, 9},
+	"IE": {"353", "0", 7, 9},
+	"FI": {"358", "0", 5, 12},
+	"UA": {"380", "0", 9, 9},
+	"CZ": {"420", "", 9, 9},
+	"HK": {"852", "", 8, 8},
+	"TW": {"886", "0", 8, 9},
+	"BD": {"880", "0", 10, 10},
+	"AE": {"971", "0", 8, 9},
+	"IL": {"972", "0", 8, 9},
+	"SA": {"966", "0", 9, 9},
+}
+
+// primaryRegions picks the region reported for a shared country code
+var primaryRegions = map[string]string{"1": "US", "7": "RU"}
+
+// internationalPrefixes are dialed before a country code instead of '+' in
+// regions that do not use defaultInternationalPrefix
+var internationalPrefixes = map[string][]string{
+	"US": {"011"},
+	"CA": {"011"},
+	"RU": {"810"},
+	"KZ": {"810"},
+	"JP": {"010"},
+	"AU": {"0011"},
+	"KR": {"001", "002"},
+	"SG": {"001", "002"},
+	"HK": {"001"},
+	"TW": {"002"},
+	"TH": {"001"},
+	"ID": {"001", "007"},
+	"CO": {"005", "007", "009"},
+	"KE": {"000"},
+	"NG": {"009"},
+}
+
+// defaultInternationalPrefix is the international prefix of most regions
+const defaultInternationalPrefix = "00"
+
+// regionInternationalPrefixes returns the prefixes dialed before a country
+// code from region, or none for an unknown region
+func regionInternationalPrefixes(region string) []string {
+	if prefixes, ok := internationalPrefixes[region]; ok {
+		return prefixes
+	}
+	if _, ok := phoneRegions[region]; ok {
+		return []string{defaultInternationalPrefix}
+	}
+	return nil
+}
+
+// PhoneRegionCodes returns the regions whose numbering plans are known,
+// sorted
+func PhoneRegionCodes() []string {
+	codes := make([]string, 0, len(phoneRegions))
+	for region := range phoneRegions {
+		codes = append(codes, region)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// DefaultPhoneRegion returns the region assumed for numbers without a
+// country code, set with PHONE_DEFAULT_REGION (default "US")
+func DefaultPhoneRegion() string {
+	if region := strings.ToUpper(strings.TrimSpace(os.Getenv("PHONE_DEFAULT_REGION"))); region != "" {
+		return region
+	}
+	return "US"
+}
+
+// NormalizePhone parses a phone number as a person would write it, such as
+// "+44 20 7946 0958", "(415) 555-0123" or "0044 20 7946 0958", and returns
+// it in E.164 form. Numbers without a country code are read in defaultRegion,
+// and only that region's international prefix is taken to start one.
+func NormalizePhone(input string, defaultRegion string) (*NormalizedPhone, error) {
+	// NFKC folds full-width digits and dashes, common in Japanese and Chinese input
+	trimmed := strings.TrimSpace(norm.NFKC.String(input))
+	if trimmed == "" {
+		return nil, &PhoneError{Reason: "number is empty"}
+	}
+
+	// Drop an extension such as "x123" or "ext. 123"
+	lower := strings.ToLower(trimmed)
+	for _, marker := range []string{"ext", "x", "#"} {
+		if i := strings.Index(lower, marker); i > 0 {
+			trimmed, lower = trimmed[:i], lower[:i]
+		}
+	}
+
+	var digits strings.Builder
+	international := false
+	for i, char := range strings.TrimSpace(trimmed) {
+		switch {
+		case char >= '0' && char <= '9':
+			digits.WriteRune(char)
+		case char == '+' && i == 0:
+			international = true
+		case strings.ContainsRune(" -.()/", char):
+		default:
+			return nil, &PhoneError{Reason: "number contains " + string(char)}
+		}
+	}
+	number := digits.String()
+
+	if !international {
+		for _, prefix := range regionInternationalPrefixes(strings.ToUpper(defaultRegion)) {
+			if strings.HasPrefix(number, prefix) && len(number) > len(prefix)+6 {
+				number, international = number[len(prefix):], true
+				break
+			}
+		}
+	}
+
+	var countryCode, region string
+	if international {
+		for length := 1; length <= 3 && length < len(number); length++ {
+			if code := number[:length]; countryCodeRegion(code) != "" {
+				countryCode, region = code, countryCodeRegion(code)
+				break
+			}
+		}
+		if countryCode == "" {
+			return nil, &PhoneError{Reason: "unknown country code"}
+		}
+		number = number[len(countryCode):]
+	} else {
+		region = strings.ToUpper(defaultRegion)
+		plan, ok := phoneRegions[region]
+		if !ok {
+			return nil, &PhoneError{Reason: "unknown region " + defaultRegion}
+		}
+		countryCode = plan.CountryCode
+	}
+
+	plan := phoneRegions[region]
+	if plan.TrunkPrefix != "" && strings.HasPrefix(number, plan.TrunkPrefix) && len(number)-len(plan.TrunkPrefix) >= plan.MinLength {
+		number = number[len(plan.TrunkPrefix):]
+	}
+
+	if len(number) < plan.MinLength || len(number) > plan.MaxLength {
+		return nil, &PhoneError{Reason: "wrong number of digits for " + region}
+	}
+	if len(countryCode)+len(number) > 15 {
+		return nil, &PhoneError{Reason: "number is longer than 15 digits"}
+	}
+
+	return &NormalizedPhone{
+		Input:          input,
+		E164:           "+" + countryCode + number,
+		CountryCode:    countryCode,
+		NationalNumber: number,
+		Region:         region,
+	}, nil
+}
+
+// countryCodeRegion returns the region a country code is reported as, or ""
+func countryCodeRegion(code string) string {
+	if region, ok := primaryRegions[code]; ok {
+		return region
+	}
+	for region, plan := range phoneRegions {
+		if plan.CountryCode == code {
+			return region
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package utils
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		region string
+		want   string
+	}{
+		{"E.164", "+44 20 7946 0958", "US", "+442079460958"},
+		{"NANP national", "(415) 555-0123", "US", "+14155550123"},
+		{"NANP trunk prefix", "1 415 555 0123", "US", "+14155550123"},
+		{"NANP international prefix", "011 44 20 7946 0958", "US", "+442079460958"},
+		{"00 from GB", "0044 20 7946 0958", "GB", "+442079460958"},
+		{"GB national starting 01", "0118 496 0123", "GB", "+441184960123"},
+		{"GB national starting 011", "0113 496 0123", "GB", "+441134960123"},
+		{"00 from DE", "0033 1 23 45 67 89", "DE", "+33123456789"},
+		{"JP international prefix", "010 1 415 555 0123", "JP", "+14155550123"},
+		{"AU international prefix", "0011 44 20 7946 0958", "AU", "+442079460958"},
+		{"AU national", "02 9876 5432", "AU", "+61298765432"},
+		{"full-width digits", "０３-１２３４-５６７８", "JP", "+81312345678"},
+		{"extension dropped", "415-555-0123 ext. 12", "US", "+14155550123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := NormalizePhone(tt.input, tt.region)
+			if err != nil {
+				t.Fatalf("NormalizePhone(%q, %q): %v", tt.input, tt.region, err)
+			}
+			if phone.E164 != tt.want {
+				t.Errorf("NormalizePhone(%q, %q) = %s, want %s", tt.input, tt.region, phone.E164, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizePhoneRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		region string
+	}{
+		{"empty", " ", "US"},
+		{"letters", "415-555-CALL", "US"},
+		{"too short for region", "555 0123", "US"},
+		{"00 is not international from US", "0044 20 7946 0958", "US"},
+		{"unknown region", "020 7946 0958", "XX"},
+		{"unknown country code", "+999 1234 5678", "US"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if phone, err := NormalizePhone(tt.input, tt.region); err == nil {
+				t.Errorf("NormalizePhone(%q, %q) = %s, want an error", tt.input, tt.region, phone.E164)
+			}
+		})
+	}
+}
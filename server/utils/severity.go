@@ -0,0 +1,29 @@
+package utils
+
+// SeverityLevel calculates a severity level based on breach count
+func SeverityLevel(count int) string {
+	if count == 0 {
+		return "None"
+	} else if count < 3 {
+		return "Low"
+	} else if count < 7 {
+		return "Medium"
+	} else {
+		return "High"
+	}
+}
+
+// SeverityRank orders severity levels so they can be compared, with unknown
+// levels ranked lowest
+func SeverityRank(severity string) int {
+	switch severity {
+	case "Low":
+		return 1
+	case "Medium":
+		return 2
+	case "High":
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxUsernameLength is the longest username accepted, in characters
+const maxUsernameLength = 64
+
+// UsernameError describes why a username was rejected
+type UsernameError struct {
+	Reason string
+}
+
+func (e *UsernameError) Error() string {
+	return "invalid username: " + e.Reason
+}
+
+// NormalizeUsername returns the form usernames are matched on in leaked
+// datasets: NFKC-normalized, lowercased and without a leading '@' handle
+// prefix, so "@JohnDoe" and "ｊｏｈｎｄｏｅ" both become "johndoe"
+func NormalizeUsername(input string) (string, error) {
+	username := strings.TrimSpace(norm.NFKC.String(input))
+	username = strings.TrimPrefix(username, "@")
+	username = strings.ToLower(username)
+
+	if username == "" {
+		return "", &UsernameError{Reason: "username is empty"}
+	}
+	if utf8.RuneCountInString(username) > maxUsernameLength {
+		return "", &UsernameError{Reason: "username is longer than 64 characters"}
+	}
+	for _, char := range username {
+		if unicode.IsSpace(char) || unicode.IsControl(char) {
+			return "", &UsernameError{Reason: "username contains whitespace or control characters"}
+		}
+		if char == '@' {
+			return "", &UsernameError{Reason: "username contains '@', use the email check for addresses"}
+		}
+	}
+
+	return username, nil
+}